@@ -55,6 +55,7 @@ type AzureClient struct {
 	MicrosoftGraph  MicrosoftGraphClient
 	ResourceManager ResourceManagerClient
 	SubscriptionID  string
+	TenantID        string
 }
 
 type MicrosoftGraphClient struct {
@@ -72,6 +73,7 @@ type ResourceManagerClient struct {
 	DataProtection                             *dataProtection.Client
 	EventHubDisasterRecoveryClient             *disasterrecoveryconfigs.DisasterRecoveryConfigsClient
 	EventHubNameSpaceClient                    *eventhubNamespace.NamespacesClient
+	GenericResourcesClient                     *GenericResourcesClient
 	GraphServicesClient                        *graphservices.Client
 	LocksClient                                *managementlocks.ManagementLocksClient
 	MachineLearningWorkspacesClient            *workspaces.WorkspacesClient
@@ -99,10 +101,14 @@ type ResourceManagerClient struct {
 	StorageSyncCloudEndpointClient             *cloudendpointresource.CloudEndpointResourceClient
 	StorageSyncGroupClient                     *syncgroupresource.SyncGroupResourceClient
 	StorageSyncRegisteredServerClient          *registeredserverresource.RegisteredServerResourceClient
+	SubscriptionsClient                        *SubscriptionsClient
 	WebResourceProviderClient                  *webResourceProviders.ResourceProvidersClient
 	WorkloadsClient                            *workloads.Client
 }
 
+// Credentials configures how the dalek authenticates. Each of the supported methods is attempted (when enabled and
+// sufficiently configured) in the order: Client Certificate, Client Secret, OIDC, GitHub OIDC, Managed Identity and
+// finally the Azure CLI.
 type Credentials struct {
 	ClientID        string
 	ClientSecret    string
@@ -110,6 +116,88 @@ type Credentials struct {
 	TenantID        string
 	EnvironmentName string
 	Endpoint        string
+
+	// ClientCertificatePath is the path to a PKCS#12 bundle containing the Client Certificate (and its private key)
+	ClientCertificatePath     string
+	ClientCertificatePassword string
+
+	UseCLI bool
+
+	// UseMSI authenticates using a Managed Identity, the ClientID (when specified) being that of a User Assigned Identity
+	UseMSI      bool
+	MSIEndpoint string
+
+	// UseOIDC authenticates using a federated token, either OIDCToken, the contents of OIDCTokenFilePath, or one
+	// requested from GitHub Actions using OIDCRequestURL and OIDCRequestToken. OIDCTokenFilePath is re-read each time
+	// a new access token is needed, so that a rotated (e.g. projected) token is picked up during a long run
+	UseOIDC           bool
+	OIDCToken         string
+	OIDCTokenFilePath string
+	OIDCRequestURL    string
+	OIDCRequestToken  string
+
+	// Profiles are named Credentials which are used in place of these for whatever is assigned to them within
+	// Assignments - these are only used by BuildAzureClients
+	Profiles    map[string]Credentials
+	Assignments ProfileAssignments
+}
+
+func (c Credentials) authCredentials(environment environments.Environment) auth.Credentials {
+	return auth.Credentials{
+		ClientID:    c.ClientID,
+		TenantID:    c.TenantID,
+		Environment: environment,
+
+		EnableAuthenticatingUsingClientCertificate: c.ClientCertificatePath != "",
+		ClientCertificatePath:                      c.ClientCertificatePath,
+		ClientCertificatePassword:                  c.ClientCertificatePassword,
+
+		EnableAuthenticatingUsingClientSecret: true,
+		ClientSecret:                          c.ClientSecret,
+
+		EnableAuthenticationUsingOIDC:       c.UseOIDC,
+		OIDCAssertionToken:                  c.OIDCToken,
+		EnableAuthenticationUsingGitHubOIDC: c.UseOIDC,
+		OIDCTokenRequestURL:                 c.OIDCRequestURL,
+		OIDCTokenRequestToken:               c.OIDCRequestToken,
+
+		EnableAuthenticatingUsingManagedIdentity: c.UseMSI,
+		CustomManagedIdentityEndpoint:            c.MSIEndpoint,
+
+		EnableAuthenticatingUsingAzureCLI: c.UseCLI,
+		AzureCliSubscriptionIDHint:        c.SubscriptionID,
+	}
+}
+
+// authorizer builds the Authorizer for the specified API - which is the SDK's own, unless the federated token is
+// read from OIDCTokenFilePath (which the SDK only supports as a value, read once)
+func (c Credentials) authorizer(ctx context.Context, environment environments.Environment, api environments.Api) (auth.Authorizer, error) {
+	creds := c.authCredentials(environment)
+
+	// Client Certificates and Client Secrets take precedence over OIDC
+	usesClientCertificate := c.ClientCertificatePath != ""
+	usesClientSecret := strings.TrimSpace(c.ClientSecret) != ""
+	if !c.UseOIDC || c.OIDCToken != "" || c.OIDCTokenFilePath == "" || usesClientCertificate || usesClientSecret {
+		return auth.NewAuthorizerFromCredentials(ctx, creds, api)
+	}
+	if strings.TrimSpace(c.TenantID) == "" || strings.TrimSpace(c.ClientID) == "" {
+		return nil, fmt.Errorf("a Tenant ID and Client ID must be specified to authenticate using the OIDC token from %q", c.OIDCTokenFilePath)
+	}
+
+	a := &oidcTokenFileAuthorizer{
+		options: auth.OIDCAuthorizerOptions{
+			Environment: environment,
+			Api:         api,
+			TenantId:    c.TenantID,
+			ClientId:    c.ClientID,
+		},
+		path: c.OIDCTokenFilePath,
+	}
+	// read the file up front, so that a missing file is reported before anything's done
+	if _, err := a.token(); err != nil {
+		return nil, err
+	}
+	return auth.NewCachedAuthorizer(a)
 }
 
 func BuildAzureClient(ctx context.Context, credentials Credentials) (*AzureClient, error) {
@@ -118,21 +206,12 @@ func BuildAzureClient(ctx context.Context, credentials Credentials) (*AzureClien
 		return nil, fmt.Errorf("determining Environment: %+v", err)
 	}
 
-	creds := auth.Credentials{
-		ClientID:     credentials.ClientID,
-		ClientSecret: credentials.ClientSecret,
-		TenantID:     credentials.TenantID,
-		Environment:  *environment,
-
-		EnableAuthenticatingUsingClientSecret: true,
-	}
-
-	resourceManager, err := buildResourceManagerClient(ctx, creds, *environment, credentials.SubscriptionID)
+	resourceManager, err := buildResourceManagerClient(ctx, credentials, *environment, credentials.SubscriptionID)
 	if err != nil {
 		return nil, fmt.Errorf("building Resource Manager client: %+v", err)
 	}
 
-	microsoftGraph, err := buildMicrosoftGraphClient(ctx, creds, *environment)
+	microsoftGraph, err := buildMicrosoftGraphClient(ctx, credentials, *environment)
 	if err != nil {
 		return nil, fmt.Errorf("building Microsoft Graph client: %+v", err)
 	}
@@ -141,6 +220,7 @@ func BuildAzureClient(ctx context.Context, credentials Credentials) (*AzureClien
 		MicrosoftGraph:  *microsoftGraph,
 		ResourceManager: *resourceManager,
 		SubscriptionID:  credentials.SubscriptionID,
+		TenantID:        credentials.TenantID,
 	}
 
 	return &azureClient, nil
@@ -157,16 +237,20 @@ func environmentFromCredentials(ctx context.Context, credentials Credentials) (*
 		return env, nil
 	}
 
-	env, err := environments.FromName(credentials.EnvironmentName)
+	name := credentials.EnvironmentName
+	if name == "" {
+		name = "public"
+	}
+	env, err := environments.FromName(name)
 	if err != nil {
-		return nil, fmt.Errorf("loading with Name %q: %s", credentials.EnvironmentName, err)
+		return nil, fmt.Errorf("loading with Name %q: %s", name, err)
 	}
 
 	return env, nil
 }
 
-func buildMicrosoftGraphClient(ctx context.Context, creds auth.Credentials, environment environments.Environment) (*MicrosoftGraphClient, error) {
-	microsoftGraphAuthorizer, err := auth.NewAuthorizerFromCredentials(ctx, creds, environment.MicrosoftGraph)
+func buildMicrosoftGraphClient(ctx context.Context, credentials Credentials, environment environments.Environment) (*MicrosoftGraphClient, error) {
+	microsoftGraphAuthorizer, err := credentials.authorizer(ctx, environment, environment.MicrosoftGraph)
 	if err != nil {
 		return nil, fmt.Errorf("building Microsoft Graph authorizer: %+v", err)
 	}
@@ -210,8 +294,8 @@ func buildMicrosoftGraphClient(ctx context.Context, creds auth.Credentials, envi
 	}, nil
 }
 
-func buildResourceManagerClient(ctx context.Context, creds auth.Credentials, environment environments.Environment, _ string) (*ResourceManagerClient, error) {
-	resourceManagerAuthorizer, err := auth.NewAuthorizerFromCredentials(ctx, creds, environment.ResourceManager)
+func buildResourceManagerClient(ctx context.Context, credentials Credentials, environment environments.Environment, _ string) (*ResourceManagerClient, error) {
+	resourceManagerAuthorizer, err := credentials.authorizer(ctx, environment, environment.ResourceManager)
 	if err != nil {
 		return nil, fmt.Errorf("building Resource Manager authorizer: %+v", err)
 	}
@@ -381,6 +465,12 @@ func buildResourceManagerClient(ctx context.Context, creds auth.Credentials, env
 	recoveryServicesProtectionContainers := protectioncontainers.NewProtectionContainersClientWithBaseURI(*resourceManagerEndpoint)
 	recoveryServicesProtectionContainers.Client.Authorizer = autoRestAuthorizer
 
+	genericResourcesClient, err := NewGenericResourcesClientWithBaseURI(environment.ResourceManager)
+	if err != nil {
+		return nil, fmt.Errorf("building Generic Resources client: %+v", err)
+	}
+	genericResourcesClient.Client.Authorizer = resourceManagerAuthorizer
+
 	resourceGraphClient, err := resourceGraph.NewResourcesClientWithBaseURI(environment.ResourceManager)
 	if err != nil {
 		return nil, fmt.Errorf("building ResourceGraph client: %+v", err)
@@ -424,6 +514,12 @@ func buildResourceManagerClient(ctx context.Context, creds auth.Credentials, env
 	}
 	storageSyncRegisteredServerClient.Client.Authorizer = resourceManagerAuthorizer
 
+	subscriptionsClient, err := NewSubscriptionsClientWithBaseURI(environment.ResourceManager)
+	if err != nil {
+		return nil, fmt.Errorf("building Subscriptions Client: %+v", err)
+	}
+	subscriptionsClient.Client.Authorizer = resourceManagerAuthorizer
+
 	workloadsClient, err := workloads.NewClientWithBaseURI(environment.ResourceManager, func(c *resourcemanager.Client) {
 		c.Authorizer = resourceManagerAuthorizer
 	})
@@ -438,6 +534,7 @@ func buildResourceManagerClient(ctx context.Context, creds auth.Credentials, env
 		DataProtection:                             dataProtectionClient,
 		EventHubDisasterRecoveryClient:             eventHubDisasterRecoveryClient,
 		EventHubNameSpaceClient:                    eventHubNameSpaceClient,
+		GenericResourcesClient:                     genericResourcesClient,
 		GraphServicesClient:                        graphServicesClient,
 		LocksClient:                                locksClient,
 		MachineLearningWorkspacesClient:            workspacesClient,
@@ -465,6 +562,7 @@ func buildResourceManagerClient(ctx context.Context, creds auth.Credentials, env
 		StorageSyncGroupClient:                     storageSyncGroupClient,
 		StorageSyncCloudEndpointClient:             storageSyncCloudEndpointClient,
 		StorageSyncRegisteredServerClient:          storageSyncRegisteredServerClient,
+		SubscriptionsClient:                        subscriptionsClient,
 		WebResourceProviderClient:                  webResourceProvidersClient,
 		WorkloadsClient:                            workloadsClient,
 	}, nil
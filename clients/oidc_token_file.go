@@ -0,0 +1,67 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/auth"
+	"golang.org/x/oauth2"
+)
+
+// oidcTokenFileAuthorizer authenticates using the federated token within a file. The file is usually a projected
+// token (e.g. from AKS Workload Identity) which is rotated well within the length of a run, so it's re-read each
+// time an access token is requested - this is intended to be wrapped in a CachedAuthorizer, so that only happens
+// once the previous access token is due for renewal.
+type oidcTokenFileAuthorizer struct {
+	options auth.OIDCAuthorizerOptions
+	path    string
+}
+
+var _ auth.Authorizer = &oidcTokenFileAuthorizer{}
+
+func (a *oidcTokenFileAuthorizer) Token(ctx context.Context, request *http.Request) (*oauth2.Token, error) {
+	authorizer, err := a.authorizer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return authorizer.Token(ctx, request)
+}
+
+func (a *oidcTokenFileAuthorizer) AuxiliaryTokens(ctx context.Context, request *http.Request) ([]*oauth2.Token, error) {
+	authorizer, err := a.authorizer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return authorizer.AuxiliaryTokens(ctx, request)
+}
+
+// authorizer builds an OIDC Authorizer using the current contents of the token file
+func (a *oidcTokenFileAuthorizer) authorizer(ctx context.Context) (auth.Authorizer, error) {
+	token, err := a.token()
+	if err != nil {
+		return nil, err
+	}
+
+	options := a.options
+	options.FederatedAssertion = token
+	authorizer, err := auth.NewOIDCAuthorizer(ctx, options)
+	if err != nil {
+		return nil, fmt.Errorf("building the OIDC Authorizer: %+v", err)
+	}
+	return authorizer, nil
+}
+
+func (a *oidcTokenFileAuthorizer) token() (string, error) {
+	data, err := os.ReadFile(a.path)
+	if err != nil {
+		return "", fmt.Errorf("reading the OIDC token from %q: %+v", a.path, err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("the OIDC token file %q is empty", a.path)
+	}
+	return token, nil
+}
@@ -0,0 +1,133 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ProfileAssignments are the names of the credential Profiles used for specific Subscriptions, Microsoft Graph and the
+// Management Groups - anything which isn't assigned a Profile uses the default Credentials
+type ProfileAssignments struct {
+	// Subscriptions is the name of the Profile to use for each Subscription, keyed by the Subscription ID
+	Subscriptions map[string]string
+
+	MicrosoftGraph   string
+	ManagementGroups string
+}
+
+// AzureClients are the AzureClients built for the default Credentials and each of the assigned Profiles
+type AzureClients struct {
+	Default *AzureClient
+
+	subscriptions    map[string]*AzureClient
+	microsoftGraph   *AzureClient
+	managementGroups *AzureClient
+}
+
+// BuildAzureClients builds an AzureClient for the default Credentials, and one for each of the Profiles which is
+// assigned to something
+func BuildAzureClients(ctx context.Context, credentials Credentials) (*AzureClients, error) {
+	defaultClient, err := BuildAzureClient(ctx, credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &AzureClients{
+		Default:          defaultClient,
+		subscriptions:    make(map[string]*AzureClient),
+		microsoftGraph:   defaultClient,
+		managementGroups: defaultClient,
+	}
+
+	built := make(map[string]*AzureClient)
+	forProfile := func(name string) (*AzureClient, error) {
+		if client, ok := built[name]; ok {
+			return client, nil
+		}
+		profile, ok := credentials.Profiles[name]
+		if !ok {
+			return nil, fmt.Errorf("the credential profile %q isn't defined", name)
+		}
+		client, err := BuildAzureClient(ctx, profile)
+		if err != nil {
+			return nil, fmt.Errorf("building the Azure Clients for the credential profile %q: %+v", name, err)
+		}
+		built[name] = client
+		return client, nil
+	}
+
+	assignments := credentials.Assignments
+	subscriptionIds := make([]string, 0, len(assignments.Subscriptions))
+	for subscriptionId := range assignments.Subscriptions {
+		subscriptionIds = append(subscriptionIds, subscriptionId)
+	}
+	sort.Strings(subscriptionIds)
+	for _, subscriptionId := range subscriptionIds {
+		client, err := forProfile(assignments.Subscriptions[subscriptionId])
+		if err != nil {
+			return nil, err
+		}
+		output.subscriptions[strings.ToLower(subscriptionId)] = client
+	}
+	if assignments.MicrosoftGraph != "" {
+		if output.microsoftGraph, err = forProfile(assignments.MicrosoftGraph); err != nil {
+			return nil, err
+		}
+	}
+	if assignments.ManagementGroups != "" {
+		if output.managementGroups, err = forProfile(assignments.ManagementGroups); err != nil {
+			return nil, err
+		}
+	}
+
+	return output, nil
+}
+
+// ForSubscription returns the AzureClient to use for the specified Subscription
+func (c *AzureClients) ForSubscription(subscriptionId string) *AzureClient {
+	if client, ok := c.subscriptions[strings.ToLower(subscriptionId)]; ok {
+		return client
+	}
+	return c.Default
+}
+
+// MicrosoftGraph returns the AzureClient to use for Microsoft Graph
+func (c *AzureClients) MicrosoftGraph() *AzureClient {
+	return c.microsoftGraph
+}
+
+// ManagementGroups returns the AzureClient to use for the Management Groups
+func (c *AzureClients) ManagementGroups() *AzureClient {
+	return c.managementGroups
+}
+
+// TenantIDs returns the distinct IDs of the Tenants which the AzureClients authenticate against
+func (c *AzureClients) TenantIDs() []string {
+	output := make([]string, 0)
+	seen := make(map[string]struct{})
+	for _, client := range append([]*AzureClient{c.Default, c.microsoftGraph, c.managementGroups}, c.subscriptionClients()...) {
+		key := strings.ToLower(client.TenantID)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		output = append(output, client.TenantID)
+	}
+	return output
+}
+
+func (c *AzureClients) subscriptionClients() []*AzureClient {
+	keys := make([]string, 0, len(c.subscriptions))
+	for k := range c.subscriptions {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	output := make([]*AzureClient, 0, len(keys))
+	for _, k := range keys {
+		output = append(output, c.subscriptions[k])
+	}
+	return output
+}
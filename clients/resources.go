@@ -0,0 +1,167 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/client"
+	"github.com/hashicorp/go-azure-sdk/sdk/client/resourcemanager"
+	"github.com/hashicorp/go-azure-sdk/sdk/environments"
+	"github.com/hashicorp/go-azure-sdk/sdk/odata"
+)
+
+// Deleting an arbitrary resource requires an API Version which its Resource Provider supports, so this is a minimal
+// client built on top of the base Resource Manager client which looks these up from the Providers API (caching them)
+// and then deletes the resource by its ID.
+
+const providersApiVersion = "2021-04-01"
+
+type GenericResourcesClient struct {
+	Client *resourcemanager.Client
+
+	// apiVersions is the API Version to use for each Resource Type, keyed by the lower-cased Subscription ID and
+	// Resource Type (e.g. `microsoft.storage/storageaccounts`)
+	apiVersions map[string]string
+	mu          sync.Mutex
+}
+
+func NewGenericResourcesClientWithBaseURI(sdkApi environments.Api) (*GenericResourcesClient, error) {
+	c, err := resourcemanager.NewClient(sdkApi, "resources", providersApiVersion)
+	if err != nil {
+		return nil, fmt.Errorf("instantiating GenericResourcesClient: %+v", err)
+	}
+
+	return &GenericResourcesClient{
+		Client:      c,
+		apiVersions: make(map[string]string),
+	}, nil
+}
+
+type apiVersionOptions struct {
+	apiVersion string
+}
+
+func (o apiVersionOptions) ToHeaders() *client.Headers {
+	return &client.Headers{}
+}
+
+func (o apiVersionOptions) ToOData() *odata.Query {
+	return &odata.Query{}
+}
+
+func (o apiVersionOptions) ToQuery() *client.QueryParams {
+	out := &client.QueryParams{}
+	out.Append("api-version", o.apiVersion)
+	return out
+}
+
+// Delete triggers the deletion of the resource with the specified ID and Resource Type, without waiting for this to
+// complete. A resource which no longer exists isn't an error.
+func (c *GenericResourcesClient) Delete(ctx context.Context, subscriptionId, resourceType, id string) (*http.Response, error) {
+	apiVersion, err := c.apiVersion(ctx, subscriptionId, resourceType)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := client.RequestOptions{
+		ContentType: "application/json; charset=utf-8",
+		ExpectedStatusCodes: []int{
+			http.StatusAccepted,
+			http.StatusNoContent,
+			http.StatusNotFound,
+			http.StatusOK,
+		},
+		HttpMethod:    http.MethodDelete,
+		OptionsObject: apiVersionOptions{apiVersion: apiVersion},
+		Path:          id,
+	}
+
+	req, err := c.Client.NewRequest(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := req.Execute(ctx)
+	if resp != nil {
+		return resp.Response, err
+	}
+	return nil, err
+}
+
+type provider struct {
+	ResourceTypes *[]struct {
+		ResourceType *string   `json:"resourceType,omitempty"`
+		ApiVersions  *[]string `json:"apiVersions,omitempty"`
+	} `json:"resourceTypes,omitempty"`
+}
+
+// apiVersion returns the latest stable API Version for the Resource Type, or the latest preview version when there's
+// no stable version
+func (c *GenericResourcesClient) apiVersion(ctx context.Context, subscriptionId, resourceType string) (string, error) {
+	key := strings.ToLower(fmt.Sprintf("%s|%s", subscriptionId, resourceType))
+	c.mu.Lock()
+	apiVersion, ok := c.apiVersions[key]
+	c.mu.Unlock()
+	if ok {
+		return apiVersion, nil
+	}
+
+	namespace, typeName, ok := strings.Cut(resourceType, "/")
+	if !ok {
+		return "", fmt.Errorf("parsing the Resource Type %q: expected it to be in the format `Namespace/Type`", resourceType)
+	}
+
+	opts := client.RequestOptions{
+		ContentType: "application/json; charset=utf-8",
+		ExpectedStatusCodes: []int{
+			http.StatusOK,
+		},
+		HttpMethod: http.MethodGet,
+		Path:       fmt.Sprintf("/subscriptions/%s/providers/%s", subscriptionId, namespace),
+	}
+
+	req, err := c.Client.NewRequest(ctx, opts)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := req.Execute(ctx)
+	if err != nil {
+		return "", fmt.Errorf("retrieving the Resource Provider %q: %+v", namespace, err)
+	}
+
+	var model provider
+	if err := resp.Unmarshal(&model); err != nil {
+		return "", fmt.Errorf("unmarshalling the Resource Provider %q: %+v", namespace, err)
+	}
+
+	if model.ResourceTypes != nil {
+		for _, v := range *model.ResourceTypes {
+			if v.ResourceType == nil || !strings.EqualFold(*v.ResourceType, typeName) || v.ApiVersions == nil {
+				continue
+			}
+			// the API Versions are ordered newest first
+			for _, version := range *v.ApiVersions {
+				if !strings.Contains(version, "preview") {
+					apiVersion = version
+					break
+				}
+			}
+			if apiVersion == "" && len(*v.ApiVersions) > 0 {
+				apiVersion = (*v.ApiVersions)[0]
+			}
+		}
+	}
+	if apiVersion == "" {
+		return "", fmt.Errorf("the Resource Provider %q doesn't list any API Versions for the Resource Type %q", namespace, resourceType)
+	}
+
+	c.mu.Lock()
+	c.apiVersions[key] = apiVersion
+	c.mu.Unlock()
+
+	return apiVersion, nil
+}
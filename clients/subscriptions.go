@@ -0,0 +1,88 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/hashicorp/go-azure-sdk/sdk/client"
+	"github.com/hashicorp/go-azure-sdk/sdk/client/resourcemanager"
+	"github.com/hashicorp/go-azure-sdk/sdk/environments"
+	"github.com/hashicorp/go-azure-sdk/sdk/odata"
+)
+
+// The Subscriptions API isn't vendored from the SDK, so this is a minimal client built on top of the base
+// Resource Manager client which lists the Subscriptions visible to the authenticated principal.
+
+const subscriptionsApiVersion = "2022-12-01"
+
+type SubscriptionsClient struct {
+	Client *resourcemanager.Client
+}
+
+type Subscription struct {
+	DisplayName    *string            `json:"displayName,omitempty"`
+	Id             *string            `json:"id,omitempty"`
+	State          *string            `json:"state,omitempty"`
+	SubscriptionId *string            `json:"subscriptionId,omitempty"`
+	Tags           *map[string]string `json:"tags,omitempty"`
+	TenantId       *string            `json:"tenantId,omitempty"`
+}
+
+func NewSubscriptionsClientWithBaseURI(sdkApi environments.Api) (*SubscriptionsClient, error) {
+	c, err := resourcemanager.NewClient(sdkApi, "subscriptions", subscriptionsApiVersion)
+	if err != nil {
+		return nil, fmt.Errorf("instantiating SubscriptionsClient: %+v", err)
+	}
+
+	return &SubscriptionsClient{
+		Client: c,
+	}, nil
+}
+
+type subscriptionsListPager struct {
+	NextLink *odata.Link `json:"nextLink"`
+}
+
+func (p *subscriptionsListPager) NextPageLink() *odata.Link {
+	defer func() {
+		p.NextLink = nil
+	}()
+
+	return p.NextLink
+}
+
+// ListComplete retrieves every Subscription visible to the authenticated principal
+func (c SubscriptionsClient) ListComplete(ctx context.Context) ([]Subscription, error) {
+	opts := client.RequestOptions{
+		ContentType: "application/json; charset=utf-8",
+		ExpectedStatusCodes: []int{
+			http.StatusOK,
+		},
+		HttpMethod: http.MethodGet,
+		Pager:      &subscriptionsListPager{},
+		Path:       "/subscriptions",
+	}
+
+	req, err := c.Client.NewRequest(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := req.ExecutePaged(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var values struct {
+		Values *[]Subscription `json:"value"`
+	}
+	if err := resp.Unmarshal(&values); err != nil {
+		return nil, err
+	}
+
+	if values.Values == nil {
+		return []Subscription{}, nil
+	}
+	return *values.Values, nil
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jackofallops/azurerm-dalek/clients"
+	"github.com/jackofallops/azurerm-dalek/dalek"
+	"github.com/jackofallops/azurerm-dalek/dalek/checkpoint"
+	"github.com/jackofallops/azurerm-dalek/dalek/cleaners"
+	"github.com/jackofallops/azurerm-dalek/dalek/logging"
+	"github.com/jackofallops/azurerm-dalek/dalek/metrics"
+	"github.com/jackofallops/azurerm-dalek/dalek/options"
+	"github.com/jackofallops/azurerm-dalek/dalek/plan"
+	"github.com/jackofallops/azurerm-dalek/dalek/report"
+	"github.com/jackofallops/azurerm-dalek/dalek/safety"
+)
+
+// runList performs a dry-run, outputting an inventory of the objects which would be deleted or purged - which is built
+// from the Report, so includes everything the Cleaners record there
+func runList(fs *flag.FlagSet, args []string) error {
+	f := newRunFlags()
+	f.registerCommon(fs)
+	f.registerFilter(fs)
+	f.registerSafety(fs)
+	all := fs.Bool("all", false, "-all (also list the objects which don't match the filter, along with why)")
+	credentials, opts, err := f.parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+
+	opts.ActuallyDelete = false
+	rep, err := run(ctx, credentials, opts)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tSCOPE\tNAME\tACTION\tID\tRULE")
+	for _, entry := range rep.SortedEntries() {
+		if entry.Action == report.ActionSkipped && !*all {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", entry.Kind, entry.Scope, entry.Name, entry.Action, entry.ID, entry.Rule)
+
+		// the changes the Cleaners would make within the object are nested beneath it
+		for _, action := range entry.CleanerActions {
+			fmt.Fprintf(w, "  %s\t\t\t%s\t%s\t\n", action.Cleaner, action.Description, action.ID)
+		}
+	}
+	if flushErr := w.Flush(); flushErr != nil {
+		err = errors.Join(err, fmt.Errorf("writing the inventory: %+v", flushErr))
+	}
+
+	return err
+}
+
+// runPlan performs a dry-run, recording every object which would be deleted into a Plan written to the `-out` file
+func runPlan(fs *flag.FlagSet, args []string) error {
+	f := newRunFlags()
+	f.registerCommon(fs)
+	f.registerFilter(fs)
+	f.registerSafety(fs)
+	f.registerOutput(fs)
+	planFile := fs.String("out", "", "-out=plan.json")
+	credentials, opts, err := f.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if *planFile == "" {
+		return fmt.Errorf("`-out` must be specified when creating a plan")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+
+	opts.ActuallyDelete = false
+	gate := plan.NewRecorder(opts)
+	_, err = run(plan.WithGate(ctx, gate), credentials, opts)
+	errs := []error{err}
+
+	if err := gate.Plan().WriteFile(*planFile); err != nil {
+		errs = append(errs, err)
+	} else {
+		slog.Info("Written the plan", slog.String("path", *planFile), slog.Int("items", len(gate.Plan().Items)))
+	}
+
+	return errors.Join(errs...)
+}
+
+// runApply deletes only the objects within the Plan, providing these haven't changed since it was created
+func runApply(fs *flag.FlagSet, args []string) error {
+	f := newRunFlags()
+	f.registerCommon(fs)
+	f.registerSafety(fs)
+	f.registerExecution(fs)
+	f.registerOutput(fs)
+	credentials, opts, err := f.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: %s apply [flags] <plan file>", binaryName())
+	}
+	planFile := fs.Arg(0)
+
+	p, err := plan.Load(planFile)
+	if err != nil {
+		return err
+	}
+
+	// the objects to process are those from the plan, whereas how they're processed is configured for this run
+	applyOpts := p.Options
+	applyOpts.ActuallyDelete = true
+	applyOpts.SubscriptionIDs = p.SubscriptionIDs()
+	applyOpts.AllSubscriptions = false
+	applyOpts.ManagementGroupID = ""
+	applyOpts.NumberOfResourceGroupsToDelete = 0
+	applyOpts.Parallelism = opts.Parallelism
+	applyOpts.WaitForDeletion = opts.WaitForDeletion
+	applyOpts.WaitForDeletionTimeout = opts.WaitForDeletionTimeout
+	applyOpts.ReportFile = opts.ReportFile
+	applyOpts.Resume = opts.Resume
+	applyOpts.StateFile = opts.StateFile
+	applyOpts.MetricsFile = opts.MetricsFile
+	applyOpts.MetricsPushgatewayURL = opts.MetricsPushgatewayURL
+	applyOpts.Timeout = opts.Timeout
+	applyOpts.Allowlist = opts.Allowlist
+	applyOpts.SafetyLimits = opts.SafetyLimits
+	applyOpts.OverrideSafetyLimits = opts.OverrideSafetyLimits
+
+	ctx, cancel := context.WithTimeout(context.Background(), applyOpts.Timeout)
+	defer cancel()
+
+	slog.Info("Applying the plan", slog.String("path", planFile), slog.Time("created_at", p.CreatedAt), slog.Int("items", len(p.Items)))
+	gate := plan.NewApplier(p)
+	_, err = run(plan.WithGate(ctx, gate), credentials, applyOpts)
+
+	for _, item := range gate.Unvisited() {
+		slog.Warn("An object from the plan was not found", slog.String("kind", string(item.Kind)), logging.ResourceID(item.ID))
+	}
+
+	return err
+}
+
+// runDelete deletes every object which matches the filter
+func runDelete(fs *flag.FlagSet, args []string) error {
+	f := newRunFlags()
+	f.registerCommon(fs)
+	f.registerFilter(fs)
+	f.registerSafety(fs)
+	f.registerExecution(fs)
+	f.registerOutput(fs)
+	interactive := fs.Bool("interactive", false, "-interactive (confirm which of the matching objects are deleted, rather than requiring YES_I_REALLY_WANT_TO_DELETE_THINGS)")
+	credentials, opts, err := f.parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if *interactive {
+		if err := requireTerminal(os.Stdin); err != nil {
+			return err
+		}
+		return runInteractive(credentials, opts)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+
+	_, err = run(ctx, credentials, opts)
+	return err
+}
+
+// runPurge purges the objects matching the filter which have already been soft-deleted, without deleting anything else
+func runPurge(fs *flag.FlagSet, args []string) error {
+	f := newRunFlags()
+	f.registerCommon(fs)
+	f.registerFilter(fs)
+	f.registerSafety(fs)
+	f.registerExecution(fs)
+	f.registerOutput(fs)
+	interactive := fs.Bool("interactive", false, "-interactive (confirm which of the soft-deleted objects are purged, rather than requiring YES_I_REALLY_WANT_TO_DELETE_THINGS)")
+	credentials, opts, err := f.parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	opts.PurgeOnly = true
+	if *interactive {
+		if err := requireTerminal(os.Stdin); err != nil {
+			return err
+		}
+		return runInteractive(credentials, opts)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+
+	_, err = run(ctx, credentials, opts)
+	return err
+}
+
+// runCleaners outputs the registered Cleaners, in the order these are run
+func runCleaners(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	subscriptionStages, err := cleaners.OrderedSubscriptionCleaners()
+	if err != nil {
+		return fmt.Errorf("determining the order to run the Subscription Cleaners in: %+v", err)
+	}
+	resourceGroupStages, err := cleaners.OrderedResourceGroupCleaners()
+	if err != nil {
+		return fmt.Errorf("determining the order to run the Resource Group Cleaners in: %+v", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SCOPE\tNAME\tRUNS AFTER\tRESOURCE TYPES")
+	for _, stage := range subscriptionStages {
+		for _, cleaner := range stage {
+			runsAfter := make([]string, 0)
+			for _, dependency := range cleaner.RunsAfter() {
+				runsAfter = append(runsAfter, dependency.Name())
+			}
+			fmt.Fprintf(w, "Subscription\t%s\t%s\t%s\n", cleaner.Name(), orNone(runsAfter), "-")
+		}
+	}
+	for _, stage := range resourceGroupStages {
+		for _, cleaner := range stage {
+			runsAfter := make([]string, 0)
+			for _, dependency := range cleaner.RunsAfter() {
+				runsAfter = append(runsAfter, dependency.Name())
+			}
+			fmt.Fprintf(w, "Resource Group\t%s\t%s\t%s\n", cleaner.Name(), orNone(runsAfter), orNone(cleaner.ResourceTypes()))
+		}
+	}
+
+	return w.Flush()
+}
+
+func orNone(input []string) string {
+	if len(input) == 0 {
+		return "-"
+	}
+	return strings.Join(input, ", ")
+}
+
+// run processes Resource Manager, Microsoft Graph and the Management Groups, returning the Report of every object
+// which was considered
+func run(ctx context.Context, credentials clients.Credentials, opts options.Options) (*report.Report, error) {
+	// the safety limits have to be checked before anything is deleted, which is done using a dry-run since whether
+	// these are exceeded is only known once everything has been listed
+	guard := safety.NewGuard(opts.SafetyLimits, opts.OverrideSafetyLimits)
+	var preflightErr error
+	if opts.ActuallyDelete && opts.SafetyLimits.Enabled() && !opts.OverrideSafetyLimits {
+		preflightErr = checkSafetyLimits(ctx, credentials, opts)
+	}
+	ctx = safety.WithGuard(ctx, guard)
+
+	rep := report.New(opts.ActuallyDelete)
+	ctx = report.WithReport(ctx, rep)
+	collected := metrics.New()
+	ctx = metrics.WithMetrics(ctx, collected)
+
+	// progress is only recorded when deleting, since there's nothing to resume from a dry-run
+	if opts.ActuallyDelete && opts.StateFile != "" {
+		progress, err := checkpoint.Open(opts.StateFile, opts.Fingerprint(), opts.Resume)
+		if err != nil {
+			return rep, fmt.Errorf("opening the state file: %+v", err)
+		}
+		if opts.Resume {
+			slog.Info("Resuming from the state file", slog.String("path", opts.StateFile), slog.Int("completed", progress.Len()))
+		}
+		ctx = checkpoint.WithCheckpoint(ctx, progress)
+	}
+
+	var errs []error
+	if preflightErr != nil {
+		errs = []error{preflightErr}
+	} else {
+		errs = process(ctx, credentials, opts)
+		if err := guard.Err(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// once a run has completed without any errors there's nothing left to resume, so the next run starts afresh
+	if len(errs) == 0 {
+		if err := checkpoint.FromContext(ctx).Remove(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	rep.Finish(errs...)
+
+	if opts.ReportFile != "" {
+		if err := rep.WriteFile(opts.ReportFile); err != nil {
+			errs = append(errs, err)
+		} else {
+			slog.Info("Written the run report", slog.String("path", opts.ReportFile))
+		}
+	}
+
+	if opts.MetricsFile != "" {
+		if err := collected.WriteFile(opts.MetricsFile, rep); err != nil {
+			errs = append(errs, err)
+		} else {
+			slog.Info("Written the metrics", slog.String("path", opts.MetricsFile))
+		}
+	}
+
+	if opts.MetricsPushgatewayURL != "" {
+		// the run's context may well have expired by now, but the metrics should still be pushed
+		pushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer cancel()
+		if err := collected.Push(pushCtx, opts.MetricsPushgatewayURL, rep); err != nil {
+			errs = append(errs, err)
+		} else {
+			slog.Info("Pushed the metrics", slog.String("url", opts.MetricsPushgatewayURL))
+		}
+	}
+
+	return rep, errors.Join(errs...)
+}
+
+// checkSafetyLimits lists and matches the objects which would be deleted to determine whether deleting these would
+// exceed the safety limits, returning an error summarising each limit which was exceeded. Only the Resource Groups,
+// Microsoft Graph objects and Management Groups are listed - neither the Cleaners nor the plan are run against these.
+func checkSafetyLimits(ctx context.Context, credentials clients.Credentials, opts options.Options) error {
+	slog.Info("Checking the safety limits before deleting anything", slog.String("limits", opts.SafetyLimits.String()))
+
+	dryRunOpts := opts
+	dryRunOpts.ActuallyDelete = false
+	guard := safety.NewPreflightGuard(opts.SafetyLimits)
+	ctx = safety.WithGuard(ctx, guard)
+	ctx = report.WithReport(ctx, report.New(false))
+	ctx = metrics.WithMetrics(ctx, metrics.New())
+
+	// any other errors will be encountered again (and returned) by the run itself, and the limits are also checked
+	// during that run for anything which couldn't be listed here
+	_ = process(ctx, credentials, dryRunOpts)
+	if err := guard.Err(); err != nil {
+		return fmt.Errorf("not deleting anything: %w", err)
+	}
+	return nil
+}
+
+func process(ctx context.Context, credentials clients.Credentials, opts options.Options) []error {
+	sdkClients, err := clients.BuildAzureClients(ctx, credentials)
+	if err != nil {
+		return []error{fmt.Errorf("building Azure Clients: %+v", err)}
+	}
+
+	errs := make([]error, 0) // nolint prealloc
+
+	slog.Debug("Options", slog.String("options", opts.String()))
+
+	client := dalek.NewDalek(sdkClients, opts)
+	if err := client.CheckAllowlist(ctx); err != nil {
+		return []error{fmt.Errorf("refusing to run against this Tenant or these Subscriptions: %+v", err)}
+	}
+
+	slog.Info("Processing Resource Manager")
+	errs = append(errs, client.ResourceManager(ctx)...)
+
+	slog.Info("Processing Microsoft Graph")
+	if err := client.MicrosoftGraph(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	slog.Info("Processing Management Groups")
+	if err := client.ManagementGroups(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
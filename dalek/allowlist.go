@@ -0,0 +1,70 @@
+package dalek
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/hashicorp/go-azure-helpers/lang/pointer"
+	"github.com/jackofallops/azurerm-dalek/clients"
+	"github.com/jackofallops/azurerm-dalek/dalek/logging"
+)
+
+// CheckAllowlist returns an error when any of the Tenants, or any of the Subscriptions which would be processed, aren't
+// within the allowlist. This is checked before anything is processed (including for a dry-run), since a mis-set
+// environment variable mustn't be able to point the dalek at production.
+func (d *Dalek) CheckAllowlist(ctx context.Context) error {
+	allowlist := d.opts.Allowlist
+	if !allowlist.Enabled() {
+		return nil
+	}
+
+	// each of the credential profiles may authenticate against a different Tenant
+	for _, tenantId := range d.clients.TenantIDs() {
+		if err := allowlist.CheckTenant(tenantId); err != nil {
+			return err
+		}
+	}
+
+	subscriptionIds, err := d.subscriptionIds(ctx)
+	if err != nil {
+		return fmt.Errorf("determining the Subscriptions to process: %+v", err)
+	}
+
+	// the Tenant and tags of each Subscription come from the list, rather than retrieving each individually - which is
+	// listed using the AzureClient used for that Subscription, since it may only be visible to that principal
+	visible := make(map[*clients.AzureClient]map[string]clients.Subscription)
+	errs := make([]error, 0)
+	for _, subscriptionId := range subscriptionIds {
+		client := d.clients.ForSubscription(subscriptionId.SubscriptionId)
+		if _, ok := visible[client]; !ok {
+			subscriptions, err := client.ResourceManager.SubscriptionsClient.ListComplete(ctx)
+			if err != nil {
+				return fmt.Errorf("listing Subscriptions: %+v", err)
+			}
+			visible[client] = make(map[string]clients.Subscription, len(subscriptions))
+			for _, subscription := range subscriptions {
+				if subscription.SubscriptionId != nil {
+					visible[client][strings.ToLower(*subscription.SubscriptionId)] = subscription
+				}
+			}
+		}
+
+		subscription, ok := visible[client][strings.ToLower(subscriptionId.SubscriptionId)]
+		if !ok {
+			errs = append(errs, fmt.Errorf("the Subscription %q isn't visible to the principal, so it can't be checked against the allowlist", subscriptionId.SubscriptionId))
+			continue
+		}
+		if err := allowlist.CheckSubscription(subscriptionId.SubscriptionId, pointer.From(subscription.TenantId), pointer.From(subscription.Tags)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).Info("The Tenant and Subscriptions are within the allowlist", slog.String("tenant_ids", strings.Join(d.clients.TenantIDs(), ",")), slog.Int("subscriptions", len(subscriptionIds)))
+	return nil
+}
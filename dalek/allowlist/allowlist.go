@@ -0,0 +1,117 @@
+package allowlist
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-uuid"
+)
+
+// Allowlist is the Tenants and Subscriptions which the dalek is allowed to run against, so that a mis-set environment
+// variable can't point it at production. Each list which is specified must contain the target - for example:
+//
+//	allowlist:
+//	  tenants: [00000000-0000-0000-0000-000000000000]
+//	  subscriptions: [11111111-1111-1111-1111-111111111111]
+//	  subscription-tags:
+//	    purpose: testing
+type Allowlist struct {
+	Tenants       []string `json:"tenants,omitempty" yaml:"tenants"`
+	Subscriptions []string `json:"subscriptions,omitempty" yaml:"subscriptions"`
+
+	// SubscriptionTags are the tags which each Subscription must have, the keys being compared case-insensitively and
+	// the values exactly
+	SubscriptionTags map[string]string `json:"subscriptionTags,omitempty" yaml:"subscription-tags"`
+}
+
+// Enabled returns whether anything is specified within the Allowlist - when it isn't, every target is allowed
+func (a Allowlist) Enabled() bool {
+	return len(a.Tenants) > 0 || len(a.Subscriptions) > 0 || len(a.SubscriptionTags) > 0
+}
+
+// Validate returns an error when any of the Tenant or Subscription IDs aren't UUIDs, which is most likely a typo
+func (a Allowlist) Validate() error {
+	for _, v := range a.Tenants {
+		if _, err := uuid.ParseUUID(v); err != nil {
+			return fmt.Errorf("allowlist.tenants: %q isn't a Tenant ID", v)
+		}
+	}
+	for _, v := range a.Subscriptions {
+		if _, err := uuid.ParseUUID(v); err != nil {
+			return fmt.Errorf("allowlist.subscriptions: %q isn't a Subscription ID", v)
+		}
+	}
+	return nil
+}
+
+// CheckTenant returns an error when the Tenant isn't allowed
+func (a Allowlist) CheckTenant(tenantId string) error {
+	if len(a.Tenants) == 0 {
+		return nil
+	}
+	if tenantId == "" {
+		return fmt.Errorf("the Tenant ID isn't known, so it can't be checked against the allowlist")
+	}
+	if !slices.ContainsFunc(a.Tenants, equalFold(tenantId)) {
+		return fmt.Errorf("the Tenant %q isn't within the allowlist", tenantId)
+	}
+	return nil
+}
+
+// CheckSubscription returns an error when the Subscription isn't allowed - either because its ID or the Tenant it's
+// within isn't within the allowlist, or since it doesn't have each of the required tags
+func (a Allowlist) CheckSubscription(subscriptionId, tenantId string, tags map[string]string) error {
+	if len(a.Subscriptions) > 0 && !slices.ContainsFunc(a.Subscriptions, equalFold(subscriptionId)) {
+		return fmt.Errorf("the Subscription %q isn't within the allowlist", subscriptionId)
+	}
+	if err := a.CheckTenant(tenantId); err != nil {
+		return fmt.Errorf("the Subscription %q is within a Tenant which isn't allowed: %+v", subscriptionId, err)
+	}
+
+	keys := make([]string, 0, len(a.SubscriptionTags))
+	for k := range a.SubscriptionTags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		expected := a.SubscriptionTags[key]
+		value, ok := tagValue(tags, key)
+		if !ok {
+			return fmt.Errorf("the Subscription %q doesn't have the tag %q required by the allowlist", subscriptionId, key)
+		}
+		if value != expected {
+			return fmt.Errorf("the Subscription %q has the tag %q with the value %q but the allowlist requires %q", subscriptionId, key, value, expected)
+		}
+	}
+
+	return nil
+}
+
+func (a Allowlist) String() string {
+	if !a.Enabled() {
+		return "<none>"
+	}
+	tags := make([]string, 0, len(a.SubscriptionTags))
+	for k, v := range a.SubscriptionTags {
+		tags = append(tags, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(tags)
+	return fmt.Sprintf("Tenants %q, Subscriptions %q, Subscription Tags %q", a.Tenants, a.Subscriptions, tags)
+}
+
+func tagValue(tags map[string]string, key string) (string, bool) {
+	for k, v := range tags {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func equalFold(value string) func(string) bool {
+	return func(v string) bool {
+		return strings.EqualFold(v, value)
+	}
+}
@@ -0,0 +1,174 @@
+package checkpoint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+type state struct {
+	StartedAt time.Time `json:"startedAt"`
+
+	// Fingerprint identifies the options which determine what the run processes, so that a run with different options
+	// can't resume from this state
+	Fingerprint string `json:"fingerprint"`
+
+	Completed map[string]time.Time `json:"completed"`
+}
+
+// Checkpoint records the units of work (such as a Cleaner for a given Subscription or Resource Group) which have
+// been completed to a state file as the run progresses, so that an interrupted run can be resumed.
+type Checkpoint struct {
+	path  string
+	state state
+
+	mu sync.Mutex
+}
+
+// Open returns a Checkpoint persisted to the specified path - when resuming the work completed by the previous run
+// is loaded from this file, otherwise any existing state is discarded. The fingerprint identifies the options used
+// for this run, resuming fails when the previous run used different options.
+func Open(path string, fingerprint string, resume bool) (*Checkpoint, error) {
+	c := &Checkpoint{
+		path: path,
+		state: state{
+			StartedAt:   time.Now().UTC(),
+			Fingerprint: fingerprint,
+			Completed:   make(map[string]time.Time),
+		},
+	}
+
+	if resume {
+		data, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("reading the state file %q: %+v", path, err)
+		}
+		if err == nil {
+			if err := json.Unmarshal(data, &c.state); err != nil {
+				return nil, fmt.Errorf("parsing the state file %q: %+v", path, err)
+			}
+			if c.state.Fingerprint != fingerprint {
+				return nil, fmt.Errorf("the state file %q was written by a run with different options - either run with the same options, or without resuming", path)
+			}
+			if c.state.Completed == nil {
+				c.state.Completed = make(map[string]time.Time)
+			}
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.write(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Len returns the number of units of work which have been completed
+func (c *Checkpoint) Len() int {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.state.Completed)
+}
+
+// IsComplete returns whether the unit of work identified by the specified parts has been completed. This always
+// returns false on a nil Checkpoint, so that callers needn't check whether progress is being recorded.
+func (c *Checkpoint) IsComplete(parts ...string) bool {
+	if c == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.state.Completed[key(parts)]
+	return ok
+}
+
+// Complete records that the unit of work identified by the specified parts has been completed - this is a no-op on
+// a nil Checkpoint.
+func (c *Checkpoint) Complete(parts ...string) error {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.state.Completed[key(parts)] = time.Now().UTC()
+	return c.write()
+}
+
+// Remove removes the state file, once the run has completed without any errors so that there's nothing left to
+// resume - this is a no-op on a nil Checkpoint.
+func (c *Checkpoint) Remove() error {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("removing the state file %q: %+v", c.path, err)
+	}
+	return nil
+}
+
+// write persists the state, writing to a temporary file first so that the state file is never left half-written
+func (c *Checkpoint) write() error {
+	data, err := json.MarshalIndent(c.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshalling the state: %+v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return fmt.Errorf("creating a temporary file for the state: %+v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing the state to %q: %+v", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing %q: %+v", tmp.Name(), err)
+	}
+
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return fmt.Errorf("writing the state file %q: %+v", c.path, err)
+	}
+
+	return nil
+}
+
+func key(parts []string) string {
+	output := make([]string, 0, len(parts))
+	for _, v := range parts {
+		output = append(output, strings.ToLower(v))
+	}
+	return strings.Join(output, "|")
+}
+
+type checkpointContextKey struct{}
+
+// WithCheckpoint returns a copy of ctx which carries the specified Checkpoint
+func WithCheckpoint(ctx context.Context, checkpoint *Checkpoint) context.Context {
+	return context.WithValue(ctx, checkpointContextKey{}, checkpoint)
+}
+
+// FromContext returns the Checkpoint carried by ctx, or nil when ctx doesn't carry one
+func FromContext(ctx context.Context) *Checkpoint {
+	checkpoint, _ := ctx.Value(checkpointContextKey{}).(*Checkpoint)
+	return checkpoint
+}
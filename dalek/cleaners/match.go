@@ -0,0 +1,77 @@
+package cleaners
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-azure-helpers/lang/pointer"
+	"github.com/hashicorp/go-azure-helpers/resourcemanager/commonids"
+	"github.com/hashicorp/go-azure-helpers/resourcemanager/systemdata"
+	"github.com/hashicorp/go-azure-sdk/resource-manager/resources/2022-09-01/resourcegroups"
+	"github.com/jackofallops/azurerm-dalek/clients"
+	"github.com/jackofallops/azurerm-dalek/dalek/matcher"
+)
+
+// matchResource returns whether a resource deleted by a Subscription Cleaner should be deleted, along with the rule
+// which matched or excluded it. The prefix is compared with the name of the Resource Group containing the resource,
+// and when the resource was created is only looked up when the Matcher needs it.
+func matchResource(ctx context.Context, client *clients.AzureClient, m matcher.Matcher, resourceGroupId commonids.ResourceGroupId, resourceType string, resourceId string, tags *map[string]string) (bool, string, error) {
+	object := matcher.Object{
+		Name: resourceGroupId.ResourceGroupName,
+		Tags: pointer.From(tags),
+	}
+	if m.NeedsCreatedTime(object) {
+		createdTime, err := resourceCreatedTime(ctx, client, resourceGroupId, resourceType, resourceId)
+		if err != nil {
+			return false, "", fmt.Errorf("determining when %s was created: %+v", resourceId, err)
+		}
+		object.CreatedTime = createdTime
+	}
+
+	matched, rule := m.Match(object)
+	return matched, rule, nil
+}
+
+// systemDataCreatedTime returns when the resource was created according to its System Data, or nil when that's
+// unavailable
+func systemDataCreatedTime(input *systemdata.SystemData) *time.Time {
+	if input == nil || input.CreatedAt == "" {
+		return nil
+	}
+	createdTime, err := time.Parse(time.RFC3339, input.CreatedAt)
+	if err != nil {
+		return nil
+	}
+	return &createdTime
+}
+
+// resourceCreatedTime returns when the resource was created, which Resource Manager only returns when listing the
+// resources within its Resource Group using `$expand=createdTime` - or nil when that's unavailable
+func resourceCreatedTime(ctx context.Context, client *clients.AzureClient, resourceGroupId commonids.ResourceGroupId, resourceType string, resourceId string) (*time.Time, error) {
+	opts := resourcegroups.ResourcesListByResourceGroupOperationOptions{
+		Expand: pointer.To("createdTime"),
+		Filter: pointer.To(fmt.Sprintf("resourceType eq '%s'", resourceType)),
+	}
+	resp, err := client.ResourceManager.ResourcesGroupsClient.ResourcesListByResourceGroupComplete(ctx, resourceGroupId, opts)
+	if err != nil {
+		return nil, fmt.Errorf("listing the %s resources within %s: %+v", resourceType, resourceGroupId, err)
+	}
+
+	for _, item := range resp.Items {
+		if item.Id == nil || !strings.EqualFold(*item.Id, resourceId) {
+			continue
+		}
+		if item.CreatedTime == nil {
+			return nil, nil
+		}
+		createdTime, err := item.GetCreatedTimeAsTime()
+		if err != nil {
+			return nil, fmt.Errorf("parsing the createdTime %q for %s: %+v", *item.CreatedTime, resourceId, err)
+		}
+		return createdTime, nil
+	}
+
+	return nil, nil
+}
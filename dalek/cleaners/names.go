@@ -0,0 +1,35 @@
+package cleaners
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Names returns the names of every registered SubscriptionCleaner and ResourceGroupCleaner
+func Names() []string {
+	output := make([]string, 0, len(SubscriptionCleaners)+len(ResourceGroupCleaners))
+	for _, cleaner := range SubscriptionCleaners {
+		output = append(output, cleaner.Name())
+	}
+	for _, cleaner := range ResourceGroupCleaners {
+		output = append(output, cleaner.Name())
+	}
+	return output
+}
+
+// ValidateNames returns an error when any of the specified names isn't that of a registered Cleaner, so that a typo
+// in the Cleaners to run (or skip) doesn't go unnoticed
+func ValidateNames(names []string) error {
+	registered := Names()
+	unknown := make([]string, 0)
+	for _, name := range names {
+		if !slices.ContainsFunc(registered, func(v string) bool { return strings.EqualFold(v, name) }) {
+			unknown = append(unknown, fmt.Sprintf("%q", name))
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("the Cleaners %s aren't registered", strings.Join(unknown, ", "))
+	}
+	return nil
+}
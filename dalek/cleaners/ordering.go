@@ -0,0 +1,88 @@
+package cleaners
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// dependentCleaner is implemented by both SubscriptionCleaner and ResourceGroupCleaner, allowing
+// the same ordering logic to be used for both kinds of Cleaner
+type dependentCleaner[T any] interface {
+	Name() string
+	RunsAfter() []T
+}
+
+// OrderedSubscriptionCleaners returns the registered SubscriptionCleaners grouped into stages, where
+// each stage only contains Cleaners whose dependencies have been run in a prior stage.
+func OrderedSubscriptionCleaners() ([][]SubscriptionCleaner, error) {
+	return orderCleaners(SubscriptionCleaners)
+}
+
+// OrderedResourceGroupCleaners returns the registered ResourceGroupCleaners grouped into stages, where
+// each stage only contains Cleaners whose dependencies have been run in a prior stage.
+func OrderedResourceGroupCleaners() ([][]ResourceGroupCleaner, error) {
+	return orderCleaners(ResourceGroupCleaners)
+}
+
+// orderCleaners builds a DAG from the dependencies declared by each Cleaner and then returns the Cleaners
+// in topological order. Cleaners within a stage are independent of one another and can run in parallel.
+func orderCleaners[T dependentCleaner[T]](input []T) ([][]T, error) {
+	positions := make(map[string]int, len(input))
+	inDegree := make(map[string]int, len(input))
+	for i, cleaner := range input {
+		if _, exists := positions[cleaner.Name()]; exists {
+			return nil, fmt.Errorf("the Cleaner %q is registered more than once", cleaner.Name())
+		}
+		positions[cleaner.Name()] = i
+		inDegree[cleaner.Name()] = 0
+	}
+
+	dependents := make(map[string][]string, len(input))
+	for _, cleaner := range input {
+		for _, dependency := range cleaner.RunsAfter() {
+			if _, ok := positions[dependency.Name()]; !ok {
+				return nil, fmt.Errorf("the Cleaner %q depends on %q which isn't registered", cleaner.Name(), dependency.Name())
+			}
+			inDegree[cleaner.Name()]++
+			dependents[dependency.Name()] = append(dependents[dependency.Name()], cleaner.Name())
+		}
+	}
+
+	stages := make([][]T, 0)
+	remaining := len(input)
+	for remaining > 0 {
+		ready := make([]string, 0)
+		for name, degree := range inDegree {
+			if degree == 0 {
+				ready = append(ready, name)
+			}
+		}
+		if len(ready) == 0 {
+			blocked := make([]string, 0, len(inDegree))
+			for name := range inDegree {
+				blocked = append(blocked, name)
+			}
+			sort.Strings(blocked)
+			return nil, fmt.Errorf("the dependencies between the Cleaners [%s] contain a cycle", strings.Join(blocked, ", "))
+		}
+
+		// keep the registration order within a stage so that runs are deterministic
+		sort.Slice(ready, func(i, j int) bool {
+			return positions[ready[i]] < positions[ready[j]]
+		})
+
+		stage := make([]T, 0, len(ready))
+		for _, name := range ready {
+			stage = append(stage, input[positions[name]])
+			delete(inDegree, name)
+			for _, dependent := range dependents[name] {
+				inDegree[dependent]--
+			}
+		}
+		stages = append(stages, stage)
+		remaining -= len(ready)
+	}
+
+	return stages, nil
+}
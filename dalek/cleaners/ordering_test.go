@@ -0,0 +1,119 @@
+package cleaners
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testCleaner struct {
+	name      string
+	runsAfter []*testCleaner
+}
+
+func (c *testCleaner) Name() string {
+	return c.name
+}
+
+func (c *testCleaner) RunsAfter() []*testCleaner {
+	return c.runsAfter
+}
+
+func TestOrderCleaners(t *testing.T) {
+	a := &testCleaner{name: "a"}
+	b := &testCleaner{name: "b"}
+	c := &testCleaner{name: "c", runsAfter: []*testCleaner{a}}
+	d := &testCleaner{name: "d", runsAfter: []*testCleaner{b, c}}
+	unregistered := &testCleaner{name: "unregistered"}
+	orphan := &testCleaner{name: "orphan", runsAfter: []*testCleaner{unregistered}}
+
+	cycleA := &testCleaner{name: "cycle-a"}
+	cycleB := &testCleaner{name: "cycle-b", runsAfter: []*testCleaner{cycleA}}
+	cycleA.runsAfter = []*testCleaner{cycleB}
+	self := &testCleaner{name: "self"}
+	self.runsAfter = []*testCleaner{self}
+
+	testData := []struct {
+		name     string
+		input    []*testCleaner
+		expected [][]string
+
+		// err is a substring of the expected error, or empty when no error is expected
+		err string
+	}{
+		{
+			name:     "empty",
+			input:    []*testCleaner{},
+			expected: [][]string{},
+		},
+		{
+			name:     "independent Cleaners keep their registration order",
+			input:    []*testCleaner{b, a},
+			expected: [][]string{{"b", "a"}},
+		},
+		{
+			name:     "dependencies run in an earlier stage",
+			input:    []*testCleaner{d, c, b, a},
+			expected: [][]string{{"b", "a"}, {"c"}, {"d"}},
+		},
+		{
+			name:  "duplicate",
+			input: []*testCleaner{a, b, a},
+			err:   `the Cleaner "a" is registered more than once`,
+		},
+		{
+			name:  "unregistered dependency",
+			input: []*testCleaner{a, orphan},
+			err:   `the Cleaner "orphan" depends on "unregistered" which isn't registered`,
+		},
+		{
+			name:  "cycle",
+			input: []*testCleaner{a, cycleA, cycleB},
+			err:   "the dependencies between the Cleaners [cycle-a, cycle-b] contain a cycle",
+		},
+		{
+			name:  "depends on itself",
+			input: []*testCleaner{self},
+			err:   "the dependencies between the Cleaners [self] contain a cycle",
+		},
+	}
+
+	for _, v := range testData {
+		t.Run(v.name, func(t *testing.T) {
+			stages, err := orderCleaners(v.input)
+			if v.err != "" {
+				if err == nil {
+					t.Fatalf("expected an error containing %q but got none", v.err)
+				}
+				if !strings.Contains(err.Error(), v.err) {
+					t.Fatalf("expected an error containing %q but got: %+v", v.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error but got: %+v", err)
+			}
+
+			actual := make([][]string, 0, len(stages))
+			for _, stage := range stages {
+				names := make([]string, 0, len(stage))
+				for _, cleaner := range stage {
+					names = append(names, cleaner.Name())
+				}
+				actual = append(actual, names)
+			}
+			if !reflect.DeepEqual(actual, v.expected) {
+				t.Fatalf("expected the stages %q but got %q", v.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRegisteredCleanersAreOrdered(t *testing.T) {
+	if _, err := OrderedSubscriptionCleaners(); err != nil {
+		t.Fatalf("ordering the Subscription Cleaners: %+v", err)
+	}
+	if _, err := OrderedResourceGroupCleaners(); err != nil {
+		t.Fatalf("ordering the Resource Group Cleaners: %+v", err)
+	}
+}
@@ -0,0 +1,19 @@
+package cleaners
+
+import (
+	"github.com/hashicorp/go-azure-helpers/resourcemanager/commonids"
+	"github.com/hashicorp/go-azure-helpers/resourcemanager/resourceids"
+	"github.com/jackofallops/azurerm-dalek/dalek/plan"
+	"github.com/jackofallops/azurerm-dalek/dalek/report"
+)
+
+// subscriptionPlanItem returns the plan Item for an object deleted by a SubscriptionCleaner
+func subscriptionPlanItem(kind report.Kind, subscriptionId commonids.SubscriptionId, id resourceids.ResourceId, name string, tags *map[string]string) plan.Item {
+	return plan.Item{
+		Kind:       kind,
+		ID:         id.ID(),
+		Name:       name,
+		Scope:      subscriptionId.SubscriptionId,
+		Attributes: plan.TagAttributes(tags),
+	}
+}
@@ -0,0 +1,17 @@
+package cleaners
+
+import (
+	"github.com/hashicorp/go-azure-helpers/resourcemanager/commonids"
+	"github.com/hashicorp/go-azure-helpers/resourcemanager/resourceids"
+	"github.com/jackofallops/azurerm-dalek/dalek/report"
+)
+
+// subscriptionEntry returns the report Entry for an object deleted (or purged) by a SubscriptionCleaner
+func subscriptionEntry(kind report.Kind, subscriptionId commonids.SubscriptionId, id resourceids.ResourceId, name string) report.Entry {
+	return report.Entry{
+		ID:    id.ID(),
+		Name:  name,
+		Kind:  kind,
+		Scope: subscriptionId.SubscriptionId,
+	}
+}
@@ -3,7 +3,7 @@ package cleaners
 import (
 	"context"
 	"fmt"
-	"log"
+	"net/http"
 
 	"github.com/hashicorp/go-azure-helpers/lang/pointer"
 	"github.com/hashicorp/go-azure-helpers/resourcemanager/commonids"
@@ -11,6 +11,7 @@ import (
 	"github.com/hashicorp/go-azure-sdk/resource-manager/compute/2022-03-03/gallerysharingupdate"
 	"github.com/jackofallops/azurerm-dalek/clients"
 	"github.com/jackofallops/azurerm-dalek/dalek/options"
+	"github.com/jackofallops/azurerm-dalek/dalek/retry"
 )
 
 var _ ResourceGroupCleaner = computeGalleryCleaner{}
@@ -21,14 +22,21 @@ func (c computeGalleryCleaner) Name() string {
 	return "Compute Galleries"
 }
 
-func (c computeGalleryCleaner) Cleanup(ctx context.Context, id commonids.ResourceGroupId, client *clients.AzureClient, o options.Options) error {
+func (c computeGalleryCleaner) RunsAfter() []ResourceGroupCleaner {
+	return []ResourceGroupCleaner{
+		removeLocksFromResourceGroupCleaner{},
+	}
+}
+
+func (c computeGalleryCleaner) Discover(ctx context.Context, id commonids.ResourceGroupId, client *clients.AzureClient, o options.Options) ([]Action, error) {
 	computeClient := client.ResourceManager.ComputeClient
 
 	computeGalleries, err := computeClient.Galleries.ListByResourceGroupComplete(ctx, id)
 	if err != nil {
-		return fmt.Errorf("listing Compute Galleries for %s", id)
+		return nil, fmt.Errorf("listing Compute Galleries for %s: %+v", id, err)
 	}
 
+	actions := make([]Action, 0)
 	for _, g := range computeGalleries.Items {
 		if g.Id == nil {
 			continue
@@ -40,27 +48,37 @@ func (c computeGalleryCleaner) Cleanup(ctx context.Context, id commonids.Resourc
 
 		galleryID, err := commonids.ParseSharedImageGalleryIDInsensitively(*g.Id)
 		if err != nil {
-			return err
-		}
-
-		if !o.ActuallyDelete {
-			log.Printf("[INFO] would have deleted %s", galleryID)
+			return nil, err
 		}
 
-		// Ensure gallery is not shared as this prevents deletion
-		payload := gallerysharingupdate.SharingUpdate{
-			OperationType: gallerysharingupdate.SharingUpdateOperationTypesReset,
-		}
-		if err := computeClient.GallerySharingUpdate.GallerySharingProfileUpdateThenPoll(ctx, *galleryID, payload); err != nil {
-			return fmt.Errorf("resetting sharing profile for %s: %w", id, err)
-		}
-
-		if _, err := computeClient.Galleries.Delete(ctx, *galleryID); err != nil {
-			return fmt.Errorf("deleting %s: %w", id, err)
-		}
+		actions = append(actions,
+			Action{
+				// Ensure gallery is not shared as this prevents deletion
+				Description: "Reset the sharing profile of Shared Image Gallery",
+				ResourceID:  galleryID.ID(),
+				Execute: func(ctx context.Context) error {
+					payload := gallerysharingupdate.SharingUpdate{
+						OperationType: gallerysharingupdate.SharingUpdateOperationTypesReset,
+					}
+					return retry.Do(ctx, fmt.Sprintf("resetting the sharing profile for %s", galleryID), func(ctx context.Context) (*http.Response, error) {
+						return nil, computeClient.GallerySharingUpdate.GallerySharingProfileUpdateThenPoll(ctx, *galleryID, payload)
+					})
+				},
+			},
+			Action{
+				Description: "Delete Shared Image Gallery",
+				ResourceID:  galleryID.ID(),
+				Execute: func(ctx context.Context) error {
+					return retry.Do(ctx, fmt.Sprintf("deleting %s", galleryID), func(ctx context.Context) (*http.Response, error) {
+						resp, err := computeClient.Galleries.Delete(ctx, *galleryID)
+						return resp.HttpResponse, err
+					})
+				},
+			},
+		)
 	}
 
-	return nil
+	return actions, nil
 }
 
 func (c computeGalleryCleaner) ResourceTypes() []string {
@@ -3,13 +3,14 @@ package cleaners
 import (
 	"context"
 	"fmt"
-	"log"
+	"net/http"
 
 	"github.com/hashicorp/go-azure-helpers/resourcemanager/commonids"
 	"github.com/hashicorp/go-azure-sdk/resource-manager/datafactory/2018-06-01/factories"
 	"github.com/hashicorp/go-azure-sdk/resource-manager/datafactory/2018-06-01/integrationruntimes"
 	"github.com/jackofallops/azurerm-dalek/clients"
 	"github.com/jackofallops/azurerm-dalek/dalek/options"
+	"github.com/jackofallops/azurerm-dalek/dalek/retry"
 )
 
 var _ ResourceGroupCleaner = dataFactoryCleaner{}
@@ -20,14 +21,21 @@ func (c dataFactoryCleaner) Name() string {
 	return "Remove Data Factory instances"
 }
 
-func (c dataFactoryCleaner) Cleanup(ctx context.Context, id commonids.ResourceGroupId, client *clients.AzureClient, o options.Options) error {
+func (c dataFactoryCleaner) RunsAfter() []ResourceGroupCleaner {
+	return []ResourceGroupCleaner{
+		removeLocksFromResourceGroupCleaner{},
+	}
+}
+
+func (c dataFactoryCleaner) Discover(ctx context.Context, id commonids.ResourceGroupId, client *clients.AzureClient, o options.Options) ([]Action, error) {
 	dfClient := client.ResourceManager.DataFactory
 
 	dataFactories, err := dfClient.Factories.ListByResourceGroupComplete(ctx, id)
 	if err != nil {
-		return fmt.Errorf("listing Data Factories for %s", id)
+		return nil, fmt.Errorf("listing Data Factories for %s: %+v", id, err)
 	}
 
+	actions := make([]Action, 0)
 	for _, d := range dataFactories.Items {
 		if d.Id == nil {
 			continue
@@ -35,14 +43,15 @@ func (c dataFactoryCleaner) Cleanup(ctx context.Context, id commonids.ResourceGr
 
 		dataFactoryID, err := integrationruntimes.ParseFactoryIDInsensitively(*d.Id)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		integrationRuntimes, err := dfClient.IntegrationRuntimes.ListByFactoryComplete(ctx, *dataFactoryID)
 		if err != nil {
-			return fmt.Errorf("listing Integration Runtimes for %s", dataFactoryID)
+			return nil, fmt.Errorf("listing Integration Runtimes for %s: %+v", dataFactoryID, err)
 		}
 
+		// the Integration Runtimes have to be removed before the Data Factory containing them
 		for _, i := range integrationRuntimes.Items {
 			if i.Id == nil {
 				continue
@@ -50,30 +59,34 @@ func (c dataFactoryCleaner) Cleanup(ctx context.Context, id commonids.ResourceGr
 
 			integrationRuntimeID, err := integrationruntimes.ParseIntegrationRuntimeIDInsensitively(*i.Id)
 			if err != nil {
-				return err
-			}
-
-			if !o.ActuallyDelete {
-				log.Printf("[INFO] would have deleted %s", integrationRuntimeID)
-				continue
+				return nil, err
 			}
 
-			if _, err := dfClient.IntegrationRuntimes.Delete(ctx, *integrationRuntimeID); err != nil {
-				return fmt.Errorf("deleting %s", integrationRuntimeID)
-			}
+			actions = append(actions, Action{
+				Description: "Delete Integration Runtime",
+				ResourceID:  integrationRuntimeID.ID(),
+				Execute: func(ctx context.Context) error {
+					return retry.Do(ctx, fmt.Sprintf("deleting %s", integrationRuntimeID), func(ctx context.Context) (*http.Response, error) {
+						resp, err := dfClient.IntegrationRuntimes.Delete(ctx, *integrationRuntimeID)
+						return resp.HttpResponse, err
+					})
+				},
+			})
 		}
 
-		if !o.ActuallyDelete {
-			log.Printf("[INFO] would have deleted %s", dataFactoryID)
-			return nil
-		}
-
-		if _, err := dfClient.Factories.Delete(ctx, factories.FactoryId(*dataFactoryID)); err != nil {
-			return fmt.Errorf("deleting %s", dataFactoryID)
-		}
+		actions = append(actions, Action{
+			Description: "Delete Data Factory",
+			ResourceID:  dataFactoryID.ID(),
+			Execute: func(ctx context.Context) error {
+				return retry.Do(ctx, fmt.Sprintf("deleting %s", dataFactoryID), func(ctx context.Context) (*http.Response, error) {
+					resp, err := dfClient.Factories.Delete(ctx, factories.FactoryId(*dataFactoryID))
+					return resp.HttpResponse, err
+				})
+			},
+		})
 	}
 
-	return nil
+	return actions, nil
 }
 
 func (c dataFactoryCleaner) ResourceTypes() []string {
@@ -3,7 +3,7 @@ package cleaners
 import (
 	"context"
 	"fmt"
-	"log"
+	"net/http"
 
 	"github.com/hashicorp/go-azure-helpers/lang/pointer"
 	"github.com/hashicorp/go-azure-helpers/resourcemanager/commonids"
@@ -12,7 +12,9 @@ import (
 	"github.com/hashicorp/go-azure-sdk/resource-manager/dataprotection/2024-04-01/backupvaults"
 	"github.com/hashicorp/go-azure-sdk/resource-manager/dataprotection/2024-04-01/deletedbackupinstances"
 	"github.com/jackofallops/azurerm-dalek/clients"
+	"github.com/jackofallops/azurerm-dalek/dalek/logging"
 	"github.com/jackofallops/azurerm-dalek/dalek/options"
+	"github.com/jackofallops/azurerm-dalek/dalek/retry"
 )
 
 var _ ResourceGroupCleaner = removeDataProtectionFromResourceGroupCleaner{}
@@ -23,107 +25,125 @@ func (removeDataProtectionFromResourceGroupCleaner) Name() string {
 	return "Removing Data Protection"
 }
 
-func (removeDataProtectionFromResourceGroupCleaner) Cleanup(ctx context.Context, id commonids.ResourceGroupId, client *clients.AzureClient, opts options.Options) error {
-	backupVaults, err := client.ResourceManager.DataProtection.BackupVaults.GetInResourceGroupComplete(ctx, id)
+func (removeDataProtectionFromResourceGroupCleaner) RunsAfter() []ResourceGroupCleaner {
+	return []ResourceGroupCleaner{
+		removeLocksFromResourceGroupCleaner{},
+	}
+}
+
+func (removeDataProtectionFromResourceGroupCleaner) Discover(ctx context.Context, id commonids.ResourceGroupId, client *clients.AzureClient, opts options.Options) ([]Action, error) {
+	logger := logging.FromContext(ctx)
+	dataProtection := client.ResourceManager.DataProtection
+	backupVaults, err := dataProtection.BackupVaults.GetInResourceGroupComplete(ctx, id)
 	if err != nil {
-		log.Printf("[DEBUG] Error retrieving the Backup Vaults within %s: %+v", id, err)
+		logger.Warn("Retrieving the Backup Vaults", logging.Err(err))
 	}
+
+	actions := make([]Action, 0)
 	for _, vault := range backupVaults.Items {
 		vaultId := backupvaults.NewBackupVaultID(id.SubscriptionId, id.ResourceGroupName, *vault.Name)
 
 		// disable soft-delete first, this will block the deletion of the vault if instances are soft-deleted
-		patch := backupvaults.PatchResourceRequestInput{
-			Properties: &backupvaults.PatchBackupVaultInput{
-				SecuritySettings: &backupvaults.SecuritySettings{
-					SoftDeleteSettings: &backupvaults.SoftDeleteSettings{
-						State: pointer.To(backupvaults.SoftDeleteStateOff),
+		actions = append(actions, Action{
+			Description:     "Disable soft delete for Backup Vault",
+			ResourceID:      vaultId.ID(),
+			ContinueOnError: true,
+			Execute: func(ctx context.Context) error {
+				patch := backupvaults.PatchResourceRequestInput{
+					Properties: &backupvaults.PatchBackupVaultInput{
+						SecuritySettings: &backupvaults.SecuritySettings{
+							SoftDeleteSettings: &backupvaults.SoftDeleteSettings{
+								State: pointer.To(backupvaults.SoftDeleteStateOff),
+							},
+						},
 					},
-				},
+				}
+				return retry.Do(ctx, fmt.Sprintf("disabling soft delete for %s", vaultId), func(ctx context.Context) (*http.Response, error) {
+					return nil, dataProtection.BackupVaults.UpdateThenPoll(ctx, vaultId, patch, backupvaults.DefaultUpdateOperationOptions())
+				})
 			},
-		}
-		if err := client.ResourceManager.DataProtection.BackupVaults.UpdateThenPoll(ctx, vaultId, patch, backupvaults.DefaultUpdateOperationOptions()); err != nil {
-			log.Printf("Failed to turn off Soft Delete for %s: %+v", vaultId, err)
-			continue
-		}
+		})
 
 		// We have to undelete items that were deleted when softdelete was enabled and then delete them again
 		deletedBackupInstanceVaultId := deletedbackupinstances.NewBackupVaultID(vaultId.SubscriptionId, vaultId.ResourceGroupName, vaultId.BackupVaultName)
-		deletedInstances, err := client.ResourceManager.DataProtection.DeletedBackupInstances.ListComplete(ctx, deletedBackupInstanceVaultId)
+		deletedInstances, err := dataProtection.DeletedBackupInstances.ListComplete(ctx, deletedBackupInstanceVaultId)
 		if err != nil {
-			log.Printf("deleted Backup Instances within %s was empty", deletedBackupInstanceVaultId)
+			logger.Debug("No deleted Backup Instances were found", logging.ResourceID(deletedBackupInstanceVaultId.ID()))
 			continue
 		}
 
+		instanceNames := make([]string, 0)
 		for _, deletedInstance := range deletedInstances.Items {
 			deletedInstanceId := deletedbackupinstances.NewDeletedBackupInstanceID(deletedBackupInstanceVaultId.SubscriptionId, deletedBackupInstanceVaultId.ResourceGroupName, deletedBackupInstanceVaultId.BackupVaultName, *deletedInstance.Name)
-
-			if !opts.ActuallyDelete {
-				log.Printf("[DEBUG] Would have deleted %s..", deletedInstanceId)
-				continue
-			}
-
-			log.Printf("[DEBUG] Deleting %s..", deletedInstanceId)
-			if err := client.ResourceManager.DataProtection.DeletedBackupInstances.UndeleteThenPoll(ctx, deletedInstanceId); err != nil {
-				log.Printf("[ERROR] deleting %s: %+v", deletedInstanceId, err)
-				// todo readd this when https://github.com/hashicorp/go-azure-sdk/issues/886 is resolved
-				// return fmt.Errorf("deleting %s: %+v", deletedInstanceId, err)
-			}
-			log.Printf("[DEBUG] Deleted %s.", deletedInstanceId)
+			actions = append(actions, Action{
+				Description: "Undelete deleted Backup Instance",
+				ResourceID:  deletedInstanceId.ID(),
+				// todo stop continuing on error when https://github.com/hashicorp/go-azure-sdk/issues/886 is resolved
+				ContinueOnError: true,
+				Execute: func(ctx context.Context) error {
+					return retry.Do(ctx, fmt.Sprintf("undeleting %s", deletedInstanceId), func(ctx context.Context) (*http.Response, error) {
+						return nil, dataProtection.DeletedBackupInstances.UndeleteThenPoll(ctx, deletedInstanceId)
+					})
+				},
+			})
+			instanceNames = append(instanceNames, *deletedInstance.Name)
 		}
 
-		// list the Backup Instances within it, those need to be removed first
+		// list the Backup Instances within it, those need to be removed first - along with those which were undeleted
 		backupInstancesVaultId := backupinstances.NewBackupVaultID(vaultId.SubscriptionId, vaultId.ResourceGroupName, vaultId.BackupVaultName)
-		instances, err := client.ResourceManager.DataProtection.BackupInstances.ListComplete(ctx, backupInstancesVaultId)
+		instances, err := dataProtection.BackupInstances.ListComplete(ctx, backupInstancesVaultId)
 		if err != nil {
-			return fmt.Errorf("listing Backup Instances within %s: %+v", backupInstancesVaultId, err)
+			return nil, fmt.Errorf("listing Backup Instances within %s: %+v", backupInstancesVaultId, err)
 		}
-
 		for _, instance := range instances.Items {
-			instanceId := backupinstances.NewBackupInstanceID(backupInstancesVaultId.SubscriptionId, backupInstancesVaultId.ResourceGroupName, backupInstancesVaultId.BackupVaultName, *instance.Name)
-			if !opts.ActuallyDelete {
-				log.Printf("[DEBUG] Would have deleted %s..", instanceId)
-				continue
-			}
-
-			log.Printf("[DEBUG] Deleting %s..", instanceId)
-			if err := client.ResourceManager.DataProtection.BackupInstances.DeleteThenPoll(ctx, instanceId, backupinstances.DefaultDeleteOperationOptions()); err != nil {
-				return fmt.Errorf("deleting %s: %+v", instanceId, err)
-			}
-			log.Printf("[DEBUG] Deleted %s.", instanceId)
+			instanceNames = append(instanceNames, *instance.Name)
+		}
+
+		for _, instanceName := range instanceNames {
+			instanceId := backupinstances.NewBackupInstanceID(backupInstancesVaultId.SubscriptionId, backupInstancesVaultId.ResourceGroupName, backupInstancesVaultId.BackupVaultName, instanceName)
+			actions = append(actions, Action{
+				Description: "Delete Backup Instance",
+				ResourceID:  instanceId.ID(),
+				Execute: func(ctx context.Context) error {
+					return retry.Do(ctx, fmt.Sprintf("deleting %s", instanceId), func(ctx context.Context) (*http.Response, error) {
+						return nil, dataProtection.BackupInstances.DeleteThenPoll(ctx, instanceId, backupinstances.DefaultDeleteOperationOptions())
+					})
+				},
+			})
 		}
 
 		// then let's go through and remove the Backup Policies
 		backupPoliciesVaultId := backuppolicies.NewBackupVaultID(vaultId.SubscriptionId, vaultId.ResourceGroupName, vaultId.BackupVaultName)
-		policies, err := client.ResourceManager.DataProtection.BackupPolicies.ListComplete(ctx, backupPoliciesVaultId)
+		policies, err := dataProtection.BackupPolicies.ListComplete(ctx, backupPoliciesVaultId)
 		if err != nil {
-			return fmt.Errorf("listing Backup Policies within %s: %+v", backupPoliciesVaultId, err)
+			return nil, fmt.Errorf("listing Backup Policies within %s: %+v", backupPoliciesVaultId, err)
 		}
 		for _, policy := range policies.Items {
 			policyId := backuppolicies.NewBackupPolicyID(backupPoliciesVaultId.SubscriptionId, backupPoliciesVaultId.ResourceGroupName, backupPoliciesVaultId.BackupVaultName, *policy.Name)
-			if !opts.ActuallyDelete {
-				log.Printf("[DEBUG] Would have deleted %s..", policyId)
-				continue
-			}
-
-			log.Printf("[DEBUG] Deleting %s..", policyId)
-			if _, err := client.ResourceManager.DataProtection.BackupPolicies.Delete(ctx, policyId); err != nil {
-				return fmt.Errorf("deleting %s: %+v", policyId, err)
-			}
-			log.Printf("[DEBUG] Deleted %s.", policyId)
+			actions = append(actions, Action{
+				Description: "Delete Backup Policy",
+				ResourceID:  policyId.ID(),
+				Execute: func(ctx context.Context) error {
+					return retry.Do(ctx, fmt.Sprintf("deleting %s", policyId), func(ctx context.Context) (*http.Response, error) {
+						resp, err := dataProtection.BackupPolicies.Delete(ctx, policyId)
+						return resp.HttpResponse, err
+					})
+				},
+			})
 		}
 
-		if !opts.ActuallyDelete {
-			log.Printf("[DEBUG] Would have deleted %s..", vaultId)
-			continue
-		}
-		log.Printf("[DEBUG] Deleting %s..", vaultId)
-		if err := client.ResourceManager.DataProtection.BackupVaults.DeleteThenPoll(ctx, vaultId); err != nil {
-			return fmt.Errorf("deleting %s: %+v", vaultId, err)
-		}
-		log.Printf("[DEBUG] Deleted %s.", vaultId)
+		actions = append(actions, Action{
+			Description: "Delete Backup Vault",
+			ResourceID:  vaultId.ID(),
+			Execute: func(ctx context.Context) error {
+				return retry.Do(ctx, fmt.Sprintf("deleting %s", vaultId), func(ctx context.Context) (*http.Response, error) {
+					return nil, dataProtection.BackupVaults.DeleteThenPoll(ctx, vaultId)
+				})
+			},
+		})
 	}
 
-	return nil
+	return actions, nil
 }
 
 func (removeDataProtectionFromResourceGroupCleaner) ResourceTypes() []string {
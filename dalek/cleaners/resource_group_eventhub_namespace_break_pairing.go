@@ -3,7 +3,6 @@ package cleaners
 import (
 	"context"
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/hashicorp/go-azure-helpers/lang/response"
@@ -11,6 +10,7 @@ import (
 	"github.com/hashicorp/go-azure-sdk/resource-manager/eventhub/2021-11-01/disasterrecoveryconfigs"
 	"github.com/hashicorp/go-azure-sdk/sdk/client/pollers"
 	"github.com/jackofallops/azurerm-dalek/clients"
+	"github.com/jackofallops/azurerm-dalek/dalek/logging"
 	"github.com/jackofallops/azurerm-dalek/dalek/options"
 )
 
@@ -28,56 +28,64 @@ func (eventhubNamespaceBreakPairingCleaner) Name() string {
 	return "EventHub Namespace - Break Pairing"
 }
 
-func (eventhubNamespaceBreakPairingCleaner) Cleanup(ctx context.Context, id commonids.ResourceGroupId, client *clients.AzureClient, opts options.Options) error {
+func (eventhubNamespaceBreakPairingCleaner) RunsAfter() []ResourceGroupCleaner {
+	return []ResourceGroupCleaner{
+		removeLocksFromResourceGroupCleaner{},
+	}
+}
+
+func (eventhubNamespaceBreakPairingCleaner) Discover(ctx context.Context, id commonids.ResourceGroupId, client *clients.AzureClient, opts options.Options) ([]Action, error) {
+	logger := logging.FromContext(ctx)
 	eventhubNamespaceClient := client.ResourceManager.EventHubNameSpaceClient
 	disasterRecoveryClient := client.ResourceManager.EventHubDisasterRecoveryClient
 	namespacesInResourceGroup, err := eventhubNamespaceClient.ListByResourceGroupComplete(ctx, id)
 	if err != nil {
-		log.Printf("[DEBUG] Error retrieving the EventHub Namespaces within %s: %+v", id, err)
+		logger.Warn("Retrieving the EventHub Namespaces", logging.Err(err))
 	}
 
+	actions := make([]Action, 0)
 	for _, namespace := range namespacesInResourceGroup.Items {
 		namespaceId, err := disasterrecoveryconfigs.ParseNamespaceIDInsensitively(*namespace.Id)
 		if err != nil {
-			log.Printf("[ERROR] Parsing EventHub Namespace ID %q: %+v", *namespace.Id, err)
+			logger.Error("Parsing EventHub Namespace ID", logging.ResourceID(*namespace.Id), logging.Err(err))
 			continue
 		}
-		log.Printf("[DEBUG] Finding Disaster Recovery Configs within %s", *namespaceId)
+		logger.Debug("Finding Disaster Recovery Configs", logging.ResourceID(namespaceId.ID()))
 		configs, err := disasterRecoveryClient.ListComplete(ctx, *namespaceId)
 		if err != nil {
-			return fmt.Errorf("finding Disaster Recovery Configs within %s: %+v", *namespaceId, err)
+			return nil, fmt.Errorf("finding Disaster Recovery Configs within %s: %+v", *namespaceId, err)
 		}
 
 		for _, config := range configs.Items {
 			configId, err := disasterrecoveryconfigs.ParseDisasterRecoveryConfigIDInsensitively(*config.Id)
 			if err != nil {
-				return fmt.Errorf("parsing the Disaster Recovery Config ID %q: %+v", *config.Id, err)
-			}
-
-			if !opts.ActuallyDelete {
-				log.Printf("[DEBUG] Would have broken the pairing for %s..", *configId)
-				continue
+				return nil, fmt.Errorf("parsing the Disaster Recovery Config ID %q: %+v", *config.Id, err)
 			}
 
-			log.Printf("[DEBUG] Breaking Pairing for %s..", *configId)
-			if resp, err := disasterRecoveryClient.BreakPairing(ctx, *configId); err != nil {
-				if !response.WasNotFound(resp.HttpResponse) {
-					return fmt.Errorf("breaking pairing for %s: %+v", *configId, err)
-				}
-			}
-			log.Printf("[DEBUG] Polling until Pairing is broken for %s..", *configId)
-			pollerType := eventhubNamespaceBreakPairingPoller{
-				client:   disasterRecoveryClient,
-				configId: *configId,
-			}
-			poller := pollers.NewPoller(pollerType, 30*time.Second, pollers.DefaultNumberOfDroppedConnectionsToAllow)
-			if err := poller.PollUntilDone(ctx); err != nil {
-				return fmt.Errorf("polling until the Pairing is broken for %s: %+v", *configId, err)
-			}
-			log.Printf("[DEBUG] Pairing Broken for %s", *configId)
+			actions = append(actions, Action{
+				Description: "Break the pairing",
+				ResourceID:  configId.ID(),
+				Execute: func(ctx context.Context) error {
+					if resp, err := disasterRecoveryClient.BreakPairing(ctx, *configId); err != nil {
+						if !response.WasNotFound(resp.HttpResponse) {
+							return err
+						}
+					}
+					logging.FromContext(ctx).Debug("Polling until the pairing is broken", logging.ResourceID(configId.ID()))
+					pollerType := eventhubNamespaceBreakPairingPoller{
+						client:   disasterRecoveryClient,
+						configId: *configId,
+					}
+					poller := pollers.NewPoller(pollerType, 30*time.Second, pollers.DefaultNumberOfDroppedConnectionsToAllow)
+					if err := poller.PollUntilDone(ctx); err != nil {
+						return fmt.Errorf("polling until the Pairing is broken: %+v", err)
+					}
+					return nil
+				},
+			})
 		}
 	}
-	return nil
+	return actions, nil
 }
 
 type eventhubNamespaceBreakPairingPoller struct {
@@ -3,7 +3,6 @@ package cleaners
 import (
 	"context"
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/hashicorp/go-azure-helpers/resourcemanager/commonids"
@@ -20,14 +19,21 @@ func (graphServicesAccountCleaner) Name() string {
 	return "Graph Services Account"
 }
 
-func (graphServicesAccountCleaner) Cleanup(ctx context.Context, id commonids.ResourceGroupId, client *clients.AzureClient, o options.Options) error {
+func (graphServicesAccountCleaner) RunsAfter() []ResourceGroupCleaner {
+	return []ResourceGroupCleaner{
+		removeLocksFromResourceGroupCleaner{},
+	}
+}
+
+func (graphServicesAccountCleaner) Discover(ctx context.Context, id commonids.ResourceGroupId, client *clients.AzureClient, o options.Options) ([]Action, error) {
 	c := client.ResourceManager.GraphServicesClient.Graphservicesprods
 
 	graphServiceAccounts, err := c.AccountsListByResourceGroupComplete(ctx, id)
 	if err != nil {
-		return fmt.Errorf("listing Graph Service Accounts for %s: %w", id, err)
+		return nil, fmt.Errorf("listing Graph Service Accounts for %s: %w", id, err)
 	}
 
+	actions := make([]Action, 0)
 	for _, g := range graphServiceAccounts.Items {
 		if g.Id == nil {
 			continue
@@ -35,38 +41,38 @@ func (graphServicesAccountCleaner) Cleanup(ctx context.Context, id commonids.Res
 
 		graphServiceAccountID, err := graphservicesprods.ParseAccountID(*g.Id)
 		if err != nil {
-			return err
-		}
-
-		if !o.ActuallyDelete {
-			log.Printf("would have deleted %s", graphServiceAccountID)
-			continue
+			return nil, err
 		}
 
-		// For unknown reasons, Graph Service Accounts can get into a weird state where deleting them returns an internal server error
-		// but if we update the account, then delete, it *usually* works so we'll attempt that here ¯\_(ツ)_/¯
-		graphServiceAccount := graphservicesprods.AccountResource{
-			Location: g.Location,
-			Properties: graphservicesprods.AccountResourceProperties{
-				AppId: g.Properties.AppId,
+		actions = append(actions, Action{
+			Description: "Delete Graph Services Account",
+			ResourceID:  graphServiceAccountID.ID(),
+			Execute: func(ctx context.Context) error {
+				// For unknown reasons, Graph Service Accounts can get into a weird state where deleting them returns an internal server error
+				// but if we update the account, then delete, it *usually* works so we'll attempt that here ¯\_(ツ)_/¯
+				graphServiceAccount := graphservicesprods.AccountResource{
+					Location: g.Location,
+					Properties: graphservicesprods.AccountResourceProperties{
+						AppId: g.Properties.AppId,
+					},
+				}
+
+				if err := c.AccountsCreateAndUpdateThenPoll(ctx, *graphServiceAccountID, graphServiceAccount); err != nil {
+					return fmt.Errorf("updating %s: %w", graphServiceAccountID, err)
+				}
+
+				// In the rare event that Azure returns a 500, this would retry until timeout, to prevent polling for ~6 hours use a new context that is much shorter.
+				ctxForDelete, cancel := context.WithTimeout(ctx, time.Minute*1)
+				defer cancel()
+				if _, err := c.AccountsDelete(ctxForDelete, *graphServiceAccountID); err != nil {
+					return fmt.Errorf("deleting %s: %w", graphServiceAccountID, err)
+				}
+				return nil
 			},
-		}
-
-		if err := c.AccountsCreateAndUpdateThenPoll(ctx, *graphServiceAccountID, graphServiceAccount); err != nil {
-			return fmt.Errorf("updating %s: %w", graphServiceAccountID, err)
-		}
-
-		// In the rare event that Azure returns a 500, this would retry until timeout, to prevent polling for ~6 hours use a new context that is much shorter.
-		ctxForDelete, cancel := context.WithTimeout(ctx, time.Minute*1)
-		if _, err := c.AccountsDelete(ctxForDelete, *graphServiceAccountID); err != nil {
-			cancel()
-			return fmt.Errorf("deleting %s: %w", graphServiceAccountID, err)
-		}
-
-		cancel()
+		})
 	}
 
-	return nil
+	return actions, nil
 }
 
 func (graphServicesAccountCleaner) ResourceTypes() []string {
@@ -2,7 +2,8 @@ package cleaners
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/hashicorp/go-azure-helpers/lang/response"
@@ -10,7 +11,9 @@ import (
 	"github.com/hashicorp/go-azure-sdk/resource-manager/resources/2020-05-01/managementlocks"
 	"github.com/hashicorp/go-azure-sdk/sdk/client/pollers"
 	"github.com/jackofallops/azurerm-dalek/clients"
+	"github.com/jackofallops/azurerm-dalek/dalek/logging"
 	"github.com/jackofallops/azurerm-dalek/dalek/options"
+	"github.com/jackofallops/azurerm-dalek/dalek/retry"
 )
 
 var _ ResourceGroupCleaner = removeLocksFromResourceGroupCleaner{}
@@ -21,52 +24,66 @@ func (removeLocksFromResourceGroupCleaner) Name() string {
 	return "Removing Locks.."
 }
 
-func (removeLocksFromResourceGroupCleaner) Cleanup(ctx context.Context, id commonids.ResourceGroupId, client *clients.AzureClient, opts options.Options) error {
+func (removeLocksFromResourceGroupCleaner) RunsAfter() []ResourceGroupCleaner {
+	// a Write or Delete lock would prevent us from doing anything else, as such every other
+	// ResourceGroupCleaner declares that it runs after this one
+	return nil
+}
+
+func (removeLocksFromResourceGroupCleaner) Discover(ctx context.Context, id commonids.ResourceGroupId, client *clients.AzureClient, opts options.Options) ([]Action, error) {
+	logger := logging.FromContext(ctx)
 	locks, err := client.ResourceManager.LocksClient.ListAtResourceGroupLevel(ctx, id, managementlocks.DefaultListAtResourceGroupLevelOperationOptions())
 	if err != nil {
-		log.Printf("[DEBUG] Error obtaining Resource Group Locks : %+v", err)
+		logger.Warn("Retrieving the Resource Group Locks", logging.Err(err))
 	}
 
+	actions := make([]Action, 0)
 	if model := locks.Model; model != nil {
 		for _, lock := range *model {
 			if lock.Id == nil {
-				log.Printf("[DEBUG]   Lock with nil id on %q", id.ResourceGroupName)
+				logger.Debug("Skipping Lock with a nil ID")
 				continue
 			}
 			lockId, err := managementlocks.ParseScopedLockID(*lock.Id)
 			if err != nil {
-				log.Printf("[ERROR] Parsing Scoped Lock ID %q: %+v", *lock.Id, err)
+				logger.Error("Parsing Scoped Lock ID", logging.ResourceID(*lock.Id), logging.Err(err))
 				continue
 			}
 
 			if lock.Name == nil {
-				log.Printf("[DEBUG]   Lock %s with nil name on %q", id, id.ResourceGroupName)
+				logger.Debug("Skipping Lock with a nil name", logging.ResourceID(lockId.ID()))
 				continue
 			}
 
-			log.Printf("[DEBUG]   Attemping to remove lock %s from: %s", id, id.ResourceGroupName)
-
-			if _, err := client.ResourceManager.LocksClient.DeleteByScope(ctx, *lockId); err != nil {
-				log.Printf("[DEBUG]   Unable to delete lock %s on resource group %q", *lock.Name, id.ResourceGroupName)
-				continue
-			}
+			actions = append(actions, Action{
+				Description:     "Remove Lock",
+				ResourceID:      lockId.ID(),
+				ContinueOnError: true,
+				Execute: func(ctx context.Context) error {
+					if err := retry.Do(ctx, fmt.Sprintf("deleting %s", *lockId), func(ctx context.Context) (*http.Response, error) {
+						resp, err := client.ResourceManager.LocksClient.DeleteByScope(ctx, *lockId)
+						return resp.HttpResponse, err
+					}); err != nil {
+						return err
+					}
 
-			// Deletion of locks has been observed to be delayed (asynch) for some scopes.
-			// Use a simple poller to wait for lock removal, otherwise RG deletion will fail if any delay occurs
-			log.Printf("[DEBUG]   Polling for lock deletion of: %s", *lockId)
-			pollerType := lockDeletePoller{
-				client: client.ResourceManager.LocksClient,
-				lockId: *lockId,
-			}
-			poller := pollers.NewPoller(pollerType, 5*time.Second, pollers.DefaultNumberOfDroppedConnectionsToAllow)
-			if err := poller.PollUntilDone(ctx); err != nil {
-				log.Printf("[ERROR] Polling for deletion is broken for lock %s: %+v", *lockId, err)
-				continue
-			}
-			log.Printf("[DEBUG] Lock delete is complete for %s", *lockId)
+					// Deletion of locks has been observed to be delayed (asynch) for some scopes.
+					// Use a simple poller to wait for lock removal, otherwise RG deletion will fail if any delay occurs
+					logging.FromContext(ctx).Debug("Polling until the Lock is removed", logging.ResourceID(lockId.ID()))
+					pollerType := lockDeletePoller{
+						client: client.ResourceManager.LocksClient,
+						lockId: *lockId,
+					}
+					poller := pollers.NewPoller(pollerType, 5*time.Second, pollers.DefaultNumberOfDroppedConnectionsToAllow)
+					if err := poller.PollUntilDone(ctx); err != nil {
+						return fmt.Errorf("polling until the Lock is removed: %+v", err)
+					}
+					return nil
+				},
+			})
 		}
 	}
-	return nil
+	return actions, nil
 }
 
 func (removeLocksFromResourceGroupCleaner) ResourceTypes() []string {
@@ -3,7 +3,6 @@ package cleaners
 import (
 	"context"
 	"fmt"
-	"log"
 	"net/http"
 
 	"github.com/hashicorp/go-azure-helpers/lang/pointer"
@@ -13,6 +12,7 @@ import (
 	baseSdkClient "github.com/hashicorp/go-azure-sdk/sdk/client"
 	"github.com/jackofallops/azurerm-dalek/clients"
 	"github.com/jackofallops/azurerm-dalek/dalek/options"
+	"github.com/jackofallops/azurerm-dalek/dalek/retry"
 )
 
 var _ ResourceGroupCleaner = networkSubnetPropertiesCleaner{}
@@ -23,62 +23,78 @@ func (networkSubnetPropertiesCleaner) Name() string {
 	return "Remove Network Subnet Options.."
 }
 
-func (networkSubnetPropertiesCleaner) Cleanup(ctx context.Context, id commonids.ResourceGroupId, client *clients.AzureClient, opts options.Options) error {
+func (networkSubnetPropertiesCleaner) RunsAfter() []ResourceGroupCleaner {
+	return []ResourceGroupCleaner{
+		removeLocksFromResourceGroupCleaner{},
+	}
+}
+
+func (networkSubnetPropertiesCleaner) Discover(ctx context.Context, id commonids.ResourceGroupId, client *clients.AzureClient, opts options.Options) ([]Action, error) {
 	networkList, err := client.ResourceManager.NetworkClient.List(ctx, id)
 	if err != nil {
-		return fmt.Errorf("retrieving networks for resource group %s: %+v", id, err)
+		return nil, fmt.Errorf("retrieving networks for resource group %s: %+v", id, err)
 	}
 
+	actions := make([]Action, 0)
 	if networkList.Model != nil {
 		for _, net := range *networkList.Model {
 			networkId, err := commonids.ParseVirtualNetworkID(*net.Id)
 			if err != nil {
-				return fmt.Errorf("parsing Virtual Network ID %s: %+v", *net.Id, err)
+				return nil, fmt.Errorf("parsing Virtual Network ID %s: %+v", *net.Id, err)
 			}
 
 			subnetList, err := client.ResourceManager.NetworkSubnetClient.List(ctx, *networkId)
 			if err != nil {
-				return fmt.Errorf("retrieving subnets for resource group %s: %+v", id, err)
+				return nil, fmt.Errorf("retrieving subnets for resource group %s: %+v", id, err)
 			}
 
 			if subnetList.Model != nil {
 				for _, sub := range *subnetList.Model {
-					// Updates/deletes are not allowed on a subnet if there are existing orphan network integragions.
-					// Call a special purge API that will clean up any integrations before we attempt to update.
-					log.Printf("[DEBUG] Purging unused network integrations for Network Subnet %s", *sub.Id)
+					subnetId, err := commonids.ParseSubnetID(*sub.Id)
+					if err != nil {
+						return nil, err
+					}
 
 					webProviderLocationId := resourceproviders.ProviderLocationId{
 						SubscriptionId: id.SubscriptionId,
 						LocationName:   *net.Location,
 					}
 
-					err := purgeUnusedVnetIntegrations(ctx, webProviderLocationId, *sub.Id, client.ResourceManager.WebResourceProviderClient)
-					if err != nil {
-						// log the error only, this may not be required for next step and should not return
-						log.Printf("[ERROR] purging unused network integrations for Subnet %s: %+v", *sub.Id, err)
-					}
-
-					log.Printf("[DEBUG] Updating default properties for Network Subnet %s", *sub.Id)
-
-					subnetId, err := commonids.ParseSubnetID(*sub.Id)
-					if err != nil {
-						return err
-					}
-
-					// update delegation and private policies to their default values (disabled)
-					sub.Properties.Delegations = pointer.To([]subnets.Delegation{})
-					sub.Properties.PrivateEndpointNetworkPolicies = pointer.To(subnets.VirtualNetworkPrivateEndpointNetworkPoliciesDisabled)
-
-					if _, err := client.ResourceManager.NetworkSubnetClient.CreateOrUpdate(ctx, *subnetId, sub); err != nil {
-						// There are many cases where setting Delegations to None will fail (orphan SALs mostly).
-						// If this errors, log the error only and continue with other vnets
-						log.Printf("[ERROR] updating properties for Subnet %s: %+v", subnetId, err)
-					}
+					actions = append(actions,
+						Action{
+							// Updates/deletes are not allowed on a subnet if there are existing orphan network integragions.
+							// Call a special purge API that will clean up any integrations before we attempt to update.
+							Description: "Purge unused network integrations for Network Subnet",
+							ResourceID:  *sub.Id,
+							// this may not be required for the next step, so only the error is logged
+							ContinueOnError: true,
+							Execute: func(ctx context.Context) error {
+								return purgeUnusedVnetIntegrations(ctx, webProviderLocationId, *sub.Id, client.ResourceManager.WebResourceProviderClient)
+							},
+						},
+						Action{
+							Description: "Reset the delegations and private endpoint network policies for Network Subnet",
+							ResourceID:  subnetId.ID(),
+							// There are many cases where setting Delegations to None will fail (orphan SALs mostly).
+							// If this errors, log the error only and continue with other vnets
+							ContinueOnError: true,
+							Execute: func(ctx context.Context) error {
+								// update delegation and private policies to their default values (disabled)
+								sub.Properties.Delegations = pointer.To([]subnets.Delegation{})
+								sub.Properties.PrivateEndpointNetworkPolicies = pointer.To(subnets.VirtualNetworkPrivateEndpointNetworkPoliciesDisabled)
+
+								return retry.Do(ctx, fmt.Sprintf("updating %s", subnetId), func(ctx context.Context) (*http.Response, error) {
+									resp, err := client.ResourceManager.NetworkSubnetClient.CreateOrUpdate(ctx, *subnetId, sub)
+									return resp.HttpResponse, err
+								})
+							},
+						},
+					)
 				}
 			}
 		}
 	}
-	return nil
+	return actions, nil
 }
 
 func (networkSubnetPropertiesCleaner) ResourceTypes() []string {
@@ -3,7 +3,7 @@ package cleaners
 import (
 	"context"
 	"fmt"
-	"log"
+	"net/http"
 	"strings"
 
 	"github.com/hashicorp/go-azure-helpers/lang/pointer"
@@ -11,7 +11,9 @@ import (
 	"github.com/hashicorp/go-azure-sdk/resource-manager/notificationhubs/2023-09-01/namespaces"
 	"github.com/hashicorp/go-azure-sdk/resource-manager/resourcegraph/2024-04-01/resources"
 	"github.com/jackofallops/azurerm-dalek/clients"
+	"github.com/jackofallops/azurerm-dalek/dalek/logging"
 	"github.com/jackofallops/azurerm-dalek/dalek/options"
+	"github.com/jackofallops/azurerm-dalek/dalek/retry"
 )
 
 type notificationHubNamespacesCleaner struct{}
@@ -22,29 +24,35 @@ func (c notificationHubNamespacesCleaner) Name() string {
 	return "Notification Hub Namespaces"
 }
 
-func (c notificationHubNamespacesCleaner) Cleanup(ctx context.Context, id commonids.ResourceGroupId, client *clients.AzureClient, opts options.Options) error {
-	// Notification Hub Namespaces don't clean up cleanly when deleting the Resource Group, so let's remove these
+func (c notificationHubNamespacesCleaner) RunsAfter() []ResourceGroupCleaner {
+	return []ResourceGroupCleaner{
+		removeLocksFromResourceGroupCleaner{},
+	}
+}
 
-	log.Printf("[DEBUG] Retrieving Notification Hub Namespaces in %s..", id)
+func (c notificationHubNamespacesCleaner) Discover(ctx context.Context, id commonids.ResourceGroupId, client *clients.AzureClient, opts options.Options) ([]Action, error) {
+	// Notification Hub Namespaces don't clean up cleanly when deleting the Resource Group, so let's remove these
+	logging.FromContext(ctx).Debug("Retrieving Notification Hub Namespaces")
 	namespaceIds, err := c.findNamespacesIDs(ctx, id, client)
 	if err != nil {
-		return fmt.Errorf("finding the Namespace IDs within %s: %+v", id, err)
+		return nil, fmt.Errorf("finding the Namespace IDs within %s: %+v", id, err)
 	}
 
+	actions := make([]Action, 0, len(*namespaceIds))
 	for _, namespaceId := range *namespaceIds {
-		if !opts.ActuallyDelete {
-			log.Printf("[DEBUG] Would have deleted %s..", namespaceId)
-			continue
-		}
-
-		log.Printf("[DEBUG] Deleting %s..", namespaceId)
-		if _, err := client.ResourceManager.NotificationHubNamespaceClient.Delete(ctx, namespaceId); err != nil {
-			return fmt.Errorf("deleting %s: %+v", namespaceId, err)
-		}
-		log.Printf("[DEBUG] Deleted %s.", namespaceId)
+		actions = append(actions, Action{
+			Description: "Delete Notification Hub Namespace",
+			ResourceID:  namespaceId.ID(),
+			Execute: func(ctx context.Context) error {
+				return retry.Do(ctx, fmt.Sprintf("deleting %s", namespaceId), func(ctx context.Context) (*http.Response, error) {
+					resp, err := client.ResourceManager.NotificationHubNamespaceClient.Delete(ctx, namespaceId)
+					return resp.HttpResponse, err
+				})
+			},
+		})
 	}
 
-	return nil
+	return actions, nil
 }
 
 func (c notificationHubNamespacesCleaner) ResourceTypes() []string {
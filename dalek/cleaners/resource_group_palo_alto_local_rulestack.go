@@ -3,7 +3,6 @@ package cleaners
 import (
 	"context"
 	"fmt"
-	"log"
 
 	"github.com/hashicorp/go-azure-helpers/lang/pointer"
 	"github.com/hashicorp/go-azure-helpers/lang/response"
@@ -14,6 +13,7 @@ import (
 	"github.com/hashicorp/go-azure-sdk/resource-manager/paloaltonetworks/2022-08-29/localrulestacks"
 	"github.com/hashicorp/go-azure-sdk/resource-manager/paloaltonetworks/2022-08-29/prefixlistlocalrulestack"
 	"github.com/jackofallops/azurerm-dalek/clients"
+	"github.com/jackofallops/azurerm-dalek/dalek/logging"
 	"github.com/jackofallops/azurerm-dalek/dalek/options"
 )
 
@@ -25,14 +25,23 @@ func (paloAltoLocalRulestackCleaner) Name() string {
 	return "Removing Rulestack Rules"
 }
 
-func (paloAltoLocalRulestackCleaner) Cleanup(ctx context.Context, id commonids.ResourceGroupId, client *clients.AzureClient, opts options.Options) error {
+func (paloAltoLocalRulestackCleaner) RunsAfter() []ResourceGroupCleaner {
+	return []ResourceGroupCleaner{
+		removeLocksFromResourceGroupCleaner{},
+	}
+}
+
+func (c paloAltoLocalRulestackCleaner) Discover(ctx context.Context, id commonids.ResourceGroupId, client *clients.AzureClient, opts options.Options) ([]Action, error) {
+	logger := logging.FromContext(ctx)
 	rulestacksClient := client.ResourceManager.PaloAlto.LocalRulestacks
 
 	rulestacks, err := rulestacksClient.ListByResourceGroupComplete(ctx, id)
 	if err != nil {
-		log.Printf("[DEBUG] Error retrieving the Palo Alto Local Rulestacks within %s: %+v", id, err)
+		logger.Warn("Retrieving the Palo Alto Local Rulestacks", logging.Err(err))
 	}
 
+	actions := make([]Action, 0)
+
 	// Rules
 	rulesClient := client.ResourceManager.PaloAlto.LocalRules
 	for _, rg := range rulestacks.Items {
@@ -42,35 +51,21 @@ func (paloAltoLocalRulestackCleaner) Cleanup(ctx context.Context, id commonids.R
 			if response.WasStatusCode(rulesInRulestack.HttpResponse, 500) || response.WasNotFound(rulesInRulestack.HttpResponse) || response.WasStatusCode(rulesInRulestack.HttpResponse, 502) {
 				continue
 			}
-			return fmt.Errorf("listing rules for %s: %+v", id, err)
+			return nil, fmt.Errorf("listing rules for %s: %+v", id, err)
 		}
 		if model := rulesInRulestack.Model; model != nil {
 			for _, v := range *model {
 				ruleId, err := localrules.ParseLocalRuleIDInsensitively(pointer.From(v.Id))
 				if err != nil {
-					return fmt.Errorf("parsing rule %s: %+v", pointer.From(v.Id), err)
-				}
-
-				if !opts.ActuallyDelete {
-					log.Printf("[DEBUG] Would have deleted the Local Rule for %s..", *ruleId)
-					continue
-				}
-
-				log.Printf("[DEBUG] Deleting %s..", *ruleId)
-				if _, err := rulesClient.Delete(ctx, *ruleId); err != nil {
-					// (@jackofallops) Commit process can get stuck in an unmanageable state, results in need to contact PA Support
-					// Switching to non-blocking on failure but reporting error
-					// return fmt.Errorf("deleting rule %s from rulestack %s: %+v", ruleId, id, err)
-					log.Printf("[ERROR] deleting rule %s from rulestack %s: %+v", ruleId, rulestackId, err)
-					log.Printf("[DEBUG] Support ticket required to remove %s", rulestackId)
-					return nil
+					return nil, fmt.Errorf("parsing rule %s: %+v", pointer.From(v.Id), err)
 				}
-				log.Printf("[DEBUG] Deleting %s..", *ruleId)
+				actions = append(actions, c.deleteAction("Delete Local Rule", ruleId.ID(), rulestackId.ID(), func(ctx context.Context) error {
+					_, err := rulesClient.Delete(ctx, *ruleId)
+					return err
+				}))
 			}
 		}
-		if _, err := rulestacksClient.Commit(ctx, localrulestacks.NewLocalRulestackID(rulestackId.SubscriptionId, rulestackId.ResourceGroupName, rulestackId.LocalRulestackName)); err != nil {
-			return fmt.Errorf("failed to commit changes to %s cannot delete, support ticket may be required to remove resource", rulestackId)
-		}
+		actions = append(actions, c.commitAction(rulestacksClient, localrulestacks.NewLocalRulestackID(rulestackId.SubscriptionId, rulestackId.ResourceGroupName, rulestackId.LocalRulestackName)))
 	}
 
 	// FQDN Lists
@@ -82,35 +77,21 @@ func (paloAltoLocalRulestackCleaner) Cleanup(ctx context.Context, id commonids.R
 			if response.WasStatusCode(fqdnInRulestack.HttpResponse, 500) || response.WasStatusCode(fqdnInRulestack.HttpResponse, 502) || response.WasNotFound(fqdnInRulestack.HttpResponse) {
 				continue
 			}
-			return fmt.Errorf("listing FQDNs for %s: %+v", id, err)
+			return nil, fmt.Errorf("listing FQDNs for %s: %+v", id, err)
 		}
 		if model := fqdnInRulestack.Model; model != nil {
 			for _, v := range *model {
 				fqdnId, err := fqdnlistlocalrulestack.ParseLocalRulestackFqdnListIDInsensitively(pointer.From(v.Id))
 				if err != nil {
-					return fmt.Errorf("parsing %q as a fqdn list id: %+v", pointer.From(v.Id), err)
-				}
-
-				if !opts.ActuallyDelete {
-					log.Printf("[DEBUG] Would have deleted the FQDN for %s..", *fqdnId)
-					continue
+					return nil, fmt.Errorf("parsing %q as a fqdn list id: %+v", pointer.From(v.Id), err)
 				}
-
-				log.Printf("[DEBUG] Deleting %s..", *fqdnId)
-				if _, err := fqdnClient.Delete(ctx, *fqdnId); err != nil {
-					// (@jackofallops) Commit process can get stuck in an unmanageable state, results in need to contact PA Support
-					// Switching to non-blocking on failure but reporting error
-					// return fmt.Errorf("deleting fqdn %s from rulestack %s: %+v", fqdnId, id, err)
-					log.Printf("[ERROR] deleting fqdn %s from rulestack %s: %+v", fqdnId, rulestackId, err)
-					log.Printf("[DEBUG] Support ticket required to remove %s", rulestackId)
-					return nil
-				}
-				log.Printf("[DEBUG] Deleted %s..", *fqdnId)
+				actions = append(actions, c.deleteAction("Delete FQDN List", fqdnId.ID(), rulestackId.ID(), func(ctx context.Context) error {
+					_, err := fqdnClient.Delete(ctx, *fqdnId)
+					return err
+				}))
 			}
 		}
-		if _, err := rulestacksClient.Commit(ctx, localrulestacks.NewLocalRulestackID(rulestackId.SubscriptionId, rulestackId.ResourceGroupName, rulestackId.LocalRulestackName)); err != nil {
-			return fmt.Errorf("failed to commit changes to %s cannot delete, support ticket may be required to remove resource", rulestackId)
-		}
+		actions = append(actions, c.commitAction(rulestacksClient, localrulestacks.NewLocalRulestackID(rulestackId.SubscriptionId, rulestackId.ResourceGroupName, rulestackId.LocalRulestackName)))
 	}
 
 	// Certificates
@@ -118,19 +99,23 @@ func (paloAltoLocalRulestackCleaner) Cleanup(ctx context.Context, id commonids.R
 	for _, rg := range rulestacks.Items {
 		// Remove inspection config - blocks removal of certs if referenced
 		rulestackId := certificateobjectlocalrulestack.NewLocalRulestackID(id.SubscriptionId, id.ResourceGroupName, pointer.From(rg.Name))
-		rs, err := rulestacksClient.Get(ctx, localrulestacks.LocalRulestackId(rulestackId))
+		localRulestackId := localrulestacks.NewLocalRulestackID(rulestackId.SubscriptionId, rulestackId.ResourceGroupName, rulestackId.LocalRulestackName)
+		rs, err := rulestacksClient.Get(ctx, localRulestackId)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		sec := pointer.From(rs.Model.Properties.SecurityServices)
 		if pointer.From(sec.OutboundTrustCertificate) != "" || pointer.From(sec.OutboundUnTrustCertificate) != "" {
-			sec.OutboundTrustCertificate = nil
-			sec.OutboundUnTrustCertificate = nil
-			rs.Model.Properties.SecurityServices = pointer.To(sec)
-			localRulestackId := localrulestacks.NewLocalRulestackID(rulestackId.SubscriptionId, rulestackId.ResourceGroupName, rulestackId.LocalRulestackName)
-			if err = rulestacksClient.CreateOrUpdateThenPoll(ctx, localRulestackId, *rs.Model); err != nil {
-				return fmt.Errorf("removing certificate usage on %s: %+v", rulestackId, err)
-			}
+			actions = append(actions, Action{
+				Description: "Remove the certificate usage from Local Rulestack",
+				ResourceID:  localRulestackId.ID(),
+				Execute: func(ctx context.Context) error {
+					sec.OutboundTrustCertificate = nil
+					sec.OutboundUnTrustCertificate = nil
+					rs.Model.Properties.SecurityServices = pointer.To(sec)
+					return rulestacksClient.CreateOrUpdateThenPoll(ctx, localRulestackId, *rs.Model)
+				},
+			})
 		}
 		// Remove certs
 		certInRulestack, err := certClient.ListByLocalRulestacks(ctx, rulestackId)
@@ -138,25 +123,21 @@ func (paloAltoLocalRulestackCleaner) Cleanup(ctx context.Context, id commonids.R
 			if response.WasStatusCode(certInRulestack.HttpResponse, 500) || response.WasStatusCode(certInRulestack.HttpResponse, 502) || response.WasNotFound(certInRulestack.HttpResponse) {
 				continue
 			}
-			return fmt.Errorf("listing FQDNs for %s: %+v", id, err)
+			return nil, fmt.Errorf("listing Certificates for %s: %+v", id, err)
 		}
 		if model := certInRulestack.Model; model != nil {
 			for _, v := range *model {
-				if certId, err := certificateobjectlocalrulestack.ParseLocalRulestackCertificateID(pointer.From(v.Id)); err != nil && certId != nil {
-					if _, err := certClient.Delete(ctx, *certId); err != nil {
-						// (@jackofallops) Commit process can get stuck in an unmanageable state, results in need to contact PA Support
-						// Switching to non-blocking on failure but reporting error
-						// return fmt.Errorf("deleting certificate %s from rulestack %s: %+v", fqdnId, id, err)
-						log.Printf("[ERROR] deleting certificate %s from rulestack %s: %+v", certId, rulestackId, err)
-						log.Printf("[DEBUG] Support ticket required to remove %s", rulestackId)
-						return nil
-					}
+				certId, err := certificateobjectlocalrulestack.ParseLocalRulestackCertificateIDInsensitively(pointer.From(v.Id))
+				if err != nil {
+					return nil, fmt.Errorf("parsing %q as a certificate id: %+v", pointer.From(v.Id), err)
 				}
+				actions = append(actions, c.deleteAction("Delete Certificate", certId.ID(), rulestackId.ID(), func(ctx context.Context) error {
+					_, err := certClient.Delete(ctx, *certId)
+					return err
+				}))
 			}
 		}
-		if _, err := rulestacksClient.Commit(ctx, localrulestacks.NewLocalRulestackID(rulestackId.SubscriptionId, rulestackId.ResourceGroupName, rulestackId.LocalRulestackName)); err != nil {
-			return fmt.Errorf("failed to commit changes to %s cannot delete, support ticket may be required to remove resource", rulestackId)
-		}
+		actions = append(actions, c.commitAction(rulestacksClient, localRulestackId))
 	}
 
 	// Prefixes
@@ -168,28 +149,54 @@ func (paloAltoLocalRulestackCleaner) Cleanup(ctx context.Context, id commonids.R
 			if response.WasStatusCode(prefixInRulestack.HttpResponse, 500) || response.WasStatusCode(prefixInRulestack.HttpResponse, 502) || response.WasNotFound(prefixInRulestack.HttpResponse) {
 				continue
 			}
-			return fmt.Errorf("listing FQDNs for %s: %+v", id, err)
+			return nil, fmt.Errorf("listing Prefix Lists for %s: %+v", id, err)
 		}
 		if model := prefixInRulestack.Model; model != nil {
 			for _, v := range *model {
-				if prefixId, err := prefixlistlocalrulestack.ParseLocalRulestackPrefixListIDInsensitively(pointer.From(v.Id)); err != nil && prefixId != nil {
-					if _, err := prefixClient.Delete(ctx, *prefixId); err != nil {
-						// (@jackofallops) Commit process can get stuck in an unmanageable state, results in need to contact PA Support
-						// Switching to non-blocking on failure but reporting error
-						// return fmt.Errorf("deleting prefix %s from rulestack %s: %+v", prefixId, id, err)
-						log.Printf("[ERROR] deleting prefix %s from rulestack %s: %+v", prefixId, rulestackId, err)
-						log.Printf("[DEBUG] Support ticket required to remove %s", rulestackId)
-						return nil
-					}
+				prefixId, err := prefixlistlocalrulestack.ParseLocalRulestackPrefixListIDInsensitively(pointer.From(v.Id))
+				if err != nil {
+					return nil, fmt.Errorf("parsing %q as a prefix list id: %+v", pointer.From(v.Id), err)
 				}
+				actions = append(actions, c.deleteAction("Delete Prefix List", prefixId.ID(), rulestackId.ID(), func(ctx context.Context) error {
+					_, err := prefixClient.Delete(ctx, *prefixId)
+					return err
+				}))
 			}
 		}
-		if _, err := rulestacksClient.Commit(ctx, localrulestacks.NewLocalRulestackID(rulestackId.SubscriptionId, rulestackId.ResourceGroupName, rulestackId.LocalRulestackName)); err != nil {
-			return fmt.Errorf("failed to commit changes to %s cannot delete, support ticket may be required to remove resource", rulestackId)
-		}
+		actions = append(actions, c.commitAction(rulestacksClient, localrulestacks.NewLocalRulestackID(rulestackId.SubscriptionId, rulestackId.ResourceGroupName, rulestackId.LocalRulestackName)))
 	}
 
-	return nil
+	return actions, nil
+}
+
+// deleteAction returns an Action which deletes an object from the Local Rulestack
+func (paloAltoLocalRulestackCleaner) deleteAction(description, objectId, rulestackId string, deleteFunc func(ctx context.Context) error) Action {
+	return Action{
+		Description: description,
+		ResourceID:  objectId,
+		Execute: func(ctx context.Context) error {
+			if err := deleteFunc(ctx); err != nil {
+				// (@jackofallops) Commit process can get stuck in an unmanageable state, results in need to contact PA Support
+				logging.FromContext(ctx).Warn("A support ticket is required to remove the Local Rulestack", logging.ResourceID(rulestackId))
+				return err
+			}
+			return nil
+		},
+	}
+}
+
+// commitAction returns an Action which commits the changes made to the Local Rulestack
+func (paloAltoLocalRulestackCleaner) commitAction(client *localrulestacks.LocalRulestacksClient, rulestackId localrulestacks.LocalRulestackId) Action {
+	return Action{
+		Description: "Commit the changes to Local Rulestack",
+		ResourceID:  rulestackId.ID(),
+		Execute: func(ctx context.Context) error {
+			if _, err := client.Commit(ctx, rulestackId); err != nil {
+				return fmt.Errorf("failed to commit changes to %s cannot delete, support ticket may be required to remove resource", rulestackId)
+			}
+			return nil
+		},
+	}
 }
 
 func (paloAltoLocalRulestackCleaner) ResourceTypes() []string {
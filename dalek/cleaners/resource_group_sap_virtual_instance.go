@@ -3,7 +3,7 @@ package cleaners
 import (
 	"context"
 	"fmt"
-	"log"
+	"net/http"
 
 	"github.com/hashicorp/go-azure-helpers/lang/pointer"
 	"github.com/hashicorp/go-azure-helpers/lang/response"
@@ -15,6 +15,7 @@ import (
 	"github.com/hashicorp/go-uuid"
 	"github.com/jackofallops/azurerm-dalek/clients"
 	"github.com/jackofallops/azurerm-dalek/dalek/options"
+	"github.com/jackofallops/azurerm-dalek/dalek/retry"
 )
 
 var _ ResourceGroupCleaner = sapVirtualInstance{}
@@ -25,16 +26,23 @@ func (sapVirtualInstance) Name() string {
 	return "SAP Virtual Instance"
 }
 
-func (sapVirtualInstance) Cleanup(ctx context.Context, id commonids.ResourceGroupId, client *clients.AzureClient, o options.Options) error {
+func (sapVirtualInstance) RunsAfter() []ResourceGroupCleaner {
+	return []ResourceGroupCleaner{
+		removeLocksFromResourceGroupCleaner{},
+	}
+}
+
+func (sapVirtualInstance) Discover(ctx context.Context, id commonids.ResourceGroupId, client *clients.AzureClient, o options.Options) ([]Action, error) {
 	c := client.ResourceManager.WorkloadsClient.SAPVirtualInstances
 	resourceGroupsClient := client.ResourceManager.ResourcesGroupsClient
 	roleAssignmentsClient := client.ResourceManager.AuthorizationClient.RoleAssignments
 
 	instances, err := c.ListByResourceGroupComplete(ctx, id)
 	if err != nil {
-		return fmt.Errorf("listing SAP Virtual Instances on %s", id)
+		return nil, fmt.Errorf("listing SAP Virtual Instances on %s: %+v", id, err)
 	}
 
+	actions := make([]Action, 0)
 	for _, i := range instances.Items {
 		if i.Id == nil {
 			continue
@@ -42,17 +50,12 @@ func (sapVirtualInstance) Cleanup(ctx context.Context, id commonids.ResourceGrou
 
 		instanceID, err := sapvirtualinstances.ParseSapVirtualInstanceID(*i.Id)
 		if err != nil {
-			return err
-		}
-
-		if !o.ActuallyDelete {
-			log.Printf("[INFO] would have deleted %s", instanceID)
-			continue
+			return nil, err
 		}
 
 		instance, err := c.Get(ctx, *instanceID)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		if instance.Model == nil || instance.Model.Properties == nil {
@@ -66,50 +69,64 @@ func (sapVirtualInstance) Cleanup(ctx context.Context, id commonids.ResourceGrou
 
 			managedResourceGroup, err := resourceGroupsClient.Get(ctx, managedResourceGroupID)
 			if err != nil && !response.WasNotFound(managedResourceGroup.HttpResponse) {
-				return fmt.Errorf("retrieving %s: %w", managedResourceGroupID, err)
+				return nil, fmt.Errorf("retrieving %s: %w", managedResourceGroupID, err)
 			}
 
 			// If the managed resource group no longer exists, we need to reprovision it, and grant access to `Azure SAP Workloads Management`
 			// otherwise the deletion fails with an internal server error.
 			// These will be automatically removed again by the SAP Virtual Instance deletion process.
 			if response.WasNotFound(managedResourceGroup.HttpResponse) {
-				resourceGroup := resourcegroups.ResourceGroup{
-					Location: instance.Model.Location,
-					Name:     pointer.To(managedResourceGroupID.ResourceGroupName),
-				}
-				if _, err := resourceGroupsClient.CreateOrUpdate(ctx, managedResourceGroupID, resourceGroup); err != nil {
-					return fmt.Errorf("creating %s: %w", managedResourceGroupID, err)
-				}
-
-				roleAssignmentName, err := uuid.GenerateUUID()
-				if err != nil {
-					return err
-				}
-
-				scopeID := roleassignments.NewScopedRoleAssignmentID(managedResourceGroupID.ID(), roleAssignmentName)
-
-				roleUUID := "b24988ac-6180-42a0-ab88-20f7382dd24c"    // This is the `Contributor` built-in role
-				principalID := "00cc41ee-b6e1-4f2e-b3b9-b66547a967a5" // This is the `Azure SAP Workloads Management` enterprise app
-
-				roleAssignment := roleassignments.RoleAssignmentCreateParameters{
-					Properties: roleassignments.RoleAssignmentProperties{
-						PrincipalId:      principalID,
-						RoleDefinitionId: roledefinitions.NewScopedRoleDefinitionID(commonids.NewSubscriptionID(id.SubscriptionId).ID(), roleUUID).ID(),
+				actions = append(actions, Action{
+					Description: "Recreate the managed Resource Group for SAP Virtual Instance",
+					ResourceID:  managedResourceGroupID.ID(),
+					Execute: func(ctx context.Context) error {
+						resourceGroup := resourcegroups.ResourceGroup{
+							Location: instance.Model.Location,
+							Name:     pointer.To(managedResourceGroupID.ResourceGroupName),
+						}
+						if _, err := resourceGroupsClient.CreateOrUpdate(ctx, managedResourceGroupID, resourceGroup); err != nil {
+							return fmt.Errorf("creating %s: %w", managedResourceGroupID, err)
+						}
+
+						roleAssignmentName, err := uuid.GenerateUUID()
+						if err != nil {
+							return err
+						}
+
+						scopeID := roleassignments.NewScopedRoleAssignmentID(managedResourceGroupID.ID(), roleAssignmentName)
+
+						roleUUID := "b24988ac-6180-42a0-ab88-20f7382dd24c"    // This is the `Contributor` built-in role
+						principalID := "00cc41ee-b6e1-4f2e-b3b9-b66547a967a5" // This is the `Azure SAP Workloads Management` enterprise app
+
+						roleAssignment := roleassignments.RoleAssignmentCreateParameters{
+							Properties: roleassignments.RoleAssignmentProperties{
+								PrincipalId:      principalID,
+								RoleDefinitionId: roledefinitions.NewScopedRoleDefinitionID(commonids.NewSubscriptionID(id.SubscriptionId).ID(), roleUUID).ID(),
+							},
+						}
+						if _, err := roleAssignmentsClient.Create(ctx, scopeID, roleAssignment); err != nil {
+							return fmt.Errorf("creating %s: %w", scopeID, err)
+						}
+						return nil
 					},
-				}
-				if _, err := roleAssignmentsClient.Create(ctx, scopeID, roleAssignment); err != nil {
-					return fmt.Errorf("creating %s: %w", scopeID, err)
-				}
+				})
 			}
 		}
 
-		// No polling here as it could get stuck polling until the context expires when Azure errors during the async operation
-		if _, err := c.Delete(ctx, *instanceID); err != nil {
-			return fmt.Errorf("deleting %s: %w", *instanceID, err)
-		}
+		actions = append(actions, Action{
+			Description: "Delete SAP Virtual Instance",
+			ResourceID:  instanceID.ID(),
+			Execute: func(ctx context.Context) error {
+				// No polling here as it could get stuck polling until the context expires when Azure errors during the async operation
+				return retry.Do(ctx, fmt.Sprintf("deleting %s", *instanceID), func(ctx context.Context) (*http.Response, error) {
+					resp, err := c.Delete(ctx, *instanceID)
+					return resp.HttpResponse, err
+				})
+			},
+		})
 	}
 
-	return nil
+	return actions, nil
 }
 
 func (sapVirtualInstance) ResourceTypes() []string {
@@ -3,7 +3,7 @@ package cleaners
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 
 	"github.com/hashicorp/go-azure-helpers/lang/response"
@@ -12,6 +12,7 @@ import (
 	"github.com/hashicorp/go-azure-sdk/resource-manager/servicebus/2024-01-01/disasterrecoveryconfigs"
 	"github.com/hashicorp/go-azure-sdk/sdk/client/pollers"
 	"github.com/jackofallops/azurerm-dalek/clients"
+	"github.com/jackofallops/azurerm-dalek/dalek/logging"
 	"github.com/jackofallops/azurerm-dalek/dalek/options"
 )
 
@@ -23,59 +24,67 @@ func (serviceBusNamespaceBreakPairingCleaner) Name() string {
 	return "ServiceBus Namespace - Break Pairing"
 }
 
-func (serviceBusNamespaceBreakPairingCleaner) Cleanup(ctx context.Context, id commonids.ResourceGroupId, client *clients.AzureClient, opts options.Options) error {
+func (serviceBusNamespaceBreakPairingCleaner) RunsAfter() []ResourceGroupCleaner {
+	return []ResourceGroupCleaner{
+		removeLocksFromResourceGroupCleaner{},
+	}
+}
+
+func (serviceBusNamespaceBreakPairingCleaner) Discover(ctx context.Context, id commonids.ResourceGroupId, client *clients.AzureClient, opts options.Options) ([]Action, error) {
+	logger := logging.FromContext(ctx)
 	serviceBusClient := client.ResourceManager.ServiceBus
 	namespacesInResourceGroup, err := serviceBusClient.Namespaces.ListByResourceGroupComplete(ctx, id)
 	if err != nil {
-		log.Printf("[DEBUG] Error retrieving the ServiceBus Namespaces within %s: %+v", id, err)
+		logger.Warn("Retrieving the ServiceBus Namespaces", logging.Err(err))
 	}
 
+	actions := make([]Action, 0)
 	for _, namespace := range namespacesInResourceGroup.Items {
 		namespaceId, err := disasterrecoveryconfigs.ParseNamespaceIDInsensitively(*namespace.Id)
 		if err != nil {
-			log.Printf("[ERROR] Parsing ServiceBus Namespace ID %q: %+v", *namespace.Id, err)
+			logger.Error("Parsing ServiceBus Namespace ID", logging.ResourceID(*namespace.Id), logging.Err(err))
 			continue
 		}
-		log.Printf("[DEBUG] Finding Disaster Recovery Configs within %s", *namespaceId)
+		logger.Debug("Finding Disaster Recovery Configs", logging.ResourceID(namespaceId.ID()))
 		configs, err := serviceBusClient.DisasterRecoveryConfigs.ListComplete(ctx, *namespaceId)
 		if err != nil {
-			return fmt.Errorf("finding Disaster Recovery Configs within %s: %+v", *namespaceId, err)
+			return nil, fmt.Errorf("finding Disaster Recovery Configs within %s: %+v", *namespaceId, err)
 		}
 
 		for _, config := range configs.Items {
 			if props := config.Properties; props == nil || *props.Role == disasterrecoveryconfigs.RoleDisasterRecoverySecondary {
-				log.Printf("[DEBUG] Skipping %s for %s: Role is %q", *config.Id, *namespace.Id, *props.Role)
+				logger.Debug("Skipping Disaster Recovery Config since it isn't the primary", logging.ResourceID(*config.Id), slog.String("role", string(*props.Role)))
 				continue
 			}
 			configId, err := disasterrecoveryconfigs.ParseDisasterRecoveryConfigIDInsensitively(*config.Id)
 			if err != nil {
-				return fmt.Errorf("parsing the Disaster Recovery Config ID %q: %+v", *config.Id, err)
+				return nil, fmt.Errorf("parsing the Disaster Recovery Config ID %q: %+v", *config.Id, err)
 			}
 
-			if !opts.ActuallyDelete {
-				log.Printf("[DEBUG] Would have broken the pairing for %s..", *configId)
-				continue
-			}
-
-			log.Printf("[DEBUG] Breaking Pairing for %s..", *configId)
-			if resp, err := serviceBusClient.DisasterRecoveryConfigs.BreakPairing(ctx, *configId); err != nil {
-				if !response.WasNotFound(resp.HttpResponse) {
-					return fmt.Errorf("breaking pairing for %s: %+v", *configId, err)
-				}
-			}
-			log.Printf("[DEBUG] Polling until Pairing is broken for %s..", *configId)
-			pollerType := serviceBusNamespaceBreakPairingPoller{
-				client:   serviceBusClient,
-				configId: *configId,
-			}
-			poller := pollers.NewPoller(pollerType, 30*time.Second, pollers.DefaultNumberOfDroppedConnectionsToAllow)
-			if err := poller.PollUntilDone(ctx); err != nil {
-				return fmt.Errorf("polling until the Pairing is broken for %s: %+v", *configId, err)
-			}
-			log.Printf("[DEBUG] Pairing Broken for %s", *configId)
+			actions = append(actions, Action{
+				Description: "Break the pairing",
+				ResourceID:  configId.ID(),
+				Execute: func(ctx context.Context) error {
+					if resp, err := serviceBusClient.DisasterRecoveryConfigs.BreakPairing(ctx, *configId); err != nil {
+						if !response.WasNotFound(resp.HttpResponse) {
+							return err
+						}
+					}
+					logging.FromContext(ctx).Debug("Polling until the pairing is broken", logging.ResourceID(configId.ID()))
+					pollerType := serviceBusNamespaceBreakPairingPoller{
+						client:   serviceBusClient,
+						configId: *configId,
+					}
+					poller := pollers.NewPoller(pollerType, 30*time.Second, pollers.DefaultNumberOfDroppedConnectionsToAllow)
+					if err := poller.PollUntilDone(ctx); err != nil {
+						return fmt.Errorf("polling until the Pairing is broken: %+v", err)
+					}
+					return nil
+				},
+			})
 		}
 	}
-	return nil
+	return actions, nil
 }
 
 func (serviceBusNamespaceBreakPairingCleaner) ResourceTypes() []string {
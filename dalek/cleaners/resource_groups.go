@@ -8,9 +8,9 @@ import (
 	"github.com/jackofallops/azurerm-dalek/dalek/options"
 )
 
+// ResourceGroupCleaners is the set of registered ResourceGroupCleaners - the order these are run in is
+// determined by the dependencies each Cleaner declares via RunsAfter, rather than their position here.
 var ResourceGroupCleaners = []ResourceGroupCleaner{
-	// NOTE: the ordering is important here, we want to remove Locks first because a Write or Delete lock
-	// would prevent us from doing anything else, so that needs to be first.
 	removeLocksFromResourceGroupCleaner{},
 	removeDataProtectionFromResourceGroupCleaner{},
 	computeGalleryCleaner{},
@@ -28,9 +28,30 @@ type ResourceGroupCleaner interface {
 	// Name returns the name of this ResourceGroupCleaner
 	Name() string
 
-	// Cleanup performs the cleanup operation for this ResourceGroupCleaner
-	Cleanup(ctx context.Context, id commonids.ResourceGroupId, client *clients.AzureClient, opts options.Options) error
+	// Discover returns the Actions needed to clean up the Resource Group, in the order these must be executed. This
+	// mustn't change anything, so that it can be used to show what a dry-run would do.
+	Discover(ctx context.Context, id commonids.ResourceGroupId, client *clients.AzureClient, opts options.Options) ([]Action, error)
 
 	// ResourceTypes returns the list of Resource Types supported by this ResourceGroupCleaner
 	ResourceTypes() []string
+
+	// RunsAfter returns the ResourceGroupCleaners which must have completed before this one is run
+	RunsAfter() []ResourceGroupCleaner
+}
+
+// Action is a change which a ResourceGroupCleaner makes to a resource within the Resource Group, such as removing a
+// Lock or breaking a pairing, so that the Resource Group can then be deleted
+type Action struct {
+	// Description describes the change, e.g. "Remove Lock"
+	Description string
+
+	// ResourceID is the ID of the resource being changed
+	ResourceID string
+
+	// ContinueOnError is whether the remaining Actions are still executed when this one fails, in which case the
+	// failure is logged rather than failing the ResourceGroupCleaner
+	ContinueOnError bool
+
+	// Execute makes the change, which is only called when deleting
+	Execute func(ctx context.Context) error
 }
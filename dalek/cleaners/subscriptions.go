@@ -8,6 +8,8 @@ import (
 	"github.com/jackofallops/azurerm-dalek/dalek/options"
 )
 
+// SubscriptionCleaners is the set of registered SubscriptionCleaners - the order these are run in is
+// determined by the dependencies each Cleaner declares via RunsAfter, rather than their position here.
 var SubscriptionCleaners = []SubscriptionCleaner{
 	deleteNetAppSubscriptionCleaner{},
 	deleteRecoveryServicesVaultSubscriptionCleaner{},
@@ -18,10 +20,36 @@ var SubscriptionCleaners = []SubscriptionCleaner{
 	purgeSoftDeletedMachineLearningWorkspacesInSubscriptionCleaner{},
 }
 
+// softDeletePurger is implemented by the SubscriptionCleaners which only purge objects that have already been soft-deleted
+type softDeletePurger interface {
+	purgesSoftDeletedItems()
+}
+
+// PurgesSoftDeletedItems returns whether the SubscriptionCleaner only purges objects that have already been soft-deleted
+func PurgesSoftDeletedItems(cleaner SubscriptionCleaner) bool {
+	_, ok := cleaner.(softDeletePurger)
+	return ok
+}
+
+// safetyLimitChecker is implemented by the SubscriptionCleaners which check the safety limits, which are the only
+// SubscriptionCleaners run when only checking the safety limits
+type safetyLimitChecker interface {
+	checksSafetyLimits()
+}
+
+// ChecksSafetyLimits returns whether the SubscriptionCleaner checks the safety limits before deleting anything
+func ChecksSafetyLimits(cleaner SubscriptionCleaner) bool {
+	_, ok := cleaner.(safetyLimitChecker)
+	return ok
+}
+
 type SubscriptionCleaner interface {
 	// Name specifies the name of this SubscriptionCleaner
 	Name() string
 
 	// Cleanup performs this clean-up operation against the given Subscription
 	Cleanup(ctx context.Context, subscriptionId commonids.SubscriptionId, client *clients.AzureClient, opts options.Options) error
+
+	// RunsAfter returns the SubscriptionCleaners which must have completed before this one is run
+	RunsAfter() []SubscriptionCleaner
 }
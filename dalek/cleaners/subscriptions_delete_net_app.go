@@ -4,8 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
-	"strings"
+	"log/slog"
+	"net/http"
+	"time"
 
 	"github.com/hashicorp/go-azure-helpers/resourcemanager/commonids"
 	"github.com/hashicorp/go-azure-sdk/resource-manager/netapp/2025-03-01/capacitypools"
@@ -13,7 +14,12 @@ import (
 	"github.com/hashicorp/go-azure-sdk/resource-manager/netapp/2025-03-01/volumes"
 	"github.com/hashicorp/go-azure-sdk/resource-manager/netapp/2025-03-01/volumesreplication"
 	"github.com/jackofallops/azurerm-dalek/clients"
+	"github.com/jackofallops/azurerm-dalek/dalek/logging"
+	"github.com/jackofallops/azurerm-dalek/dalek/matcher"
 	"github.com/jackofallops/azurerm-dalek/dalek/options"
+	"github.com/jackofallops/azurerm-dalek/dalek/plan"
+	"github.com/jackofallops/azurerm-dalek/dalek/report"
+	"github.com/jackofallops/azurerm-dalek/dalek/retry"
 )
 
 type deleteNetAppSubscriptionCleaner struct{}
@@ -24,7 +30,14 @@ func (p deleteNetAppSubscriptionCleaner) Name() string {
 	return "Removing Net App"
 }
 
+func (p deleteNetAppSubscriptionCleaner) RunsAfter() []SubscriptionCleaner {
+	return nil
+}
+
 func (p deleteNetAppSubscriptionCleaner) Cleanup(ctx context.Context, subscriptionId commonids.SubscriptionId, client *clients.AzureClient, opts options.Options) error {
+	logger := logging.FromContext(ctx)
+	rep := report.FromContext(ctx)
+	m := matcher.New(opts)
 	netAppAccountClient := client.ResourceManager.NetAppAccountClient
 	netAppCapcityPoolClient := client.ResourceManager.NetAppCapacityPoolClient
 	netAppVolumeClient := client.ResourceManager.NetAppVolumeClient
@@ -51,19 +64,45 @@ func (p deleteNetAppSubscriptionCleaner) Cleanup(ctx context.Context, subscripti
 			return err
 		}
 
-		if !strings.HasPrefix(accountIdForCapacityPool.ResourceGroupName, opts.Prefix) {
-			log.Printf("[DEBUG] Not deleting %q as it does not match target RG prefix %q", *accountIdForCapacityPool, opts.Prefix)
+		entry := subscriptionEntry(report.KindNetAppAccount, subscriptionId, accountIdForCapacityPool, accountIdForCapacityPool.NetAppAccountName)
+		resourceGroupId := commonids.NewResourceGroupID(accountIdForCapacityPool.SubscriptionId, accountIdForCapacityPool.ResourceGroupName)
+		matched, rule, err := matchResource(ctx, client, m, resourceGroupId, "Microsoft.NetApp/netAppAccounts", accountIdForCapacityPool.ID(), account.Tags)
+		if err != nil {
+			errs = append(errs, err)
+			entry.Action = report.ActionSkipped
+			entry.Error = err.Error()
+			rep.Record(entry)
+			continue
+		}
+		entry.Rule = rule
+		if !matched {
+			logger.Debug("Skipping NetApp Account", logging.ResourceID(accountIdForCapacityPool.ID()), slog.String("rule", rule))
+			entry.Action = report.ActionSkipped
+			rep.Record(entry)
+			continue
+		}
+
+		planItem := subscriptionPlanItem(report.KindNetAppAccount, subscriptionId, accountIdForCapacityPool, accountIdForCapacityPool.NetAppAccountName, account.Tags)
+		if err := plan.FromContext(ctx).Check(planItem); err != nil {
+			logger.Warn("Skipping NetApp Account", logging.ResourceID(accountIdForCapacityPool.ID()), logging.Err(err))
+			entry.Action = report.ActionSkipped
+			entry.Rule = err.Error()
+			rep.Record(entry)
 			continue
 		}
 
 		if !opts.ActuallyDelete {
-			log.Printf("[DEBUG] Would have deleted %s..", accountIdForCapacityPool)
+			logger.Info("Would have deleted NetApp Account", logging.ResourceID(accountIdForCapacityPool.ID()))
+			entry.Action = report.ActionWouldDelete
+			rep.Record(entry)
 			continue
 		}
 
+		startedAt := time.Now()
+		accountErrs := make([]error, 0)
 		capacityPoolList, err := netAppCapcityPoolClient.PoolsListComplete(ctx, *accountIdForCapacityPool)
 		if err != nil {
-			errs = append(errs, fmt.Errorf("listing NetApp Capacity Pools for %s: %+v", accountIdForCapacityPool, err))
+			accountErrs = append(accountErrs, fmt.Errorf("listing NetApp Capacity Pools for %s: %+v", accountIdForCapacityPool, err))
 		}
 
 		for _, capacityPool := range capacityPoolList.Items {
@@ -73,17 +112,17 @@ func (p deleteNetAppSubscriptionCleaner) Cleanup(ctx context.Context, subscripti
 
 			capacityPoolForVolumesId, err := volumes.ParseCapacityPoolID(*capacityPool.Id)
 			if err != nil {
-				errs = append(errs, err)
+				accountErrs = append(accountErrs, err)
 			}
 
 			if !opts.ActuallyDelete {
-				log.Printf("[DEBUG] Would have deleted %s..", capacityPoolForVolumesId)
+				logger.Info("Would have deleted NetApp Capacity Pool", logging.ResourceID(capacityPoolForVolumesId.ID()))
 				continue
 			}
 
 			volumeList, err := netAppVolumeClient.ListComplete(ctx, *capacityPoolForVolumesId)
 			if err != nil {
-				errs = append(errs, fmt.Errorf("listing NetApp Volumes for %s: %+v", capacityPoolForVolumesId, err))
+				accountErrs = append(accountErrs, fmt.Errorf("listing NetApp Volumes for %s: %+v", capacityPoolForVolumesId, err))
 				continue
 			}
 
@@ -94,56 +133,71 @@ func (p deleteNetAppSubscriptionCleaner) Cleanup(ctx context.Context, subscripti
 
 				volumeId, err := volumes.ParseVolumeID(*volume.Id)
 				if err != nil {
-					errs = append(errs, err)
+					accountErrs = append(accountErrs, err)
 					continue
 				}
 
 				volumeReplicationId, err := volumesreplication.ParseVolumeID(*volume.Id)
 				if err != nil {
-					errs = append(errs, err)
+					accountErrs = append(accountErrs, err)
 					continue
 				}
 
-				if err := netAppVolumeReplicationClient.VolumesDeleteReplicationThenPoll(ctx, *volumeReplicationId); err != nil {
-					errs = append(errs, fmt.Errorf("deleting replication for %s: %+v", volumeReplicationId, err))
+				if err := retry.Do(ctx, fmt.Sprintf("deleting replication for %s", volumeReplicationId), func(ctx context.Context) (*http.Response, error) {
+					return nil, netAppVolumeReplicationClient.VolumesDeleteReplicationThenPoll(ctx, *volumeReplicationId)
+				}); err != nil {
+					accountErrs = append(accountErrs, fmt.Errorf("deleting replication for %s: %+v", volumeReplicationId, err))
 					continue
 				}
 
 				forceDelete := true
 				// the netapp api doesn't error if the delete fails so we'll just fire and forget as to not break the dalek
-				if _, err = netAppVolumeClient.Delete(ctx, *volumeId, volumes.DeleteOperationOptions{ForceDelete: &forceDelete}); err != nil {
+				if err = retry.Do(ctx, fmt.Sprintf("deleting %s", volumeId), func(ctx context.Context) (*http.Response, error) {
+					resp, err := netAppVolumeClient.Delete(ctx, *volumeId, volumes.DeleteOperationOptions{ForceDelete: &forceDelete})
+					return resp.HttpResponse, err
+				}); err != nil {
 					// Potential Eventual Consistency Issues so we'll just log and move on
-					errs = append(errs, fmt.Errorf("[DEBUG] Unable to delete %s: %+v", volumeId, err))
+					accountErrs = append(accountErrs, fmt.Errorf("deleting %s: %+v", volumeId, err))
 					continue
 				}
 			}
 
 			capacityPoolId, err := capacitypools.ParseCapacityPoolID(*capacityPool.Id)
 			if err != nil {
-				errs = append(errs, err)
+				accountErrs = append(accountErrs, err)
 				continue
 			}
 
 			// the netapp api doesn't error if the delete fails so we'll just fire and forget as to not break the dalek
-			if _, err = netAppCapcityPoolClient.PoolsDelete(ctx, *capacityPoolId); err != nil {
+			if err = retry.Do(ctx, fmt.Sprintf("deleting %s", capacityPoolId), func(ctx context.Context) (*http.Response, error) {
+				resp, err := netAppCapcityPoolClient.PoolsDelete(ctx, *capacityPoolId)
+				return resp.HttpResponse, err
+			}); err != nil {
 				// Potential Eventual Consistency Issues so we'll just log and move on
-				errs = append(errs, fmt.Errorf("[DEBUG] Unable to delete %s: %+v", capacityPoolId, err))
+				accountErrs = append(accountErrs, fmt.Errorf("deleting %s: %+v", capacityPoolId, err))
 				continue
 			}
 		}
 
-		accountId, err := netappaccounts.ParseNetAppAccountID(*account.Id)
-		if err != nil {
-			errs = append(errs, err)
-			continue
-		}
-
 		// the netapp api doesn't error if the delete fails so we'll just fire and forget as to not break the dalek
-		if _, err = netAppAccountClient.AccountsDelete(ctx, *accountId); err != nil {
+		if accountId, err := netappaccounts.ParseNetAppAccountID(*account.Id); err != nil {
+			accountErrs = append(accountErrs, err)
+		} else if err = retry.Do(ctx, fmt.Sprintf("deleting %s", accountId), func(ctx context.Context) (*http.Response, error) {
+			resp, err := netAppAccountClient.AccountsDelete(ctx, *accountId)
+			return resp.HttpResponse, err
+		}); err != nil {
 			// Potential Eventual Consistency Issues so we'll just log and move on
-			errs = append(errs, fmt.Errorf("[DEBUG] Unable to delete %s: %+v", accountId, err))
-			continue
+			accountErrs = append(accountErrs, fmt.Errorf("deleting %s: %+v", accountId, err))
+		}
+
+		entry.DurationSeconds = time.Since(startedAt).Seconds()
+		entry.Action = report.ActionDeleted
+		if len(accountErrs) > 0 {
+			entry.Action = report.ActionFailed
+			entry.Error = errors.Join(accountErrs...).Error()
+			errs = append(errs, accountErrs...)
 		}
+		rep.Record(entry)
 	}
 
 	return errors.Join(errs...)
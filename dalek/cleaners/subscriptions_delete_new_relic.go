@@ -4,12 +4,19 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
+	"time"
 
 	"github.com/hashicorp/go-azure-helpers/resourcemanager/commonids"
 	"github.com/hashicorp/go-azure-sdk/resource-manager/newrelic/2024-10-01/monitors"
 	"github.com/jackofallops/azurerm-dalek/clients"
+	"github.com/jackofallops/azurerm-dalek/dalek/logging"
+	"github.com/jackofallops/azurerm-dalek/dalek/matcher"
 	"github.com/jackofallops/azurerm-dalek/dalek/options"
+	"github.com/jackofallops/azurerm-dalek/dalek/plan"
+	"github.com/jackofallops/azurerm-dalek/dalek/report"
+	"github.com/jackofallops/azurerm-dalek/dalek/retry"
 )
 
 type deleteNewRelicSubscriptionCleaner struct{}
@@ -20,7 +27,14 @@ func (p deleteNewRelicSubscriptionCleaner) Name() string {
 	return "Removing New Relic"
 }
 
+func (p deleteNewRelicSubscriptionCleaner) RunsAfter() []SubscriptionCleaner {
+	return nil
+}
+
 func (p deleteNewRelicSubscriptionCleaner) Cleanup(ctx context.Context, subscriptionId commonids.SubscriptionId, client *clients.AzureClient, opts options.Options) error {
+	logger := logging.FromContext(ctx)
+	rep := report.FromContext(ctx)
+	m := matcher.New(opts)
 	newRelicMonitorClient := client.ResourceManager.NewRelicMonitorClient
 
 	errs := make([]error, 0)
@@ -37,24 +51,64 @@ func (p deleteNewRelicSubscriptionCleaner) Cleanup(ctx context.Context, subscrip
 
 		monitorId, err := monitors.ParseMonitorID(*monitor.Id)
 		if err != nil {
-			errs = append(errs, fmt.Errorf("[DEBUG] Parsing monitor Id %q: %+v", *monitor.Id, err))
+			errs = append(errs, fmt.Errorf("parsing monitor id %q: %+v", *monitor.Id, err))
 			continue
 		}
 
-		if !opts.ActuallyDelete {
-			log.Printf("[DEBUG] Would have deleted %s..", monitorId)
+		entry := subscriptionEntry(report.KindNewRelicMonitor, subscriptionId, monitorId, monitorId.MonitorName)
+		resourceGroupId := commonids.NewResourceGroupID(monitorId.SubscriptionId, monitorId.ResourceGroupName)
+		matched, rule, err := matchResource(ctx, client, m, resourceGroupId, "NewRelic.Observability/monitors", monitorId.ID(), monitor.Tags)
+		if err != nil {
+			errs = append(errs, err)
+			entry.Action = report.ActionSkipped
+			entry.Error = err.Error()
+			rep.Record(entry)
+			continue
+		}
+		entry.Rule = rule
+		if !matched {
+			logger.Debug("Skipping New Relic Monitor", logging.ResourceID(monitorId.ID()), slog.String("rule", rule))
+			entry.Action = report.ActionSkipped
+			rep.Record(entry)
 			continue
 		}
 
-		if monitor.Properties.UserInfo == nil || monitor.Properties.UserInfo.EmailAddress == nil {
-			errs = append(errs, fmt.Errorf("[DEBUG] `user` not found for %s", monitorId))
+		if err := plan.FromContext(ctx).Check(subscriptionPlanItem(report.KindNewRelicMonitor, subscriptionId, monitorId, monitorId.MonitorName, monitor.Tags)); err != nil {
+			logger.Warn("Skipping New Relic Monitor", logging.ResourceID(monitorId.ID()), logging.Err(err))
+			entry.Action = report.ActionSkipped
+			entry.Rule = err.Error()
+			rep.Record(entry)
 			continue
 		}
 
-		if err = newRelicMonitorClient.DeleteThenPoll(ctx, *monitorId, monitors.DeleteOperationOptions{UserEmail: monitor.Properties.UserInfo.EmailAddress}); err != nil {
-			errs = append(errs, fmt.Errorf("[DEBUG] deleting %s: %+v", monitorId, err))
+		if !opts.ActuallyDelete {
+			logger.Info("Would have deleted New Relic Monitor", logging.ResourceID(monitorId.ID()))
+			entry.Action = report.ActionWouldDelete
+			rep.Record(entry)
+			continue
+		}
+
+		startedAt := time.Now()
+		if monitor.Properties.UserInfo == nil || monitor.Properties.UserInfo.EmailAddress == nil {
+			err = fmt.Errorf("`user` not found for %s", monitorId)
+		} else {
+			err = retry.Do(ctx, fmt.Sprintf("deleting %s", monitorId), func(ctx context.Context) (*http.Response, error) {
+				return nil, newRelicMonitorClient.DeleteThenPoll(ctx, *monitorId, monitors.DeleteOperationOptions{UserEmail: monitor.Properties.UserInfo.EmailAddress})
+			})
+			if err != nil {
+				err = fmt.Errorf("deleting %s: %+v", monitorId, err)
+			}
+		}
+		entry.DurationSeconds = time.Since(startedAt).Seconds()
+		if err != nil {
+			errs = append(errs, err)
+			entry.Action = report.ActionFailed
+			entry.Error = err.Error()
+			rep.Record(entry)
 			continue
 		}
+		entry.Action = report.ActionDeleted
+		rep.Record(entry)
 	}
 
 	return errors.Join(errs...)
@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
 
 	"github.com/hashicorp/go-azure-helpers/lang/pointer"
 	"github.com/hashicorp/go-azure-helpers/resourcemanager/commonids"
@@ -13,23 +16,42 @@ import (
 	"github.com/hashicorp/go-azure-sdk/resource-manager/recoveryservicesbackup/2024-10-01/protecteditems"
 	"github.com/hashicorp/go-azure-sdk/resource-manager/recoveryservicesbackup/2024-10-01/protectioncontainers"
 	"github.com/jackofallops/azurerm-dalek/clients"
+	"github.com/jackofallops/azurerm-dalek/dalek/logging"
+	"github.com/jackofallops/azurerm-dalek/dalek/matcher"
 	"github.com/jackofallops/azurerm-dalek/dalek/options"
+	"github.com/jackofallops/azurerm-dalek/dalek/plan"
+	"github.com/jackofallops/azurerm-dalek/dalek/report"
+	"github.com/jackofallops/azurerm-dalek/dalek/retry"
 )
 
 type deleteRecoveryServicesVaultSubscriptionCleaner struct{}
 
+// vaultDeleteRetryPolicy allows more time than the default, since the vault can only be deleted once the
+// asynchronous removal of the Protected Items within it has completed
+var vaultDeleteRetryPolicy = retry.Policy{
+	MaxAttempts:  10,
+	InitialDelay: 30 * time.Second,
+	MaxDelay:     5 * time.Minute,
+	TransientErrorCodes: []string{
+		"ServiceResourceNotEmpty",
+	},
+}
+
 var _ SubscriptionCleaner = deleteRecoveryServicesVaultSubscriptionCleaner{}
 
 func (p deleteRecoveryServicesVaultSubscriptionCleaner) Name() string {
 	return "Removing Recovery Services Vault"
 }
 
+func (p deleteRecoveryServicesVaultSubscriptionCleaner) RunsAfter() []SubscriptionCleaner {
+	return nil
+}
+
 func (p deleteRecoveryServicesVaultSubscriptionCleaner) Cleanup(ctx context.Context, subscriptionId commonids.SubscriptionId, client *clients.AzureClient, opts options.Options) error {
+	logger := logging.FromContext(ctx)
+	rep := report.FromContext(ctx)
+	m := matcher.New(opts)
 	vaultsClient := client.ResourceManager.RecoveryServicesVaultClient
-	protectedItemsClient := client.ResourceManager.RecoveryServicesProtectedItemClient
-	backupProtectedItemsClient := client.ResourceManager.RecoveryServicesBackupProtectedItemsClient
-	backupProtectionContainersClient := client.ResourceManager.RecoveryServicesBackupProtectionContainers
-	protectionContainersClient := client.ResourceManager.RecoveryServicesProtectionContainers
 
 	errs := make([]error, 0)
 
@@ -45,108 +67,174 @@ func (p deleteRecoveryServicesVaultSubscriptionCleaner) Cleanup(ctx context.Cont
 
 		vaultId, err := vaults.ParseVaultID(*vault.Id)
 		if err != nil {
-			errs = append(errs, fmt.Errorf("[DEBUG] parsing id %q: %+v", *vault.Id, err))
+			errs = append(errs, fmt.Errorf("parsing id %q: %+v", *vault.Id, err))
+			continue
+		}
+
+		entry := subscriptionEntry(report.KindRecoveryServicesVault, subscriptionId, vaultId, vaultId.VaultName)
+		resourceGroupId := commonids.NewResourceGroupID(vaultId.SubscriptionId, vaultId.ResourceGroupName)
+		matched, rule, err := matchResource(ctx, client, m, resourceGroupId, "Microsoft.RecoveryServices/vaults", vaultId.ID(), vault.Tags)
+		if err != nil {
+			errs = append(errs, err)
+			entry.Action = report.ActionSkipped
+			entry.Error = err.Error()
+			rep.Record(entry)
+			continue
+		}
+		entry.Rule = rule
+		if !matched {
+			logger.Debug("Skipping Recovery Services Vault", logging.ResourceID(vaultId.ID()), slog.String("rule", rule))
+			entry.Action = report.ActionSkipped
+			rep.Record(entry)
 			continue
 		}
 
-		// Update the vault to be mutable
-		isSoftDeleteEnabled := false
-		isImmutable := false
-		isMUA := false
-		if vault.Properties != nil && vault.Properties.SecuritySettings != nil && vault.Properties.SecuritySettings.SoftDeleteSettings != nil && vault.Properties.SecuritySettings.SoftDeleteSettings.SoftDeleteState != nil {
-			isSoftDeleteEnabled = *vault.Properties.SecuritySettings.SoftDeleteSettings.SoftDeleteState != vaults.SoftDeleteStateDisabled
+		if err := plan.FromContext(ctx).Check(subscriptionPlanItem(report.KindRecoveryServicesVault, subscriptionId, vaultId, vaultId.VaultName, vault.Tags)); err != nil {
+			logger.Warn("Skipping Recovery Services Vault", logging.ResourceID(vaultId.ID()), logging.Err(err))
+			entry.Action = report.ActionSkipped
+			entry.Rule = err.Error()
+			rep.Record(entry)
+			continue
 		}
-		if vault.Properties != nil && vault.Properties.SecuritySettings != nil && vault.Properties.SecuritySettings.ImmutabilitySettings != nil && vault.Properties.SecuritySettings.ImmutabilitySettings.State != nil {
-			isImmutable = *vault.Properties.SecuritySettings.ImmutabilitySettings.State != vaults.ImmutabilityStateDisabled
+
+		if !opts.ActuallyDelete {
+			logger.Info("Would have deleted Recovery Services Vault", logging.ResourceID(vaultId.ID()))
+			entry.Action = report.ActionWouldDelete
+			rep.Record(entry)
+			continue
 		}
 
-		if vault.Properties != nil && vault.Properties.SecuritySettings != nil && vault.Properties.SecuritySettings.MultiUserAuthorization != nil {
-			isMUA = *vault.Properties.SecuritySettings.MultiUserAuthorization != vaults.MultiUserAuthorizationDisabled
+		startedAt := time.Now()
+		err = p.deleteVault(ctx, client, vault, vaultId)
+		entry.DurationSeconds = time.Since(startedAt).Seconds()
+		if err != nil {
+			errs = append(errs, err)
+			entry.Action = report.ActionFailed
+			entry.Error = err.Error()
+			rep.Record(entry)
+			continue
 		}
+		entry.Action = report.ActionDeleted
+		rep.Record(entry)
+	}
+
+	return errors.Join(errs...)
+}
+
+// deleteVault deletes the Recovery Services Vault, after first making it mutable and removing the Protected Items and
+// Protection Containers within it
+func (p deleteRecoveryServicesVaultSubscriptionCleaner) deleteVault(ctx context.Context, client *clients.AzureClient, vault vaults.Vault, vaultId *vaults.VaultId) error {
+	vaultsClient := client.ResourceManager.RecoveryServicesVaultClient
+	protectedItemsClient := client.ResourceManager.RecoveryServicesProtectedItemClient
+	backupProtectedItemsClient := client.ResourceManager.RecoveryServicesBackupProtectedItemsClient
+	backupProtectionContainersClient := client.ResourceManager.RecoveryServicesBackupProtectionContainers
+	protectionContainersClient := client.ResourceManager.RecoveryServicesProtectionContainers
+
+	// Update the vault to be mutable
+	isSoftDeleteEnabled := false
+	isImmutable := false
+	isMUA := false
+	if vault.Properties != nil && vault.Properties.SecuritySettings != nil && vault.Properties.SecuritySettings.SoftDeleteSettings != nil && vault.Properties.SecuritySettings.SoftDeleteSettings.SoftDeleteState != nil {
+		isSoftDeleteEnabled = *vault.Properties.SecuritySettings.SoftDeleteSettings.SoftDeleteState != vaults.SoftDeleteStateDisabled
+	}
+	if vault.Properties != nil && vault.Properties.SecuritySettings != nil && vault.Properties.SecuritySettings.ImmutabilitySettings != nil && vault.Properties.SecuritySettings.ImmutabilitySettings.State != nil {
+		isImmutable = *vault.Properties.SecuritySettings.ImmutabilitySettings.State != vaults.ImmutabilityStateDisabled
+	}
 
-		if isSoftDeleteEnabled || isImmutable || isMUA {
-			patch := vaults.PatchVault{
-				Properties: &vaults.VaultProperties{
-					SecuritySettings: &vaults.SecuritySettings{
-						ImmutabilitySettings: &vaults.ImmutabilitySettings{
-							State: pointer.To(vaults.ImmutabilityStateDisabled),
-						},
-						SoftDeleteSettings: &vaults.SoftDeleteSettings{
-							SoftDeleteState: pointer.To(vaults.SoftDeleteStateDisabled),
-						},
-						MultiUserAuthorization: pointer.To(vaults.MultiUserAuthorizationDisabled),
+	if vault.Properties != nil && vault.Properties.SecuritySettings != nil && vault.Properties.SecuritySettings.MultiUserAuthorization != nil {
+		isMUA = *vault.Properties.SecuritySettings.MultiUserAuthorization != vaults.MultiUserAuthorizationDisabled
+	}
+
+	if isSoftDeleteEnabled || isImmutable || isMUA {
+		patch := vaults.PatchVault{
+			Properties: &vaults.VaultProperties{
+				SecuritySettings: &vaults.SecuritySettings{
+					ImmutabilitySettings: &vaults.ImmutabilitySettings{
+						State: pointer.To(vaults.ImmutabilityStateDisabled),
+					},
+					SoftDeleteSettings: &vaults.SoftDeleteSettings{
+						SoftDeleteState: pointer.To(vaults.SoftDeleteStateDisabled),
 					},
+					MultiUserAuthorization: pointer.To(vaults.MultiUserAuthorizationDisabled),
 				},
-			}
+			},
+		}
 
-			if err := vaultsClient.UpdateThenPoll(ctx, *vaultId, patch, vaults.DefaultUpdateOperationOptions()); err != nil {
-				errs = append(errs, fmt.Errorf("updating %s to not be mutable: %+v", vaultId, err))
-				continue
-			}
+		if err := retry.Do(ctx, fmt.Sprintf("updating %s", vaultId), func(ctx context.Context) (*http.Response, error) {
+			return nil, vaultsClient.UpdateThenPoll(ctx, *vaultId, patch, vaults.DefaultUpdateOperationOptions())
+		}); err != nil {
+			return fmt.Errorf("updating %s to not be mutable: %+v", vaultId, err)
 		}
+	}
 
-		backupItemsVaultId, err := backupprotecteditems.ParseVaultID(*vault.Id)
-		if err != nil {
-			errs = append(errs, fmt.Errorf("[DEBUG] parsing id %q: %+v", *vault.Id, err))
+	backupItemsVaultId, err := backupprotecteditems.ParseVaultID(*vault.Id)
+	if err != nil {
+		return fmt.Errorf("parsing id %q: %+v", *vault.Id, err)
+	}
+
+	backupItems, err := backupProtectedItemsClient.List(ctx, *backupItemsVaultId, backupprotecteditems.ListOperationOptions{})
+	if err != nil || backupItems.Model == nil {
+		return fmt.Errorf("listing Backup Protected Items for %q: %+v", backupItemsVaultId.ID(), err)
+	}
+
+	errs := make([]error, 0)
+	for _, backupItem := range *backupItems.Model {
+		if backupItem.Id == nil {
 			continue
 		}
 
-		backupItems, err := backupProtectedItemsClient.List(ctx, *backupItemsVaultId, backupprotecteditems.ListOperationOptions{})
-		if err != nil || backupItems.Model == nil {
-			errs = append(errs, fmt.Errorf("listing Backup Protected Items for %q: %+v", backupItemsVaultId.ID(), err))
+		backupItemId, err := protecteditems.ParseProtectedItemID(*backupItem.Id)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("parsing id %q: %+v", *backupItem.Id, err))
 			continue
 		}
 
-		for _, backupItem := range *backupItems.Model {
-			if backupItem.Id == nil {
-				continue
-			}
-
-			backupItemId, err := protecteditems.ParseProtectedItemID(*backupItem.Id)
-			if err != nil {
-				errs = append(errs, fmt.Errorf("[DEBUG] parsing id %q: %+v", *backupItemId, err))
-				continue
-			}
-
-			// This process takes awhile and even after completing we don't have a guarantee that the vault can't see these items anymore so we'll just fire and forget
-			// and expect this cleaner to have to run multiple times to get everything cleared out
-			_, err = protectedItemsClient.Delete(ctx, *backupItemId)
-			if err != nil {
-				errs = append(errs, fmt.Errorf("[DEBUG] deleting %q: %+v", backupItemId, err))
-				continue
-			}
+		// This process takes awhile and even after completing we don't have a guarantee that the vault can't see these items anymore so we'll just fire and forget,
+		// relying on the retries when deleting the vault below to give these time to clear out
+		err = retry.Do(ctx, fmt.Sprintf("deleting %s", backupItemId), func(ctx context.Context) (*http.Response, error) {
+			resp, err := protectedItemsClient.Delete(ctx, *backupItemId)
+			return resp.HttpResponse, err
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("deleting %q: %+v", backupItemId, err))
+			continue
 		}
+	}
 
-		// Unregister Protection Containers, prerequisite to vault deletion
-		storageContainers, err := backupProtectionContainersClient.ListComplete(ctx, backupprotectioncontainers.VaultId(*backupItemsVaultId), backupprotectioncontainers.ListOperationOptions{Filter: pointer.To("backupManagementType eq 'AzureStorage'")})
-		if err != nil {
-			errs = append(errs, fmt.Errorf("listing %s: %w", *backupItemsVaultId, err))
+	// Unregister Protection Containers, prerequisite to vault deletion
+	storageContainers, err := backupProtectionContainersClient.ListComplete(ctx, backupprotectioncontainers.VaultId(*backupItemsVaultId), backupprotectioncontainers.ListOperationOptions{Filter: pointer.To("backupManagementType eq 'AzureStorage'")})
+	if err != nil {
+		return errors.Join(append(errs, fmt.Errorf("listing %s: %w", *backupItemsVaultId, err))...)
+	}
+
+	for _, sc := range storageContainers.Items {
+		if sc.Id == nil {
 			continue
 		}
 
-		for _, sc := range storageContainers.Items {
-			if sc.Id == nil {
-				continue
-			}
-
-			scID, err := protectioncontainers.ParseProtectionContainerID(*sc.Id)
-			if err != nil {
-				errs = append(errs, err)
-				continue
-			}
-
-			if _, err := protectionContainersClient.Unregister(ctx, *scID); err != nil {
-				errs = append(errs, fmt.Errorf("unregistering %s: %w", scID, err))
-				continue
-			}
+		scID, err := protectioncontainers.ParseProtectionContainerID(*sc.Id)
+		if err != nil {
+			errs = append(errs, err)
+			continue
 		}
 
-		// Azure doesn't return an error when the vault fails deleting when using DeleteThenPoll so we'll just fire and forget and expect this to have to run multiple times to get everything cleaned out
-		if _, err := vaultsClient.Delete(ctx, *vaultId); err != nil {
-			errs = append(errs, fmt.Errorf("[DEBUG] deleting %q: %+v", vaultId.ID(), err))
+		if err := retry.Do(ctx, fmt.Sprintf("unregistering %s", scID), func(ctx context.Context) (*http.Response, error) {
+			resp, err := protectionContainersClient.Unregister(ctx, *scID)
+			return resp.HttpResponse, err
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("unregistering %s: %w", scID, err))
 			continue
 		}
 	}
 
+	// Azure doesn't return an error when the vault fails deleting when using DeleteThenPoll so we'll just fire and forget - whilst the
+	// Protected Items above are still being removed the vault can't be deleted, so this is retried until they're gone
+	if err := vaultDeleteRetryPolicy.Do(ctx, fmt.Sprintf("deleting %s", vaultId), func(ctx context.Context) (*http.Response, error) {
+		resp, err := vaultsClient.Delete(ctx, *vaultId)
+		return resp.HttpResponse, err
+	}); err != nil {
+		errs = append(errs, fmt.Errorf("deleting %q: %+v", vaultId.ID(), err))
+	}
+
 	return errors.Join(errs...)
 }
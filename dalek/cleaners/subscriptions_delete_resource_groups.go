@@ -2,19 +2,32 @@ package cleaners
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
 	"slices"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/hashicorp/go-azure-helpers/lang/pointer"
 	"github.com/hashicorp/go-azure-helpers/resourcemanager/commonids"
 	"github.com/hashicorp/go-azure-sdk/resource-manager/resourcegraph/2024-04-01/resources"
 	"github.com/hashicorp/go-azure-sdk/resource-manager/resources/2020-05-01/managementlocks"
 	"github.com/hashicorp/go-azure-sdk/resource-manager/resources/2022-09-01/resourcegroups"
+	"github.com/hashicorp/go-azure-sdk/sdk/client/pollers"
 	"github.com/jackofallops/azurerm-dalek/clients"
+	"github.com/jackofallops/azurerm-dalek/dalek/checkpoint"
+	"github.com/jackofallops/azurerm-dalek/dalek/logging"
+	"github.com/jackofallops/azurerm-dalek/dalek/matcher"
+	"github.com/jackofallops/azurerm-dalek/dalek/metrics"
 	"github.com/jackofallops/azurerm-dalek/dalek/options"
+	"github.com/jackofallops/azurerm-dalek/dalek/plan"
+	"github.com/jackofallops/azurerm-dalek/dalek/report"
+	"github.com/jackofallops/azurerm-dalek/dalek/retry"
+	"github.com/jackofallops/azurerm-dalek/dalek/safety"
 )
 
 var _ SubscriptionCleaner = deleteResourceGroupsInSubscriptionCleaner{}
@@ -25,89 +38,483 @@ func (d deleteResourceGroupsInSubscriptionCleaner) Name() string {
 	return "Delete Resource Groups in Subscription"
 }
 
+func (d deleteResourceGroupsInSubscriptionCleaner) RunsAfter() []SubscriptionCleaner {
+	// these remove items which otherwise block the deletion of the Resource Groups containing them
+	return []SubscriptionCleaner{
+		deleteNetAppSubscriptionCleaner{},
+		deleteNewRelicSubscriptionCleaner{},
+		deleteRecoveryServicesVaultSubscriptionCleaner{},
+		deleteStorageSyncSubscriptionCleaner{},
+	}
+}
+
+func (d deleteResourceGroupsInSubscriptionCleaner) checksSafetyLimits() {}
+
 func (d deleteResourceGroupsInSubscriptionCleaner) Cleanup(ctx context.Context, subscriptionId commonids.SubscriptionId, client *clients.AzureClient, opts options.Options) error {
-	log.Printf("[DEBUG] Loading the first %d resource groups to delete", opts.NumberOfResourceGroupsToDelete)
+	logger := logging.FromContext(ctx)
+	logger.Debug("Loading the Resource Groups")
 
-	listOpts := resourcegroups.ListOperationOptions{
-		Top: pointer.To(opts.NumberOfResourceGroupsToDelete),
-	}
-	groups, err := client.ResourceManager.ResourcesGroupsClient.List(ctx, subscriptionId, listOpts)
+	// NOTE: we intentionally load every Resource Group here (rather than using `$top`) so that the
+	// limit on the number of Resource Groups to delete is applied after filtering
+	groups, err := client.ResourceManager.ResourcesGroupsClient.ListComplete(ctx, subscriptionId, resourcegroups.DefaultListOperationOptions())
 	if err != nil {
 		return fmt.Errorf("listing Resource Groups: %+v", err)
 	}
 
-	if groups.Model == nil {
-		log.Printf("[DEBUG]   No Resource Groups found")
+	if len(groups.Items) == 0 {
+		logger.Debug("No Resource Groups found")
 		return nil
 	}
 
+	// the time each Resource Group was created has to be looked up separately, so this is only done when needed - which
+	// includes when planning (or applying a plan), since a Resource Group recreated with the same name is only
+	// distinguishable by when it was created
+	m := matcher.New(opts)
+	createdTimes := make(map[string]time.Time)
+	if plan.FromContext(ctx) != nil || slices.ContainsFunc(groups.Items, func(input resourcegroups.ResourceGroup) bool {
+		return m.NeedsResourceGroupCreatedTime(resourceGroupObject(input))
+	}) {
+		if createdTimes, err = resourceGroupCreatedTimes(ctx, client, subscriptionId); err != nil {
+			return fmt.Errorf("determining when the Resource Groups were created: %+v", err)
+		}
+	}
+
+	rep := report.FromContext(ctx)
 	resourceGroups := make([]string, 0)
-	for _, resource := range *groups.Model {
+	rules := make(map[string]string)
+	planItems := make(map[string]plan.Item)
+	listed := make([]string, 0, len(groups.Items))
+	for _, resource := range groups.Items {
+		id := commonids.NewResourceGroupID(subscriptionId.SubscriptionId, *resource.Name)
 		if strings.EqualFold(*resource.Properties.ProvisioningState, "Deleting") {
-			log.Printf("[DEBUG] Resource Group %q is already being deleted - Skipping..", *resource.Name)
+			logger.Debug("Skipping Resource Group since it's already being deleted", logging.ResourceGroup(*resource.Name))
+			rep.Record(skippedResourceGroupEntry(id, "the Resource Group is already being deleted"))
 			continue
 		}
-		if !shouldDeleteResourceGroup(resource, opts.Prefix) {
-			log.Printf("[DEBUG] Resource Group %q shouldn't be deleted - Skipping..", *resource.Name)
+		listed = append(listed, id.ID())
+		object := resourceGroupObject(resource)
+		if v, ok := createdTimes[strings.ToLower(*resource.Name)]; ok {
+			object.CreatedTime = &v
+		}
+		shouldDelete, rule := m.MatchResourceGroup(object)
+		if !shouldDelete {
+			logger.Debug("Skipping Resource Group", logging.ResourceGroup(*resource.Name), slog.String("rule", rule))
+			rep.Record(skippedResourceGroupEntry(id, rule))
 			continue
 		}
 
 		resourceGroups = append(resourceGroups, *resource.Name)
+		rules[*resource.Name] = rule
+		planItems[*resource.Name] = resourceGroupPlanItem(id, resource, object.CreatedTime)
 	}
 	sort.Strings(resourceGroups)
 
+	// the safety limits are checked against everything which matched (or when applying a plan, everything planned),
+	// since protected Resource Groups and the limit on the number of Resource Groups to delete only mask a filter
+	// which is far too broad
+	guard := safety.FromContext(ctx)
+	count := plan.FromContext(ctx).Count(report.KindResourceGroup, listed, len(resourceGroups))
+	if err := guard.CheckResourceGroups(ctx, subscriptionId.SubscriptionId, count, len(groups.Items)); err != nil {
+		for _, groupName := range resourceGroups {
+			id := commonids.NewResourceGroupID(subscriptionId.SubscriptionId, groupName)
+			rep.Record(skippedResourceGroupEntry(id, err.Error()))
+		}
+		return err
+	}
+	if guard.CountOnly() {
+		return nil
+	}
+
+	// the `DoNotDelete` tag on any resource within a Resource Group also protects the Resource Group, either skipping
+	// it entirely or only deleting the other resources within it
+	protected := make(map[string][]protectedResource)
+	if len(resourceGroups) > 0 {
+		if protected, err = protectedResources(ctx, client, subscriptionId); err != nil {
+			return fmt.Errorf("finding the resources with the tag %q: %+v", matcher.ProtectionTag, err)
+		}
+	}
+	if opts.ProtectedResources != options.ProtectedResourcesDeleteUnprotected {
+		resourceGroups = slices.DeleteFunc(resourceGroups, func(groupName string) bool {
+			groupProtected, ok := protected[strings.ToLower(groupName)]
+			if !ok {
+				return false
+			}
+			rule := fmt.Sprintf("it contains resources with the tag %q: %s", matcher.ProtectionTag, describeProtectedResources(groupProtected))
+			logger.Info("Skipping Resource Group since it contains protected resources", logging.ResourceGroup(groupName), slog.String("rule", rule))
+			rep.Record(skippedResourceGroupEntry(commonids.NewResourceGroupID(subscriptionId.SubscriptionId, groupName), rule))
+			return true
+		})
+	}
+
+	logger.Info("Filtered the Resource Groups", slog.Int("matched", len(resourceGroups)), slog.Int("total", len(groups.Items)))
+	if limit := opts.NumberOfResourceGroupsToDelete; limit > 0 && int64(len(resourceGroups)) > limit {
+		logger.Info("Limiting this run to the first matching Resource Groups", slog.Int64("limit", limit))
+		for _, groupName := range resourceGroups[limit:] {
+			id := commonids.NewResourceGroupID(subscriptionId.SubscriptionId, groupName)
+			rep.Record(skippedResourceGroupEntry(id, fmt.Sprintf("exceeds the limit of %d Resource Groups to delete", limit)))
+		}
+		resourceGroups = resourceGroups[:limit]
+	}
+
+	stages, err := OrderedResourceGroupCleaners()
+	if err != nil {
+		return fmt.Errorf("determining the order to run the Resource Group Cleaners in: %+v", err)
+	}
+
 	// pull out a list of Resource Types supported by the cleaners
 	resourceTypes := make([]string, 0)
 	for _, cleaner := range ResourceGroupCleaners {
+		if !opts.IsCleanerEnabled(cleaner.Name()) {
+			continue
+		}
 		resourceTypes = append(resourceTypes, cleaner.ResourceTypes()...)
 	}
 
-	for _, groupName := range resourceGroups {
-		log.Printf("[DEBUG] Resource Group: %q", groupName)
+	parallelism := opts.Parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	logger.Debug("Processing the Resource Groups", slog.Int("count", len(resourceGroups)), slog.Int("parallelism", parallelism))
 
-		id := commonids.NewResourceGroupID(subscriptionId.SubscriptionId, groupName)
-		if !opts.ActuallyDelete {
-			log.Printf("[DEBUG]   Would have deleted %s..", id)
-			continue
+	queue := make(chan string)
+	go func() {
+		defer close(queue)
+		for _, groupName := range resourceGroups {
+			select {
+			case queue <- groupName:
+			case <-ctx.Done():
+				return
+			}
 		}
+	}()
 
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	errs := make([]error, 0)
+	pendingDeletions := make(map[commonids.ResourceGroupId]*pendingResourceGroupDeletion)
+	for range parallelism {
+		wg.Go(func() {
+			for groupName := range queue {
+				id := commonids.NewResourceGroupID(subscriptionId.SubscriptionId, groupName)
+				entry := resourceGroupEntry(id)
+				entry.Rule = rules[groupName]
+				startedAt := time.Now()
+
+				// buffer the log lines for each Resource Group so that these are output together, rather
+				// than being interleaved with those from the other Resource Groups being processed
+				groupLogger, flush := logging.Buffered(logger.With(logging.ResourceGroup(groupName)))
+				groupCtx := logging.WithLogger(ctx, groupLogger)
+				poller, err := d.cleanupResourceGroup(groupCtx, client, opts, planItems[groupName], protected[strings.ToLower(groupName)], stages, resourceTypes, &entry)
+				entry.DurationSeconds = time.Since(startedAt).Seconds()
+				if entry.Action == report.ActionDeletionTriggered || entry.Action == report.ActionPartiallyDeleted || entry.Action == report.ActionFailed {
+					metrics.FromContext(ctx).ObserveResourceGroupCleanup(time.Since(startedAt))
+				}
+
+				mu.Lock()
+				flush(ctx)
+				if err != nil {
+					errs = append(errs, err)
+				}
+				if poller != nil && opts.WaitForDeletion {
+					pendingDeletions[id] = &pendingResourceGroupDeletion{
+						entry:     entry,
+						poller:    *poller,
+						startedAt: startedAt,
+					}
+				} else {
+					rep.Record(entry)
+				}
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+
+	// any Resource Groups which weren't processed before the context expired will need to be processed in a later run
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, fmt.Errorf("processing the Resource Groups within %s: %+v", subscriptionId, err))
+	}
+
+	if opts.WaitForDeletion {
+		errs = append(errs, d.waitForDeletions(ctx, pendingDeletions, opts.WaitForDeletionTimeout)...)
+		for _, pending := range pendingDeletions {
+			rep.Record(pending.entry)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+type pendingResourceGroupDeletion struct {
+	entry     report.Entry
+	poller    pollers.Poller
+	startedAt time.Time
+}
+
+// cleanupResourceGroup runs the Resource Group Cleaners against the specified Resource Group and then triggers its deletion,
+// returning the Poller for the deletion when it was triggered. When the Resource Group contains protected resources only
+// the other resources within it are deleted instead. The outcome is recorded into the specified report Entry.
+func (d deleteResourceGroupsInSubscriptionCleaner) cleanupResourceGroup(ctx context.Context, client *clients.AzureClient, opts options.Options, planItem plan.Item, protected []protectedResource, stages [][]ResourceGroupCleaner, resourceTypes []string, entry *report.Entry) (*pollers.Poller, error) {
+	id, err := commonids.ParseResourceGroupID(planItem.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := logging.FromContext(ctx)
+	logger.Debug("Processing Resource Group")
+
+	if err := plan.FromContext(ctx).Check(planItem); err != nil {
+		logger.Warn("Skipping Resource Group", logging.Err(err))
+		entry.Action = report.ActionSkipped
+		entry.Rule = err.Error()
+		return nil, nil
+	}
+
+	// the Resource Group Cleaners aren't run here, since these change the resources (and Locks) within the
+	// Resource Group regardless of whether these are protected
+	if len(protected) > 0 {
+		return nil, d.deleteUnprotectedResources(ctx, client, opts, *id, protected, entry)
+	}
+
+	progress := checkpoint.FromContext(ctx)
+	cleanerErrs := make([]error, 0)
+	if progress.IsComplete(id.ID(), "cleaners") {
+		logger.Debug("Skipping the Resource Group Cleaners since these were completed by a previous run")
+	} else {
 		// Locks and Nested Items within the Resource Group can cause issues during deletion
 		// as such we have a set of Cleaners to go through and remove these locks/items
 		// which are split out for simplicity since there's a number of them
 		//
 		// However since there's a non-trivial number of these, let's try and determine if we
 		// need to run the cleaners first
-		needsCleaners, err := d.resourceGroupContainsResourceTypes(ctx, client, id, resourceTypes)
+		needsCleaners, err := d.resourceGroupContainsResourceTypes(ctx, client, *id, resourceTypes)
 		if err != nil {
-			return fmt.Errorf("determining if %s contains the resource types needed for cleaning: %+v", id, err)
+			err = fmt.Errorf("determining if %s contains the resource types needed for cleaning: %+v", id, err)
+			entry.Action = report.ActionFailed
+			entry.Error = err.Error()
+			return nil, err
 		}
 
 		if *needsCleaners {
-			log.Printf("[DEBUG] Running Resource Group Cleaners for %s..", id)
-			for _, cleaner := range ResourceGroupCleaners {
-				log.Printf("[DEBUG] Running Resource Group Cleaner %q..", cleaner.Name())
-				if err := cleaner.Cleanup(ctx, id, client, opts); err != nil {
-					log.Printf("running Cleaner %q for %s: %+v", cleaner.Name(), id, err)
-					continue
+			cleanerErrs = d.runResourceGroupCleaners(ctx, client, opts, *id, stages, entry)
+		} else {
+			logger.Debug("Skipping the Resource Group Cleaners since the Resource Group contains none of the Resource Types these clean")
+		}
+
+		if len(cleanerErrs) == 0 {
+			if err := progress.Complete(id.ID(), "cleaners"); err != nil {
+				cleanerErrs = append(cleanerErrs, fmt.Errorf("recording progress: %+v", err))
+			}
+		}
+	}
+
+	if !opts.ActuallyDelete {
+		logger.Info("Would have deleted Resource Group", logging.ResourceID(id.ID()))
+		entry.Action = report.ActionWouldDelete
+		if len(cleanerErrs) > 0 {
+			entry.Error = errors.Join(cleanerErrs...).Error()
+		}
+		return nil, nil
+	}
+
+	logger.Info("Deleting Resource Group", logging.ResourceID(id.ID()))
+	// NOTE: we're intentionally not using DeleteThenPoll since fire-and-forgetting these is fine - when we're
+	// waiting for the deletions to complete, that's done once all the deletions have been triggered
+	var resp resourcegroups.DeleteOperationResponse
+	err = retry.Do(ctx, fmt.Sprintf("deleting %s", id), func(ctx context.Context) (*http.Response, error) {
+		var err error
+		resp, err = client.ResourceManager.ResourcesGroupsClient.Delete(ctx, *id, resourcegroups.DefaultDeleteOperationOptions())
+		return resp.HttpResponse, err
+	})
+	if err != nil {
+		logger.Error("Deleting Resource Group", logging.ResourceID(id.ID()), logging.Err(err))
+		entry.Action = report.ActionFailed
+		entry.Error = errors.Join(append(cleanerErrs, err)...).Error()
+		return nil, fmt.Errorf("deleting %s: %+v", id, err)
+	}
+	logger.Info("Triggered the deletion of Resource Group", logging.ResourceID(id.ID()))
+	entry.Action = report.ActionDeletionTriggered
+	if len(cleanerErrs) > 0 {
+		entry.Error = errors.Join(cleanerErrs...).Error()
+	}
+
+	return &resp.Poller, nil
+}
+
+// runResourceGroupCleaners runs the Resource Group Cleaners against the specified Resource Group, stage by stage. Each
+// Cleaner discovers the Actions it needs to take, which are then executed when deleting - or otherwise only logged, so
+// that a dry-run shows everything which would be changed. These Actions are recorded into the specified report Entry.
+func (d deleteResourceGroupsInSubscriptionCleaner) runResourceGroupCleaners(ctx context.Context, client *clients.AzureClient, opts options.Options, id commonids.ResourceGroupId, stages [][]ResourceGroupCleaner, entry *report.Entry) []error {
+	logger := logging.FromContext(ctx)
+	progress := checkpoint.FromContext(ctx)
+	logger.Debug("Running the Resource Group Cleaners")
+
+	errs := make([]error, 0)
+	for _, stage := range stages {
+		// the Cleaners within a stage don't depend on one another, so can be run in parallel
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		for _, cleaner := range stage {
+			if !opts.IsCleanerEnabled(cleaner.Name()) {
+				continue
+			}
+			if progress.IsComplete(id.ID(), cleaner.Name()) {
+				logger.Debug("Skipping Resource Group Cleaner since it was completed by a previous run", logging.Cleaner(cleaner.Name()))
+				continue
+			}
+
+			if opts.ActuallyDelete {
+				entry.Cleaners = append(entry.Cleaners, cleaner.Name())
+			}
+			wg.Go(func() {
+				cleanerCtx := logging.WithAttrs(ctx, logging.Cleaner(cleaner.Name()))
+				cleanerLogger := logging.FromContext(cleanerCtx)
+				cleanerLogger.Debug("Running Resource Group Cleaner")
+
+				actions, err := cleaner.Discover(cleanerCtx, id, client, opts)
+				var performed []report.CleanerAction
+				if err == nil {
+					performed, err = executeActions(cleanerCtx, cleaner.Name(), actions, opts.ActuallyDelete)
+				}
+				if opts.ActuallyDelete {
+					metrics.FromContext(ctx).ObserveCleaner(cleaner.Name(), err)
+				}
+				if err == nil {
+					err = progress.Complete(id.ID(), cleaner.Name())
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+				entry.CleanerActions = append(entry.CleanerActions, performed...)
+				if err != nil {
+					cleanerLogger.Error("Running Resource Group Cleaner", logging.Err(err))
+					errs = append(errs, fmt.Errorf("running Cleaner %q: %+v", cleaner.Name(), err))
 				}
+			})
+		}
+		wg.Wait()
+	}
+
+	return errs
+}
+
+// executeActions executes the Actions in order when deleting (otherwise only logging these), returning what was done.
+// This stops at the first Action which fails, unless that Action continues on error.
+func executeActions(ctx context.Context, cleanerName string, actions []Action, actuallyDelete bool) ([]report.CleanerAction, error) {
+	logger := logging.FromContext(ctx)
+	output := make([]report.CleanerAction, 0, len(actions))
+	for _, action := range actions {
+		performed := report.CleanerAction{
+			Cleaner:     cleanerName,
+			Description: action.Description,
+			ID:          action.ResourceID,
+			Performed:   actuallyDelete,
+		}
+		if !actuallyDelete {
+			logger.Info("Would have performed Resource Group Cleaner action", slog.String("action", action.Description), logging.ResourceID(action.ResourceID))
+			output = append(output, performed)
+			continue
+		}
+
+		logger.Info("Performing Resource Group Cleaner action", slog.String("action", action.Description), logging.ResourceID(action.ResourceID))
+		if err := action.Execute(ctx); err != nil {
+			performed.Error = err.Error()
+			output = append(output, performed)
+			if action.ContinueOnError {
+				logger.Error("Performing Resource Group Cleaner action", slog.String("action", action.Description), logging.ResourceID(action.ResourceID), logging.Err(err))
+				continue
 			}
-		} else {
-			log.Printf("[DEBUG] Skipping Resource Group Cleaners for %s..", id)
+			return output, fmt.Errorf("%s %s: %+v", strings.ToLower(action.Description), action.ResourceID, err)
 		}
+		logger.Info("Performed Resource Group Cleaner action", slog.String("action", action.Description), logging.ResourceID(action.ResourceID))
+		output = append(output, performed)
+	}
+
+	return output, nil
+}
+
+// errWaitForDeletionTimedOut is the cause of the context used to wait for the deletions being cancelled when
+// `-wait-for-deletion-timeout` is reached
+var errWaitForDeletionTimedOut = errors.New("timed out waiting for the deletion of the Resource Groups to complete")
 
-		log.Printf("[DEBUG]   Deleting Resource Group %q..", groupName)
-		// NOTE: we're intentionally not using DeleteThenPoll since fire-and-forgetting these is fine
-		if _, err := client.ResourceManager.ResourcesGroupsClient.Delete(ctx, id, resourcegroups.DefaultDeleteOperationOptions()); err != nil {
-			log.Printf("[DEBUG]   Error during deletion of Resource Group %q: %s", groupName, err)
+// waitForDeletions polls each of the triggered Resource Group deletions until these complete or the timeout is
+// reached, returning an error for each Resource Group which failed to delete or didn't finish deleting in time. The
+// outcome of each deletion is recorded into its report Entry - when the run is cancelled whilst waiting, the
+// deletions which hadn't completed are recorded as triggered rather than failed.
+func (d deleteResourceGroupsInSubscriptionCleaner) waitForDeletions(ctx context.Context, pendingDeletions map[commonids.ResourceGroupId]*pendingResourceGroupDeletion, timeout time.Duration) []error {
+	if len(pendingDeletions) == 0 {
+		return nil
+	}
+
+	logger := logging.FromContext(ctx)
+	logger.Info("Waiting for the deletion of the Resource Groups to complete", slog.Int("count", len(pendingDeletions)), slog.Duration("timeout", timeout))
+	// the run itself can be cancelled (or time out) whilst waiting, which mustn't be reported as the wait timing out
+	waitCtx, cancel := context.WithTimeoutCause(ctx, timeout, errWaitForDeletionTimedOut)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	outcomes := make(map[commonids.ResourceGroupId]error, len(pendingDeletions))
+	for id, pending := range pendingDeletions {
+		wg.Go(func() {
+			err := pending.poller.PollUntilDone(waitCtx)
+			stoppedWaiting := false
+			if err != nil && waitCtx.Err() != nil {
+				if cause := context.Cause(waitCtx); errors.Is(cause, errWaitForDeletionTimedOut) {
+					err = fmt.Errorf("timed out after %s waiting for the deletion to complete", timeout)
+				} else {
+					// the deletion was triggered and may well still complete, we just don't know
+					stoppedWaiting = true
+					err = fmt.Errorf("stopped waiting for the deletion to complete: %+v", cause)
+				}
+			}
+			pending.entry.DurationSeconds = time.Since(pending.startedAt).Seconds()
+			switch {
+			case stoppedWaiting:
+				pending.entry.Action = report.ActionDeletionTriggered
+				pending.entry.Error = err.Error()
+			case err != nil:
+				pending.entry.Action = report.ActionFailed
+				pending.entry.Error = err.Error()
+			default:
+				pending.entry.Action = report.ActionDeleted
+			}
+
+			mu.Lock()
+			outcomes[id] = err
+			mu.Unlock()
+		})
+	}
+	wg.Wait()
+
+	ids := make([]commonids.ResourceGroupId, 0, len(outcomes))
+	for id := range outcomes {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i].ResourceGroupName < ids[j].ResourceGroupName
+	})
+
+	errs := make([]error, 0)
+	for _, id := range ids {
+		if err := outcomes[id]; err != nil {
+			logger.Error("Deleting Resource Group", logging.ResourceGroup(id.ResourceGroupName), logging.ResourceID(id.ID()), logging.Err(err))
+			errs = append(errs, fmt.Errorf("deleting %s: %+v", id, err))
 			continue
 		}
-		log.Printf("[DEBUG]   Deletion triggered for Resource Group %q", groupName)
+		logger.Info("Deleted Resource Group", logging.ResourceGroup(id.ResourceGroupName), logging.ResourceID(id.ID()))
 	}
+	logger.Info("Finished waiting for the deletion of the Resource Groups", slog.Int("deleted", len(ids)-len(errs)), slog.Int("total", len(ids)))
 
-	return nil
+	return errs
 }
 
 func (d deleteResourceGroupsInSubscriptionCleaner) resourceGroupContainsResourceTypes(ctx context.Context, client *clients.AzureClient, id commonids.ResourceGroupId, resourceTypes []string) (*bool, error) {
+	// there's nothing to look for when every Resource Group Cleaner has been disabled
+	if len(resourceTypes) == 0 {
+		return pointer.To(false), nil
+	}
+
 	items := make([]string, 0, len(resourceTypes))
 	for _, resourceType := range resourceTypes {
 		items = append(items, fmt.Sprintf("'%s'", resourceType))
@@ -164,20 +571,84 @@ resources
 	return pointer.To(containsItems), nil
 }
 
-func shouldDeleteResourceGroup(input resourcegroups.ResourceGroup, prefix string) bool {
-	if prefix != "" {
-		if !strings.HasPrefix(strings.ToLower(*input.Name), strings.ToLower(prefix)) {
-			return false
-		}
+// resourceGroupObject returns the Resource Group as the Object which the Matcher uses
+func resourceGroupObject(input resourcegroups.ResourceGroup) matcher.Object {
+	object := matcher.Object{
+		Name:     pointer.From(input.Name),
+		Location: input.Location,
+		Tags:     pointer.From(input.Tags),
+	}
+	if input.Properties != nil {
+		object.ProvisioningState = pointer.From(input.Properties.ProvisioningState)
 	}
+	return object
+}
 
-	if tags := input.Tags; tags != nil {
-		for k := range *tags {
-			if strings.EqualFold(k, "donotdelete") {
-				return false
-			}
+// resourceGroupCreatedTimes returns when each Resource Group within the Subscription was created, keyed by the
+// lower-cased name of the Resource Group. Resource Groups whose creation time isn't available are omitted.
+func resourceGroupCreatedTimes(ctx context.Context, client *clients.AzureClient, subscriptionId commonids.SubscriptionId) (map[string]time.Time, error) {
+	query := `
+resourcecontainers
+| where type =~ 'microsoft.resources/subscriptions/resourcegroups'
+| extend createdTime = coalesce(tostring(properties.createdTime), tostring(column_ifexists('systemData', dynamic(null)).createdAt))
+| where isnotempty(createdTime)
+| project name, createdTime
+| sort by (tolower(tostring(name))) asc
+`
+	items, err := queryResourceGraph(ctx, client, subscriptionId.SubscriptionId, query)
+	if err != nil {
+		return nil, err
+	}
+
+	output := make(map[string]time.Time)
+	for index, item := range items {
+		name, _ := item["name"].(string)
+		createdTimeRaw, _ := item["createdTime"].(string)
+		if name == "" || createdTimeRaw == "" {
+			return nil, fmt.Errorf("expected a name and createdTime for item %d but didn't get them", index)
 		}
+		createdTime, err := time.Parse(time.RFC3339Nano, createdTimeRaw)
+		if err != nil {
+			return nil, fmt.Errorf("parsing the createdTime %q for the Resource Group %q: %+v", createdTimeRaw, name, err)
+		}
+		output[strings.ToLower(name)] = createdTime
+	}
+
+	return output, nil
+}
+
+func resourceGroupEntry(id commonids.ResourceGroupId) report.Entry {
+	return report.Entry{
+		ID:    id.ID(),
+		Name:  id.ResourceGroupName,
+		Kind:  report.KindResourceGroup,
+		Scope: id.SubscriptionId,
+	}
+}
+
+// resourceGroupPlanItem returns the plan Item for the Resource Group - which includes when it was created, so that a
+// Resource Group which is deleted and recreated with the same name after the plan was created isn't deleted
+func resourceGroupPlanItem(id commonids.ResourceGroupId, input resourcegroups.ResourceGroup, createdTime *time.Time) plan.Item {
+	attributes := plan.TagAttributes(input.Tags)
+	attributes["location"] = input.Location
+	attributes["managedBy"] = pointer.From(input.ManagedBy)
+	attributes["createdTime"] = ""
+	if createdTime != nil {
+		attributes["createdTime"] = createdTime.UTC().Format(time.RFC3339Nano)
+	}
+
+	return plan.Item{
+		Kind:       report.KindResourceGroup,
+		ID:         id.ID(),
+		Name:       id.ResourceGroupName,
+		Scope:      id.SubscriptionId,
+		Attributes: attributes,
 	}
+}
 
-	return true
+func skippedResourceGroupEntry(id commonids.ResourceGroupId, rule string) report.Entry {
+	entry := resourceGroupEntry(id)
+	entry.Action = report.ActionSkipped
+	entry.Rule = rule
+	return entry
 }
@@ -0,0 +1,214 @@
+package cleaners
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/go-azure-helpers/lang/pointer"
+	"github.com/hashicorp/go-azure-helpers/resourcemanager/commonids"
+	"github.com/hashicorp/go-azure-sdk/resource-manager/resourcegraph/2024-04-01/resources"
+	"github.com/jackofallops/azurerm-dalek/clients"
+	"github.com/jackofallops/azurerm-dalek/dalek/logging"
+	"github.com/jackofallops/azurerm-dalek/dalek/matcher"
+	"github.com/jackofallops/azurerm-dalek/dalek/options"
+	"github.com/jackofallops/azurerm-dalek/dalek/report"
+	"github.com/jackofallops/azurerm-dalek/dalek/retry"
+)
+
+// unprotectedResourcesCleanerName is recorded against the resources deleted from a Resource Group which contains
+// protected resources, in place of the name of a Resource Group Cleaner
+const unprotectedResourcesCleanerName = "Delete Unprotected Resources"
+
+// protectedResource is a resource within a Resource Group which has the `DoNotDelete` tag
+type protectedResource struct {
+	ID  string
+	Tag string
+}
+
+// protectedResources returns the resources within the Subscription which have the `DoNotDelete` tag, keyed by the
+// lower-cased name of the Resource Group containing them
+func protectedResources(ctx context.Context, client *clients.AzureClient, subscriptionId commonids.SubscriptionId) (map[string][]protectedResource, error) {
+	// the tag's name is compared case-insensitively, so this is narrowed down here and then checked exactly below
+	query := fmt.Sprintf(`
+resources
+| where tostring(tags) contains '%s'
+| project id, resourceGroup, tags
+| sort by (tolower(tostring(id))) asc
+`, matcher.ProtectionTag)
+	items, err := queryResourceGraph(ctx, client, subscriptionId.SubscriptionId, query)
+	if err != nil {
+		return nil, err
+	}
+
+	output := make(map[string][]protectedResource)
+	for _, item := range items {
+		id, _ := item["id"].(string)
+		resourceGroup, _ := item["resourceGroup"].(string)
+		tag, ok := matcher.ProtectedBy(resourceGraphTags(item))
+		if id == "" || resourceGroup == "" || !ok {
+			continue
+		}
+		key := strings.ToLower(resourceGroup)
+		output[key] = append(output[key], protectedResource{
+			ID:  id,
+			Tag: tag,
+		})
+	}
+
+	return output, nil
+}
+
+// deleteUnprotectedResources deletes the resources within the Resource Group other than those which are protected (or
+// which are nested within, or contain, a protected resource), rather than deleting the Resource Group itself. The
+// outcome is recorded into the specified report Entry.
+func (d deleteResourceGroupsInSubscriptionCleaner) deleteUnprotectedResources(ctx context.Context, client *clients.AzureClient, opts options.Options, id commonids.ResourceGroupId, protected []protectedResource, entry *report.Entry) error {
+	logger := logging.FromContext(ctx)
+	entry.Rule = fmt.Sprintf("%s, but it contains resources with the tag %q so only the others within it are deleted: %s", entry.Rule, matcher.ProtectionTag, describeProtectedResources(protected))
+	logger.Info("Deleting only the unprotected resources within the Resource Group since it contains protected resources", slog.String("protected", describeProtectedResources(protected)))
+
+	query := fmt.Sprintf(`
+resources
+| where resourceGroup =~ '%s'
+| project id, type, tags
+| sort by (tolower(tostring(id))) asc
+`, id.ResourceGroupName)
+	items, err := queryResourceGraph(ctx, client, id.SubscriptionId, query)
+	if err != nil {
+		err = fmt.Errorf("listing the resources within %s: %+v", id, err)
+		entry.Action = report.ActionFailed
+		entry.Error = err.Error()
+		return err
+	}
+
+	actions := make([]Action, 0)
+	for _, item := range items {
+		resourceId, _ := item["id"].(string)
+		resourceType, _ := item["type"].(string)
+		if resourceId == "" || resourceType == "" || isProtected(resourceId, protected) {
+			continue
+		}
+		actions = append(actions, Action{
+			Description: "Delete Resource",
+			ResourceID:  resourceId,
+			// there's no telling which resources depend on one another, so any which can't be deleted yet are
+			// deleted by a subsequent run
+			ContinueOnError: true,
+			Execute: func(ctx context.Context) error {
+				return retry.Do(ctx, fmt.Sprintf("deleting %s", resourceId), func(ctx context.Context) (*http.Response, error) {
+					return client.ResourceManager.GenericResourcesClient.Delete(ctx, id.SubscriptionId, resourceType, resourceId)
+				})
+			},
+		})
+	}
+
+	performed, _ := executeActions(ctx, unprotectedResourcesCleanerName, actions, opts.ActuallyDelete)
+	entry.CleanerActions = append(entry.CleanerActions, performed...)
+	if !opts.ActuallyDelete {
+		entry.Action = report.ActionWouldPartiallyDelete
+		return nil
+	}
+
+	errs := make([]error, 0)
+	for _, action := range performed {
+		if action.Error != "" {
+			errs = append(errs, fmt.Errorf("deleting %s: %s", action.ID, action.Error))
+		}
+	}
+	if len(errs) > 0 {
+		err := fmt.Errorf("deleting the unprotected resources within %s: %+v", id, errors.Join(errs...))
+		entry.Action = report.ActionFailed
+		entry.Error = err.Error()
+		return err
+	}
+
+	entry.Action = report.ActionPartiallyDeleted
+	return nil
+}
+
+// isProtected returns whether the resource is protected, is nested within a protected resource, or contains one -
+// since deleting it would delete the protected resource too
+func isProtected(id string, protected []protectedResource) bool {
+	id = strings.ToLower(id)
+	for _, v := range protected {
+		other := strings.ToLower(v.ID)
+		if id == other || strings.HasPrefix(id, other+"/") || strings.HasPrefix(other, id+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func describeProtectedResources(protected []protectedResource) string {
+	ids := make([]string, 0, len(protected))
+	for _, v := range protected {
+		ids = append(ids, v.ID)
+	}
+	return strings.Join(ids, ", ")
+}
+
+// resourceGraphTags returns the tags from an item returned by a Resource Graph query
+func resourceGraphTags(item map[string]interface{}) map[string]string {
+	output := make(map[string]string)
+	tags, ok := item["tags"].(map[string]interface{})
+	if !ok {
+		return output
+	}
+	for k, v := range tags {
+		output[k] = fmt.Sprintf("%v", v)
+	}
+	return output
+}
+
+// queryResourceGraph performs the Resource Graph query against the Subscription, returning every item across each
+// page of results
+func queryResourceGraph(ctx context.Context, client *clients.AzureClient, subscriptionId string, query string) ([]map[string]interface{}, error) {
+	query = strings.TrimSpace(query)
+	output := make([]map[string]interface{}, 0)
+	var skipToken *string
+	for {
+		payload := resources.QueryRequest{
+			Options: &resources.QueryRequestOptions{
+				SkipToken: skipToken,
+				Top:       pointer.To(int64(1000)),
+			},
+			Query: query,
+			Subscriptions: &[]string{
+				subscriptionId,
+			},
+		}
+		resp, err := client.ResourceManager.ResourceGraphClient.Resources(ctx, payload)
+		if err != nil {
+			return nil, fmt.Errorf("performing graph query %q: %+v", query, err)
+		}
+
+		if resp.Model == nil {
+			return nil, fmt.Errorf("performing graph query %q: response was nil", query)
+		}
+		if resp.Model.Data == nil {
+			return nil, fmt.Errorf("performing graph query %q: response.data was nil", query)
+		}
+
+		itemsRaw, ok := resp.Model.Data.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected the data to be an []interface but got %+v", resp.Model.Data)
+		}
+		for index, itemRaw := range itemsRaw {
+			item, ok := itemRaw.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("expected index %d to be a map[string]interface{} but it wasn't", index)
+			}
+			output = append(output, item)
+		}
+
+		if resp.Model.SkipToken == nil || *resp.Model.SkipToken == "" {
+			break
+		}
+		skipToken = resp.Model.SkipToken
+	}
+
+	return output, nil
+}
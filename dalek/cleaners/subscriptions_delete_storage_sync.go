@@ -4,8 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
-	"strings"
+	"log/slog"
+	"net/http"
+	"time"
 
 	"github.com/hashicorp/go-azure-helpers/resourcemanager/commonids"
 	"github.com/hashicorp/go-azure-sdk/resource-manager/storagesync/2020-03-01/cloudendpointresource"
@@ -13,7 +14,12 @@ import (
 	"github.com/hashicorp/go-azure-sdk/resource-manager/storagesync/2020-03-01/storagesyncservicesresource"
 	"github.com/hashicorp/go-azure-sdk/resource-manager/storagesync/2020-03-01/syncgroupresource"
 	"github.com/jackofallops/azurerm-dalek/clients"
+	"github.com/jackofallops/azurerm-dalek/dalek/logging"
+	"github.com/jackofallops/azurerm-dalek/dalek/matcher"
 	"github.com/jackofallops/azurerm-dalek/dalek/options"
+	"github.com/jackofallops/azurerm-dalek/dalek/plan"
+	"github.com/jackofallops/azurerm-dalek/dalek/report"
+	"github.com/jackofallops/azurerm-dalek/dalek/retry"
 )
 
 type deleteStorageSyncSubscriptionCleaner struct{}
@@ -24,11 +30,15 @@ func (p deleteStorageSyncSubscriptionCleaner) Name() string {
 	return "Removing Storage Sync"
 }
 
+func (p deleteStorageSyncSubscriptionCleaner) RunsAfter() []SubscriptionCleaner {
+	return nil
+}
+
 func (p deleteStorageSyncSubscriptionCleaner) Cleanup(ctx context.Context, subscriptionId commonids.SubscriptionId, client *clients.AzureClient, opts options.Options) error {
+	logger := logging.FromContext(ctx)
+	rep := report.FromContext(ctx)
+	m := matcher.New(opts)
 	storageSyncClient := client.ResourceManager.StorageSyncClient
-	storageSyncGroupClient := client.ResourceManager.StorageSyncGroupClient
-	storageSyncCloudEndpointClient := client.ResourceManager.StorageSyncCloudEndpointClient
-	storageSyncRegisteredServerClient := client.ResourceManager.StorageSyncRegisteredServerClient
 
 	errs := make([]error, 0)
 
@@ -52,130 +62,174 @@ func (p deleteStorageSyncSubscriptionCleaner) Cleanup(ctx context.Context, subsc
 			continue
 		}
 
-		if !strings.HasPrefix(id.ResourceGroupName, opts.Prefix) {
-			log.Printf("[DEBUG] Not deleting %q as it does not match target RG prefix %q", *id, opts.Prefix)
-			continue
-		}
-
-		// Storage Sync Registered Servers Cleanup
-
-		registeredServers, err := storageSyncRegisteredServerClient.RegisteredServersListByStorageSyncService(ctx, registeredserverresource.StorageSyncServiceId(*id))
+		entry := subscriptionEntry(report.KindStorageSyncService, subscriptionId, id, id.StorageSyncServiceName)
+		resourceGroupId := commonids.NewResourceGroupID(id.SubscriptionId, id.ResourceGroupName)
+		matched, rule, err := matchResource(ctx, client, m, resourceGroupId, "Microsoft.StorageSync/storageSyncServices", id.ID(), storageSync.Tags)
 		if err != nil {
 			errs = append(errs, err)
+			entry.Action = report.ActionSkipped
+			entry.Error = err.Error()
+			rep.Record(entry)
+			continue
+		}
+		entry.Rule = rule
+		if !matched {
+			logger.Debug("Skipping Storage Sync Service", logging.ResourceID(id.ID()), slog.String("rule", rule))
+			entry.Action = report.ActionSkipped
+			rep.Record(entry)
 			continue
 		}
 
-		if registeredServers.Model == nil || registeredServers.Model.Value == nil {
+		if err := plan.FromContext(ctx).Check(subscriptionPlanItem(report.KindStorageSyncService, subscriptionId, id, id.StorageSyncServiceName, storageSync.Tags)); err != nil {
+			logger.Warn("Skipping Storage Sync Service", logging.ResourceID(id.ID()), logging.Err(err))
+			entry.Action = report.ActionSkipped
+			entry.Rule = err.Error()
+			rep.Record(entry)
 			continue
 		}
 
-		for _, s := range *registeredServers.Model.Value {
-			if s.Id == nil {
-				continue
+		startedAt := time.Now()
+		err = p.deleteStorageSyncService(ctx, client, *id, opts.ActuallyDelete)
+		entry.DurationSeconds = time.Since(startedAt).Seconds()
+		entry.Action = report.ActionDeleted
+		if !opts.ActuallyDelete {
+			entry.Action = report.ActionWouldDelete
+		}
+		if err != nil {
+			errs = append(errs, err)
+			if opts.ActuallyDelete {
+				entry.Action = report.ActionFailed
 			}
+			entry.Error = err.Error()
+		}
+		rep.Record(entry)
+	}
 
-			registeredServerID, err := registeredserverresource.ParseRegisteredServerID(*s.Id)
-			if err != nil {
-				errs = append(errs, err)
-				continue
-			}
+	return errors.Join(errs...)
+}
 
-			if !opts.ActuallyDelete {
-				log.Printf("[DEBUG] Would have deleted %s", registeredServerID)
-				continue
-			}
+// deleteStorageSyncService deletes the Registered Servers, Sync Groups (and their Cloud Endpoints) within the Storage
+// Sync Service and then the Storage Sync Service itself - when actuallyDelete isn't set these are only listed
+func (p deleteStorageSyncSubscriptionCleaner) deleteStorageSyncService(ctx context.Context, client *clients.AzureClient, id storagesyncservicesresource.StorageSyncServiceId, actuallyDelete bool) error {
+	logger := logging.FromContext(ctx)
+	storageSyncClient := client.ResourceManager.StorageSyncClient
+	storageSyncGroupClient := client.ResourceManager.StorageSyncGroupClient
+	storageSyncCloudEndpointClient := client.ResourceManager.StorageSyncCloudEndpointClient
+	storageSyncRegisteredServerClient := client.ResourceManager.StorageSyncRegisteredServerClient
 
-			if err := storageSyncRegisteredServerClient.RegisteredServersDeleteThenPoll(ctx, *registeredServerID); err != nil {
-				errs = append(errs, fmt.Errorf("deleting %s: %w", registeredServerID, err))
-			}
-		}
+	errs := make([]error, 0)
 
-		// Storage Sync Group Cleanup
+	// Storage Sync Registered Servers Cleanup
 
-		if !opts.ActuallyDelete {
-			log.Printf("[DEBUG] Would have deleted %s..", id)
+	registeredServers, err := storageSyncRegisteredServerClient.RegisteredServersListByStorageSyncService(ctx, registeredserverresource.StorageSyncServiceId(id))
+	if err != nil {
+		return fmt.Errorf("listing registered servers for %s: %+v", id, err)
+	}
+
+	if registeredServers.Model == nil || registeredServers.Model.Value == nil {
+		return fmt.Errorf("listing registered servers for %s: model/value was nil", id)
+	}
+
+	for _, s := range *registeredServers.Model.Value {
+		if s.Id == nil {
 			continue
 		}
 
-		groupList, err := storageSyncGroupClient.SyncGroupsListByStorageSyncService(ctx, syncgroupresource.StorageSyncServiceId(*id))
+		registeredServerID, err := registeredserverresource.ParseRegisteredServerID(*s.Id)
 		if err != nil {
-			errs = append(errs, fmt.Errorf("listing storage sync groups for %s: %+v", id, err))
+			errs = append(errs, err)
+			continue
 		}
 
-		if groupList.Model == nil || groupList.Model.Value == nil {
+		if !actuallyDelete {
+			logger.Info("Would have deleted Registered Server", logging.ResourceID(registeredServerID.ID()))
 			continue
 		}
 
-		for _, group := range *groupList.Model.Value {
-			if group.Id == nil {
-				continue
-			}
+		if err := retry.Do(ctx, fmt.Sprintf("deleting %s", registeredServerID), func(ctx context.Context) (*http.Response, error) {
+			return nil, storageSyncRegisteredServerClient.RegisteredServersDeleteThenPoll(ctx, *registeredServerID)
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("deleting %s: %w", registeredServerID, err))
+		}
+	}
 
-			groupIdForCloudEndpoint, err := cloudendpointresource.ParseSyncGroupID(*group.Id)
-			if err != nil {
-				errs = append(errs, err)
-				continue
-			}
+	// Storage Sync Group Cleanup
 
-			if !opts.ActuallyDelete {
-				log.Printf("[DEBUG] Would have deleted %s..", groupIdForCloudEndpoint)
-				continue
-			}
+	if !actuallyDelete {
+		logger.Info("Would have deleted Storage Sync Service", logging.ResourceID(id.ID()))
+		return errors.Join(errs...)
+	}
 
-			cloudEndpointList, err := storageSyncCloudEndpointClient.CloudEndpointsListBySyncGroup(ctx, *groupIdForCloudEndpoint)
-			if err != nil {
-				errs = append(errs, fmt.Errorf("listing cloud endpoints for %s: %+v", groupIdForCloudEndpoint, err))
-				continue
-			}
+	groupList, err := storageSyncGroupClient.SyncGroupsListByStorageSyncService(ctx, syncgroupresource.StorageSyncServiceId(id))
+	if err != nil {
+		return errors.Join(append(errs, fmt.Errorf("listing storage sync groups for %s: %+v", id, err))...)
+	}
 
-			if cloudEndpointList.Model == nil || cloudEndpointList.Model.Value == nil {
-				continue
-			}
+	if groupList.Model == nil || groupList.Model.Value == nil {
+		return errors.Join(append(errs, fmt.Errorf("listing storage sync groups for %s: model/value was nil", id))...)
+	}
 
-			for _, endpoint := range *cloudEndpointList.Model.Value {
-				if endpoint.Id == nil {
-					continue
-				}
-
-				endpointId, err := cloudendpointresource.ParseCloudEndpointID(*endpoint.Id)
-				if err != nil {
-					errs = append(errs, err)
-					continue
-				}
-
-				if !opts.ActuallyDelete {
-					log.Printf("[DEBUG] Would have deleted %s..", endpointId)
-					continue
-				}
-
-				if err = storageSyncCloudEndpointClient.CloudEndpointsDeleteThenPoll(ctx, *endpointId); err != nil {
-					errs = append(errs, fmt.Errorf("deleting %s: %+v", endpointId, err))
-					continue
-				}
+	for _, group := range *groupList.Model.Value {
+		if group.Id == nil {
+			continue
+		}
+
+		groupIdForCloudEndpoint, err := cloudendpointresource.ParseSyncGroupID(*group.Id)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		cloudEndpointList, err := storageSyncCloudEndpointClient.CloudEndpointsListBySyncGroup(ctx, *groupIdForCloudEndpoint)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("listing cloud endpoints for %s: %+v", groupIdForCloudEndpoint, err))
+			continue
+		}
+
+		if cloudEndpointList.Model == nil || cloudEndpointList.Model.Value == nil {
+			continue
+		}
+
+		for _, endpoint := range *cloudEndpointList.Model.Value {
+			if endpoint.Id == nil {
+				continue
 			}
 
-			groupId, err := syncgroupresource.ParseSyncGroupID(*group.Id)
+			endpointId, err := cloudendpointresource.ParseCloudEndpointID(*endpoint.Id)
 			if err != nil {
 				errs = append(errs, err)
 				continue
 			}
 
-			if _, err = storageSyncGroupClient.SyncGroupsDelete(ctx, *groupId); err != nil {
-				errs = append(errs, fmt.Errorf("deleting %s: %+v", groupId, err))
+			if err = retry.Do(ctx, fmt.Sprintf("deleting %s", endpointId), func(ctx context.Context) (*http.Response, error) {
+				return nil, storageSyncCloudEndpointClient.CloudEndpointsDeleteThenPoll(ctx, *endpointId)
+			}); err != nil {
+				errs = append(errs, fmt.Errorf("deleting %s: %+v", endpointId, err))
+				continue
 			}
 		}
 
-		// Storage Sync Service Cleanup
-
-		storageSyncId, err := storagesyncservicesresource.ParseStorageSyncServiceID(*storageSync.Id)
+		groupId, err := syncgroupresource.ParseSyncGroupID(*group.Id)
 		if err != nil {
 			errs = append(errs, err)
 			continue
 		}
-		if err = storageSyncClient.StorageSyncServicesDeleteThenPoll(ctx, *storageSyncId); err != nil {
-			errs = append(errs, fmt.Errorf("deleting %s: %+v", storageSyncId, err))
+
+		if err = retry.Do(ctx, fmt.Sprintf("deleting %s", groupId), func(ctx context.Context) (*http.Response, error) {
+			resp, err := storageSyncGroupClient.SyncGroupsDelete(ctx, *groupId)
+			return resp.HttpResponse, err
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("deleting %s: %+v", groupId, err))
 		}
 	}
 
+	// Storage Sync Service Cleanup
+
+	if err = retry.Do(ctx, fmt.Sprintf("deleting %s", id), func(ctx context.Context) (*http.Response, error) {
+		return nil, storageSyncClient.StorageSyncServicesDeleteThenPoll(ctx, id)
+	}); err != nil {
+		errs = append(errs, fmt.Errorf("deleting %s: %+v", id, err))
+	}
+
 	return errors.Join(errs...)
 }
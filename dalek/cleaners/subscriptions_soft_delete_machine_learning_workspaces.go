@@ -4,13 +4,20 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
-	"strings"
+	"log/slog"
+	"net/http"
+	"time"
 
+	"github.com/hashicorp/go-azure-helpers/lang/pointer"
 	"github.com/hashicorp/go-azure-helpers/resourcemanager/commonids"
 	"github.com/hashicorp/go-azure-sdk/resource-manager/machinelearningservices/2025-09-01/workspaces"
 	"github.com/jackofallops/azurerm-dalek/clients"
+	"github.com/jackofallops/azurerm-dalek/dalek/logging"
+	"github.com/jackofallops/azurerm-dalek/dalek/matcher"
 	"github.com/jackofallops/azurerm-dalek/dalek/options"
+	"github.com/jackofallops/azurerm-dalek/dalek/plan"
+	"github.com/jackofallops/azurerm-dalek/dalek/report"
+	"github.com/jackofallops/azurerm-dalek/dalek/retry"
 )
 
 var _ SubscriptionCleaner = purgeSoftDeletedMachineLearningWorkspacesInSubscriptionCleaner{}
@@ -21,7 +28,19 @@ func (p purgeSoftDeletedMachineLearningWorkspacesInSubscriptionCleaner) Name() s
 	return "Purging Soft Deleted Machine Learning Workspaces in Subscription"
 }
 
+func (p purgeSoftDeletedMachineLearningWorkspacesInSubscriptionCleaner) purgesSoftDeletedItems() {}
+
+func (p purgeSoftDeletedMachineLearningWorkspacesInSubscriptionCleaner) RunsAfter() []SubscriptionCleaner {
+	// deleting the Resource Groups soft-deletes the Workspaces within them, which we then want to purge
+	return []SubscriptionCleaner{
+		deleteResourceGroupsInSubscriptionCleaner{},
+	}
+}
+
 func (p purgeSoftDeletedMachineLearningWorkspacesInSubscriptionCleaner) Cleanup(ctx context.Context, subscriptionId commonids.SubscriptionId, client *clients.AzureClient, opts options.Options) error {
+	logger := logging.FromContext(ctx)
+	rep := report.FromContext(ctx)
+	m := matcher.New(opts)
 	softDeletedWorkspaces, err := client.ResourceManager.MachineLearningWorkspacesClient.ListBySubscriptionComplete(ctx, subscriptionId, workspaces.DefaultListBySubscriptionOperationOptions())
 	errs := make([]error, 0)
 	if err != nil {
@@ -35,24 +54,55 @@ func (p purgeSoftDeletedMachineLearningWorkspacesInSubscriptionCleaner) Cleanup(
 			continue
 		}
 
-		if !strings.HasSuffix(workspaceId.ResourceGroupName, opts.Prefix) {
-			log.Printf("[DEBUG] Not deleting Machine Learning Workspace %q as it does not match target RG prefix %q", *workspaceId, opts.Prefix)
+		entry := subscriptionEntry(report.KindMachineLearningWorkspace, subscriptionId, workspaceId, workspaceId.WorkspaceName)
+		// the Workspaces API doesn't distinguish soft-deleted Workspaces from live ones, and purging one which is live
+		// deletes it outright - so the minimum age and expiry rules must apply to these as to any other resource
+		matched, rule := m.Match(matcher.Object{
+			Name:        workspaceId.ResourceGroupName,
+			Tags:        pointer.From(workspace.Tags),
+			CreatedTime: systemDataCreatedTime(workspace.SystemData),
+		})
+		entry.Rule = rule
+		if !matched {
+			logger.Debug("Skipping soft-deleted Machine Learning Workspace", logging.ResourceID(workspaceId.ID()), slog.String("rule", rule))
+			entry.Action = report.ActionSkipped
+			rep.Record(entry)
+			continue
+		}
+
+		if err := plan.FromContext(ctx).Check(subscriptionPlanItem(report.KindMachineLearningWorkspace, subscriptionId, workspaceId, workspaceId.WorkspaceName, workspace.Tags)); err != nil {
+			logger.Warn("Skipping soft-deleted Machine Learning Workspace", logging.ResourceID(workspaceId.ID()), logging.Err(err))
+			entry.Action = report.ActionSkipped
+			entry.Rule = err.Error()
+			rep.Record(entry)
 			continue
 		}
 
-		log.Printf("[DEBUG] Purging Soft-Deleted %s..", *workspaceId)
 		if !opts.ActuallyDelete {
-			log.Printf("[DEBUG] Would have purged soft-deleted Machine Learning Workspace %q..", *workspaceId)
+			logger.Info("Would have purged soft-deleted Machine Learning Workspace", logging.ResourceID(workspaceId.ID()))
+			entry.Action = report.ActionWouldPurge
+			rep.Record(entry)
 			continue
 		}
 
 		purge := true
-		log.Printf("[DEBUG] Purging Soft-Deleted %s..", *workspaceId)
-		if err := client.ResourceManager.MachineLearningWorkspacesClient.DeleteThenPoll(ctx, *workspaceId, workspaces.DeleteOperationOptions{ForceToPurge: &purge}); err != nil {
-			errs = append(errs, fmt.Errorf("purging %s: %+v", *workspaceId, err))
+		logger.Info("Purging soft-deleted Machine Learning Workspace", logging.ResourceID(workspaceId.ID()))
+		startedAt := time.Now()
+		err = retry.Do(ctx, fmt.Sprintf("purging %s", workspaceId), func(ctx context.Context) (*http.Response, error) {
+			return nil, client.ResourceManager.MachineLearningWorkspacesClient.DeleteThenPoll(ctx, *workspaceId, workspaces.DeleteOperationOptions{ForceToPurge: &purge})
+		})
+		entry.DurationSeconds = time.Since(startedAt).Seconds()
+		if err != nil {
+			err = fmt.Errorf("purging %s: %+v", *workspaceId, err)
+			errs = append(errs, err)
+			entry.Action = report.ActionFailed
+			entry.Error = err.Error()
+			rep.Record(entry)
 			continue
 		}
-		log.Printf("[DEBUG] Purged Soft-Deleted %s.", *workspaceId)
+		logger.Info("Purged soft-deleted Machine Learning Workspace", logging.ResourceID(workspaceId.ID()))
+		entry.Action = report.ActionPurged
+		rep.Record(entry)
 	}
 
 	return errors.Join(errs...)
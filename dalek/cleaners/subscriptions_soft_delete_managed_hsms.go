@@ -4,12 +4,20 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
+	"time"
 
+	"github.com/hashicorp/go-azure-helpers/lang/pointer"
 	"github.com/hashicorp/go-azure-helpers/resourcemanager/commonids"
 	"github.com/hashicorp/go-azure-sdk/resource-manager/keyvault/2023-07-01/managedhsms"
 	"github.com/jackofallops/azurerm-dalek/clients"
+	"github.com/jackofallops/azurerm-dalek/dalek/logging"
+	"github.com/jackofallops/azurerm-dalek/dalek/matcher"
 	"github.com/jackofallops/azurerm-dalek/dalek/options"
+	"github.com/jackofallops/azurerm-dalek/dalek/plan"
+	"github.com/jackofallops/azurerm-dalek/dalek/report"
+	"github.com/jackofallops/azurerm-dalek/dalek/retry"
 )
 
 var _ SubscriptionCleaner = purgeSoftDeletedManagedHSMsInSubscriptionCleaner{}
@@ -20,7 +28,19 @@ func (p purgeSoftDeletedManagedHSMsInSubscriptionCleaner) Name() string {
 	return "Purging Soft Deleted Key Vaults in Subscription"
 }
 
+func (p purgeSoftDeletedManagedHSMsInSubscriptionCleaner) purgesSoftDeletedItems() {}
+
+func (p purgeSoftDeletedManagedHSMsInSubscriptionCleaner) RunsAfter() []SubscriptionCleaner {
+	// deleting the Resource Groups soft-deletes the Managed HSMs within them, which we then want to purge
+	return []SubscriptionCleaner{
+		deleteResourceGroupsInSubscriptionCleaner{},
+	}
+}
+
 func (p purgeSoftDeletedManagedHSMsInSubscriptionCleaner) Cleanup(ctx context.Context, subscriptionId commonids.SubscriptionId, client *clients.AzureClient, opts options.Options) error {
+	logger := logging.FromContext(ctx)
+	rep := report.FromContext(ctx)
+	m := matcher.New(opts)
 	errs := make([]error, 0)
 	softDeletedHSMs, err := client.ResourceManager.ManagedHSMsClient.ListDeletedComplete(ctx, subscriptionId)
 	if err != nil {
@@ -32,20 +52,65 @@ func (p purgeSoftDeletedManagedHSMsInSubscriptionCleaner) Cleanup(ctx context.Co
 			errs = append(errs, fmt.Errorf("parsing Managed HSM ID %q: %+v", *hsm.Id, err))
 			continue
 		}
-		log.Printf("[DEBUG] Purging Soft-Deleted %s..", *hsmId)
+
+		// the prefix is compared with the Resource Group which contained the Managed HSM before it was deleted
+		object := matcher.Object{
+			Deleted: true,
+		}
+		if props := hsm.Properties; props != nil {
+			object.Tags = pointer.From(props.Tags)
+			if originalId, err := managedhsms.ParseManagedHSMIDInsensitively(pointer.From(props.MhsmId)); err == nil {
+				object.Name = originalId.ResourceGroupName
+			}
+		}
+		entry := subscriptionEntry(report.KindDeletedManagedHSM, subscriptionId, hsmId, hsmId.DeletedManagedHSMName)
+		matched, rule := m.Match(object)
+		entry.Rule = rule
+		if !matched {
+			logger.Debug("Skipping soft-deleted Managed HSM", logging.ResourceID(hsmId.ID()), slog.String("rule", rule))
+			entry.Action = report.ActionSkipped
+			rep.Record(entry)
+			continue
+		}
+
+		planItem := subscriptionPlanItem(report.KindDeletedManagedHSM, subscriptionId, hsmId, hsmId.DeletedManagedHSMName, nil)
+		if props := hsm.Properties; props != nil {
+			planItem.Attributes = plan.TagAttributes(props.Tags)
+			planItem.Attributes["deletionDate"] = pointer.From(props.DeletionDate)
+		}
+		if err := plan.FromContext(ctx).Check(planItem); err != nil {
+			logger.Warn("Skipping soft-deleted Managed HSM", logging.ResourceID(hsmId.ID()), logging.Err(err))
+			entry.Action = report.ActionSkipped
+			entry.Rule = err.Error()
+			rep.Record(entry)
+			continue
+		}
 
 		if !opts.ActuallyDelete {
-			log.Printf("[DEBUG] Would have purged soft-deleted Managed HSM %q..", *hsmId)
+			logger.Info("Would have purged soft-deleted Managed HSM", logging.ResourceID(hsmId.ID()))
+			entry.Action = report.ActionWouldPurge
+			rep.Record(entry)
 			continue
 		}
 
-		log.Printf("[DEBUG] Purging Soft-Deleted %s..", *hsmId)
-		if err = client.ResourceManager.ManagedHSMsClient.PurgeDeletedThenPoll(ctx, *hsmId); err != nil {
-			errs = append(errs, fmt.Errorf("purging %s: %+v", *hsmId, err))
+		logger.Info("Purging soft-deleted Managed HSM", logging.ResourceID(hsmId.ID()))
+		startedAt := time.Now()
+		err = retry.Do(ctx, fmt.Sprintf("purging %s", hsmId), func(ctx context.Context) (*http.Response, error) {
+			return nil, client.ResourceManager.ManagedHSMsClient.PurgeDeletedThenPoll(ctx, *hsmId)
+		})
+		entry.DurationSeconds = time.Since(startedAt).Seconds()
+		if err != nil {
+			err = fmt.Errorf("purging %s: %+v", *hsmId, err)
+			errs = append(errs, err)
+			entry.Action = report.ActionFailed
+			entry.Error = err.Error()
+			rep.Record(entry)
 			continue
 		}
 
-		log.Printf("[DEBUG] Purged Soft-Deleted %s.", *hsmId)
+		logger.Info("Purged soft-deleted Managed HSM", logging.ResourceID(hsmId.ID()))
+		entry.Action = report.ActionPurged
+		rep.Record(entry)
 	}
 
 	return errors.Join(errs...)
@@ -2,17 +2,32 @@ package dalek
 
 import (
 	"github.com/jackofallops/azurerm-dalek/clients"
+	"github.com/jackofallops/azurerm-dalek/dalek/matcher"
 	"github.com/jackofallops/azurerm-dalek/dalek/options"
 )
 
 type Dalek struct {
-	client *clients.AzureClient
-	opts   options.Options
+	// client is the AzureClient used for the phase being processed, which is the default unless a credential profile
+	// is assigned to it - clients contains those used for each phase
+	client  *clients.AzureClient
+	clients *clients.AzureClients
+
+	opts    options.Options
+	matcher matcher.Matcher
 }
 
-func NewDalek(client *clients.AzureClient, opts options.Options) Dalek {
+func NewDalek(azureClients *clients.AzureClients, opts options.Options) Dalek {
 	return Dalek{
-		client: client,
-		opts:   opts,
+		client:  azureClients.Default,
+		clients: azureClients,
+		opts:    opts,
+		matcher: matcher.New(opts),
 	}
 }
+
+// withClient returns a copy of the Dalek which uses the specified AzureClient
+func (d *Dalek) withClient(client *clients.AzureClient) *Dalek {
+	output := *d
+	output.client = client
+	return &output
+}
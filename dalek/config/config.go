@@ -0,0 +1,185 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackofallops/azurerm-dalek/dalek/allowlist"
+	"github.com/jackofallops/azurerm-dalek/dalek/filter"
+	"gopkg.in/yaml.v3"
+)
+
+// Credentials configures how the dalek authenticates - each of these is overridden by the matching `ARM_*`
+// environment variable when that's set.
+type Credentials struct {
+	ClientID       *string `yaml:"client-id"`
+	ClientSecret   *string `yaml:"client-secret"`
+	SubscriptionID *string `yaml:"subscription-id"`
+	TenantID       *string `yaml:"tenant-id"`
+	Environment    *string `yaml:"environment"`
+	Endpoint       *string `yaml:"endpoint"`
+
+	// ClientSecretEnv is the name of an environment variable containing the client secret, so that it needn't be
+	// specified within the file
+	ClientSecretEnv *string `yaml:"client-secret-env"`
+
+	ClientCertificatePath *string `yaml:"client-certificate-path"`
+	UseCLI                *bool   `yaml:"use-cli"`
+	UseMSI                *bool   `yaml:"use-msi"`
+	MSIEndpoint           *string `yaml:"msi-endpoint"`
+	UseOIDC               *bool   `yaml:"use-oidc"`
+	OIDCTokenFilePath     *string `yaml:"oidc-token-file-path"`
+}
+
+// ProfileAssignments are the names of the credential profiles to use for specific Subscriptions (keyed by the
+// Subscription ID), Microsoft Graph and the Management Groups
+type ProfileAssignments struct {
+	Subscriptions    map[string]string `yaml:"subscriptions"`
+	MicrosoftGraph   *string           `yaml:"microsoft-graph"`
+	ManagementGroups *string           `yaml:"management-groups"`
+}
+
+// Config is the contents of a configuration file. Each key matches the name of the command line flag which
+// overrides it, and any key which isn't specified falls back to the default for that flag.
+type Config struct {
+	Credentials Credentials `yaml:"credentials"`
+
+	// CredentialProfiles are named credentials which are used in place of `credentials` for whatever is assigned to
+	// them within `credential-profile-assignments`. The `ARM_*` environment variables don't apply to these, since
+	// each profile is a different identity.
+	CredentialProfiles map[string]Credentials `yaml:"credential-profiles"`
+	ProfileAssignments ProfileAssignments     `yaml:"credential-profile-assignments"`
+
+	// ActuallyDelete is overridden by the `YES_I_REALLY_WANT_TO_DELETE_THINGS` environment variable when that's set
+	ActuallyDelete *bool `yaml:"actually-delete"`
+
+	Prefix            *string        `yaml:"prefix"`
+	Subscriptions     []string       `yaml:"subscriptions"`
+	AllSubscriptions  *bool          `yaml:"all-subscriptions"`
+	ManagementGroup   *string        `yaml:"management-group"`
+	MaxResourceGroups *int64         `yaml:"max-resource-groups"`
+	Cleaners          []string       `yaml:"cleaners"`
+	SkipCleaners      []string       `yaml:"skip-cleaners"`
+	MinAge            *time.Duration `yaml:"min-age"`
+	ExpiresOnTag      *string        `yaml:"expires-on-tag"`
+	TTLTag            *string        `yaml:"ttl-tag"`
+
+	ProtectedResources *string `yaml:"protected-resources"`
+
+	// ResourceGroupFilter has no equivalent command line flag, since it can't reasonably be expressed as one
+	ResourceGroupFilter *filter.Filter `yaml:"resource-group-filter"`
+
+	// Allowlist intentionally has no equivalent command line flag or environment variable, so that it can only come
+	// from a (reviewed) configuration file
+	Allowlist allowlist.Allowlist `yaml:"allowlist"`
+
+	// the safety limits can be specified here, but overriding these can't - that has to be an explicit choice for each run
+	SafetyMaxResourceGroups          *int64   `yaml:"safety-max-resource-groups"`
+	SafetyMaxResourceGroupsPercent   *float64 `yaml:"safety-max-resource-groups-percent"`
+	SafetyMaxGraphObjects            *int64   `yaml:"safety-max-graph-objects"`
+	SafetyMaxManagementGroups        *int64   `yaml:"safety-max-management-groups"`
+	SafetyMaxManagementGroupsPercent *float64 `yaml:"safety-max-management-groups-percent"`
+
+	Parallelism            *int           `yaml:"parallelism"`
+	Timeout                *time.Duration `yaml:"timeout"`
+	WaitForDeletion        *bool          `yaml:"wait-for-deletion"`
+	WaitForDeletionTimeout *time.Duration `yaml:"wait-for-deletion-timeout"`
+
+	ReportFile            *string `yaml:"report-file"`
+	MetricsFile           *string `yaml:"metrics-file"`
+	MetricsPushgatewayURL *string `yaml:"metrics-pushgateway-url"`
+	StateFile             *string `yaml:"state-file"`
+	LogLevel              *string `yaml:"log-level"`
+	LogFormat             *string `yaml:"log-format"`
+}
+
+// Load reads the configuration file at the specified path, which can be either YAML or JSON (since JSON is also
+// valid YAML). Unknown keys are an error, so that a typo doesn't silently fall back to a default.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading the configuration file %q: %+v", path, err)
+	}
+
+	var config Config
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parsing the configuration file %q: %+v", path, err)
+	}
+
+	return &config, nil
+}
+
+// FlagValues returns the values specified within the configuration file keyed by the name of the command line flag
+// which they configure, so that these can be used for any flags which weren't specified explicitly.
+func (c Config) FlagValues() map[string]string {
+	output := make(map[string]string)
+	setString := func(name string, v *string) {
+		if v != nil {
+			output[name] = *v
+		}
+	}
+	setList := func(name string, v []string) {
+		if v != nil {
+			output[name] = strings.Join(v, ",")
+		}
+	}
+	setBool := func(name string, v *bool) {
+		if v != nil {
+			output[name] = strconv.FormatBool(*v)
+		}
+	}
+	setDuration := func(name string, v *time.Duration) {
+		if v != nil {
+			output[name] = v.String()
+		}
+	}
+	setInt64 := func(name string, v *int64) {
+		if v != nil {
+			output[name] = strconv.FormatInt(*v, 10)
+		}
+	}
+	setFloat := func(name string, v *float64) {
+		if v != nil {
+			output[name] = strconv.FormatFloat(*v, 'f', -1, 64)
+		}
+	}
+
+	setString("prefix", c.Prefix)
+	setList("subscriptions", c.Subscriptions)
+	setBool("all-subscriptions", c.AllSubscriptions)
+	setString("management-group", c.ManagementGroup)
+	setInt64("max-resource-groups", c.MaxResourceGroups)
+	setList("cleaners", c.Cleaners)
+	setList("skip-cleaners", c.SkipCleaners)
+	setDuration("min-age", c.MinAge)
+	setString("expires-on-tag", c.ExpiresOnTag)
+	setString("ttl-tag", c.TTLTag)
+	setString("protected-resources", c.ProtectedResources)
+	setInt64("safety-max-resource-groups", c.SafetyMaxResourceGroups)
+	setFloat("safety-max-resource-groups-percent", c.SafetyMaxResourceGroupsPercent)
+	setInt64("safety-max-graph-objects", c.SafetyMaxGraphObjects)
+	setInt64("safety-max-management-groups", c.SafetyMaxManagementGroups)
+	setFloat("safety-max-management-groups-percent", c.SafetyMaxManagementGroupsPercent)
+	if c.Parallelism != nil {
+		output["parallelism"] = strconv.Itoa(*c.Parallelism)
+	}
+	setDuration("timeout", c.Timeout)
+	setBool("wait-for-deletion", c.WaitForDeletion)
+	setDuration("wait-for-deletion-timeout", c.WaitForDeletionTimeout)
+	setString("report-file", c.ReportFile)
+	setString("metrics-file", c.MetricsFile)
+	setString("metrics-pushgateway-url", c.MetricsPushgatewayURL)
+	setString("state-file", c.StateFile)
+	setString("log-level", c.LogLevel)
+	setString("log-format", c.LogFormat)
+
+	return output
+}
@@ -0,0 +1,238 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Subject is the object being filtered, currently a Resource Group
+type Subject struct {
+	Name              string
+	Location          string
+	ProvisioningState string
+	Tags              map[string]string
+
+	// CreatedTime is when the object was created, or nil when that's unknown
+	CreatedTime *time.Time
+}
+
+// Tag matches objects which have the tag Key (compared case-insensitively) and, when Value is specified, where that
+// tag has that value
+type Tag struct {
+	Key   string  `json:"key" yaml:"key"`
+	Value *string `json:"value,omitempty" yaml:"value"`
+}
+
+// Filter is an expression which determines whether an object is processed. Each Filter is either a combination of
+// other Filters (`all`, `any` or `not`) or exactly one predicate - for example "acctest* or tf-test* in westeurope,
+// not tagged team=platform" is:
+//
+//	all:
+//	  - any:
+//	      - name: ^acctest
+//	      - name: ^tf-test
+//	  - locations: [westeurope]
+//	  - not:
+//	      tag: {key: team, value: platform}
+type Filter struct {
+	All []Filter `json:"all,omitempty" yaml:"all"`
+	Any []Filter `json:"any,omitempty" yaml:"any"`
+	Not *Filter  `json:"not,omitempty" yaml:"not"`
+
+	// Name is a regular expression which the name must match, case-insensitively
+	Name string `json:"name,omitempty" yaml:"name"`
+
+	Tag *Tag `json:"tag,omitempty" yaml:"tag"`
+
+	// Locations are the locations which the object must be within, compared case-insensitively and ignoring spaces
+	// so that both `westeurope` and `West Europe` can be used
+	Locations []string `json:"locations,omitempty" yaml:"locations"`
+
+	// ProvisioningStates are the provisioning states which the object must be in, compared case-insensitively
+	ProvisioningStates []string `json:"provisioningStates,omitempty" yaml:"provisioning-states"`
+
+	// OlderThan is the minimum age of the object - objects whose age can't be determined never match
+	OlderThan *time.Duration `json:"olderThan,omitempty" yaml:"older-than"`
+}
+
+// Validate returns an error when the Filter (or any Filter within it) doesn't contain exactly one combination or
+// predicate, or contains an invalid regular expression
+func (f Filter) Validate() error {
+	return f.validate("resource-group-filter")
+}
+
+func (f Filter) validate(path string) error {
+	specified := make([]string, 0)
+	if f.All != nil {
+		specified = append(specified, "all")
+	}
+	if f.Any != nil {
+		specified = append(specified, "any")
+	}
+	if f.Not != nil {
+		specified = append(specified, "not")
+	}
+	if f.Name != "" {
+		specified = append(specified, "name")
+	}
+	if f.Tag != nil {
+		specified = append(specified, "tag")
+	}
+	if f.Locations != nil {
+		specified = append(specified, "locations")
+	}
+	if f.ProvisioningStates != nil {
+		specified = append(specified, "provisioning-states")
+	}
+	if f.OlderThan != nil {
+		specified = append(specified, "older-than")
+	}
+	if len(specified) != 1 {
+		return fmt.Errorf("%s: exactly one of `all`, `any`, `not`, `name`, `tag`, `locations`, `provisioning-states` or `older-than` must be specified but got %q", path, specified)
+	}
+
+	switch {
+	case f.All != nil || f.Any != nil:
+		key, filters := "all", f.All
+		if f.Any != nil {
+			key, filters = "any", f.Any
+		}
+		if len(filters) == 0 {
+			return fmt.Errorf("%s.%s: at least one filter must be specified", path, key)
+		}
+		for i, v := range filters {
+			if err := v.validate(fmt.Sprintf("%s.%s[%d]", path, key, i)); err != nil {
+				return err
+			}
+		}
+
+	case f.Not != nil:
+		return f.Not.validate(path + ".not")
+
+	case f.Name != "":
+		if _, err := regexp.Compile(f.Name); err != nil {
+			return fmt.Errorf("%s.name: parsing the regular expression %q: %+v", path, f.Name, err)
+		}
+
+	case f.Tag != nil:
+		if f.Tag.Key == "" {
+			return fmt.Errorf("%s.tag.key: must be specified", path)
+		}
+
+	case f.Locations != nil && len(f.Locations) == 0:
+		return fmt.Errorf("%s.locations: at least one location must be specified", path)
+
+	case f.ProvisioningStates != nil && len(f.ProvisioningStates) == 0:
+		return fmt.Errorf("%s.provisioning-states: at least one provisioning state must be specified", path)
+
+	case f.OlderThan != nil && *f.OlderThan < 0:
+		return fmt.Errorf("%s.older-than: must not be negative", path)
+	}
+
+	return nil
+}
+
+// Matches returns whether the Subject matches the Filter, where the age of the Subject is relative to now. The
+// Filter must have been validated.
+func (f Filter) Matches(subject Subject, now time.Time) bool {
+	switch {
+	case f.All != nil:
+		for _, v := range f.All {
+			if !v.Matches(subject, now) {
+				return false
+			}
+		}
+		return true
+
+	case f.Any != nil:
+		for _, v := range f.Any {
+			if v.Matches(subject, now) {
+				return true
+			}
+		}
+		return false
+
+	case f.Not != nil:
+		return !f.Not.Matches(subject, now)
+
+	case f.Name != "":
+		matched, _ := regexp.MatchString("(?i)"+f.Name, subject.Name)
+		return matched
+
+	case f.Tag != nil:
+		for k, v := range subject.Tags {
+			if strings.EqualFold(k, f.Tag.Key) {
+				return f.Tag.Value == nil || v == *f.Tag.Value
+			}
+		}
+		return false
+
+	case f.Locations != nil:
+		return slices.ContainsFunc(f.Locations, func(v string) bool {
+			return normalizeLocation(v) == normalizeLocation(subject.Location)
+		})
+
+	case f.ProvisioningStates != nil:
+		return slices.ContainsFunc(f.ProvisioningStates, func(v string) bool {
+			return strings.EqualFold(v, subject.ProvisioningState)
+		})
+
+	case f.OlderThan != nil:
+		return subject.CreatedTime != nil && now.Sub(*subject.CreatedTime) >= *f.OlderThan
+	}
+
+	return false
+}
+
+// UsesAge returns whether the Filter (or any Filter within it) depends on when the Subject was created, so that this
+// is only looked up when it's needed
+func (f Filter) UsesAge() bool {
+	if f.OlderThan != nil {
+		return true
+	}
+	if f.Not != nil && f.Not.UsesAge() {
+		return true
+	}
+	return slices.ContainsFunc(append(slices.Clone(f.All), f.Any...), Filter.UsesAge)
+}
+
+// String returns a description of the Filter, for use in the rule recorded for each object
+func (f Filter) String() string {
+	join := func(filters []Filter) string {
+		output := make([]string, 0, len(filters))
+		for _, v := range filters {
+			output = append(output, v.String())
+		}
+		return strings.Join(output, ", ")
+	}
+
+	switch {
+	case f.All != nil:
+		return fmt.Sprintf("all(%s)", join(f.All))
+	case f.Any != nil:
+		return fmt.Sprintf("any(%s)", join(f.Any))
+	case f.Not != nil:
+		return fmt.Sprintf("not(%s)", f.Not.String())
+	case f.Name != "":
+		return fmt.Sprintf("name =~ %q", f.Name)
+	case f.Tag != nil && f.Tag.Value != nil:
+		return fmt.Sprintf("tag %q = %q", f.Tag.Key, *f.Tag.Value)
+	case f.Tag != nil:
+		return fmt.Sprintf("tag %q", f.Tag.Key)
+	case f.Locations != nil:
+		return fmt.Sprintf("location in %q", f.Locations)
+	case f.ProvisioningStates != nil:
+		return fmt.Sprintf("provisioning state in %q", f.ProvisioningStates)
+	case f.OlderThan != nil:
+		return fmt.Sprintf("older than %s", *f.OlderThan)
+	}
+
+	return "<empty>"
+}
+
+func normalizeLocation(input string) string {
+	return strings.ToLower(strings.ReplaceAll(input, " ", ""))
+}
@@ -0,0 +1,307 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func parse(t *testing.T, input string) Filter {
+	t.Helper()
+
+	var f Filter
+	if err := yaml.Unmarshal([]byte(input), &f); err != nil {
+		t.Fatalf("unmarshalling %q: %+v", input, err)
+	}
+	return f
+}
+
+func TestValidate(t *testing.T) {
+	testData := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:  "name",
+			input: `name: ^acctest`,
+		},
+		{
+			name:  "tag without a value",
+			input: `tag: {key: team}`,
+		},
+		{
+			name: "nested",
+			input: `
+all:
+  - any:
+      - name: ^acctest
+      - name: ^tf-test
+  - locations: [westeurope]
+  - not:
+      tag: {key: team, value: platform}
+`,
+		},
+		{
+			name:     "empty",
+			input:    `{}`,
+			expected: "resource-group-filter: exactly one of",
+		},
+		{
+			name: "two predicates",
+			input: `
+name: ^acctest
+locations: [westeurope]
+`,
+			expected: `but got ["name" "locations"]`,
+		},
+		{
+			name:     "empty all",
+			input:    `all: []`,
+			expected: "resource-group-filter.all: at least one filter must be specified",
+		},
+		{
+			name: "invalid nested regular expression",
+			input: `
+any:
+  - name: ^acctest
+  - not:
+      name: "("
+`,
+			expected: "resource-group-filter.any[1].not.name: parsing the regular expression",
+		},
+		{
+			name:     "tag without a key",
+			input:    `tag: {value: platform}`,
+			expected: "resource-group-filter.tag.key: must be specified",
+		},
+		{
+			name:     "empty locations",
+			input:    `locations: []`,
+			expected: "resource-group-filter.locations: at least one location must be specified",
+		},
+		{
+			name:     "empty provisioning states",
+			input:    `provisioning-states: []`,
+			expected: "resource-group-filter.provisioning-states: at least one provisioning state must be specified",
+		},
+		{
+			name:     "negative age",
+			input:    `older-than: -1h`,
+			expected: "resource-group-filter.older-than: must not be negative",
+		},
+	}
+
+	for _, v := range testData {
+		t.Run(v.name, func(t *testing.T) {
+			err := parse(t, v.input).Validate()
+			if v.expected == "" {
+				if err != nil {
+					t.Fatalf("expected no error but got: %+v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected an error containing %q but got none", v.expected)
+			}
+			if !strings.Contains(err.Error(), v.expected) {
+				t.Fatalf("expected an error containing %q but got: %+v", v.expected, err)
+			}
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	now := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+	dayOld := now.Add(-24 * time.Hour)
+
+	nested := `
+all:
+  - any:
+      - name: ^acctest
+      - name: ^tf-test
+  - locations: [westeurope]
+  - not:
+      tag: {key: team, value: platform}
+`
+
+	testData := []struct {
+		name     string
+		filter   string
+		subject  Subject
+		expected bool
+	}{
+		{
+			name:     "name is case-insensitive",
+			filter:   `name: ^acctest`,
+			subject:  Subject{Name: "ACCTEST-rg-1"},
+			expected: true,
+		},
+		{
+			name:     "name is a regular expression",
+			filter:   `name: ^acctest`,
+			subject:  Subject{Name: "rg-acctest"},
+			expected: false,
+		},
+		{
+			name:     "tag key is case-insensitive",
+			filter:   `tag: {key: Team}`,
+			subject:  Subject{Tags: map[string]string{"team": "anything"}},
+			expected: true,
+		},
+		{
+			name:     "tag value is case-sensitive",
+			filter:   `tag: {key: team, value: platform}`,
+			subject:  Subject{Tags: map[string]string{"team": "Platform"}},
+			expected: false,
+		},
+		{
+			name:     "missing tag",
+			filter:   `tag: {key: team}`,
+			subject:  Subject{},
+			expected: false,
+		},
+		{
+			name:     "location ignores case and spaces",
+			filter:   `locations: [West Europe]`,
+			subject:  Subject{Location: "westeurope"},
+			expected: true,
+		},
+		{
+			name:     "provisioning state is case-insensitive",
+			filter:   `provisioning-states: [Failed]`,
+			subject:  Subject{ProvisioningState: "failed"},
+			expected: true,
+		},
+		{
+			name:     "older than",
+			filter:   `older-than: 12h`,
+			subject:  Subject{CreatedTime: &dayOld},
+			expected: true,
+		},
+		{
+			name:     "not old enough",
+			filter:   `older-than: 48h`,
+			subject:  Subject{CreatedTime: &dayOld},
+			expected: false,
+		},
+		{
+			name:     "unknown age never matches",
+			filter:   `older-than: 0s`,
+			subject:  Subject{},
+			expected: false,
+		},
+		{
+			name:     "not unknown age matches",
+			filter:   `not: {older-than: 1h}`,
+			subject:  Subject{},
+			expected: true,
+		},
+		{
+			name:     "nested matches",
+			filter:   nested,
+			subject:  Subject{Name: "tf-test-1", Location: "westeurope", Tags: map[string]string{"team": "dev"}},
+			expected: true,
+		},
+		{
+			name:     "nested excluded by the negated tag",
+			filter:   nested,
+			subject:  Subject{Name: "tf-test-1", Location: "westeurope", Tags: map[string]string{"team": "platform"}},
+			expected: false,
+		},
+		{
+			name:     "nested requires every filter within all",
+			filter:   nested,
+			subject:  Subject{Name: "acctest-1", Location: "eastus"},
+			expected: false,
+		},
+		{
+			name:     "nested requires one filter within any",
+			filter:   nested,
+			subject:  Subject{Name: "other", Location: "westeurope"},
+			expected: false,
+		},
+	}
+
+	for _, v := range testData {
+		t.Run(v.name, func(t *testing.T) {
+			f := parse(t, v.filter)
+			if err := f.Validate(); err != nil {
+				t.Fatalf("validating: %+v", err)
+			}
+			if actual := f.Matches(v.subject, now); actual != v.expected {
+				t.Fatalf("expected %t but got %t", v.expected, actual)
+			}
+		})
+	}
+}
+
+func TestUsesAge(t *testing.T) {
+	testData := []struct {
+		filter   string
+		expected bool
+	}{
+		{
+			filter:   `name: ^acctest`,
+			expected: false,
+		},
+		{
+			filter:   `older-than: 1h`,
+			expected: true,
+		},
+		{
+			filter:   `not: {older-than: 1h}`,
+			expected: true,
+		},
+		{
+			filter:   `any: [{name: ^acctest}, {all: [{locations: [westeurope]}, {older-than: 1h}]}]`,
+			expected: true,
+		},
+		{
+			filter:   `all: [{name: ^acctest}, {not: {tag: {key: team}}}]`,
+			expected: false,
+		},
+	}
+
+	for _, v := range testData {
+		t.Run(v.filter, func(t *testing.T) {
+			if actual := parse(t, v.filter).UsesAge(); actual != v.expected {
+				t.Fatalf("expected %t but got %t", v.expected, actual)
+			}
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	testData := []struct {
+		filter   string
+		expected string
+	}{
+		{
+			filter:   `name: ^acctest`,
+			expected: `name =~ "^acctest"`,
+		},
+		{
+			filter:   `tag: {key: team}`,
+			expected: `tag "team"`,
+		},
+		{
+			filter:   `all: [{any: [{name: ^a}, {name: ^b}]}, {not: {tag: {key: team, value: platform}}}, {older-than: 2h}]`,
+			expected: `all(any(name =~ "^a", name =~ "^b"), not(tag "team" = "platform"), older than 2h0m0s)`,
+		},
+		{
+			filter:   `{}`,
+			expected: "<empty>",
+		},
+	}
+
+	for _, v := range testData {
+		t.Run(v.filter, func(t *testing.T) {
+			if actual := parse(t, v.filter).String(); actual != v.expected {
+				t.Fatalf("expected %q but got %q", v.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// the keys used for the attributes common to many log lines, so that these are consistent when filtering
+const (
+	KeyCleaner        = "cleaner"
+	KeyError          = "error"
+	KeyResourceGroup  = "resource_group"
+	KeyResourceID     = "resource_id"
+	KeySubscriptionID = "subscription_id"
+)
+
+// Configure sets the default Logger to write to w, using the specified level (`debug`, `info`, `warn` or `error`)
+// and format (`text` or `json`)
+func Configure(w io.Writer, level string, format string) error {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("parsing the log level %q: %+v", level, err)
+	}
+
+	opts := &slog.HandlerOptions{
+		Level: lvl,
+	}
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "json":
+		handler = slog.NewJSONHandler(w, opts)
+	case "text":
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return fmt.Errorf("unsupported log format %q - expected `text` or `json`", format)
+	}
+
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+type loggerContextKey struct{}
+
+// WithLogger returns a copy of ctx which carries the specified Logger
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, logger)
+}
+
+// WithAttrs returns a copy of ctx carrying a Logger which includes the specified attributes on every line
+func WithAttrs(ctx context.Context, attrs ...any) context.Context {
+	return WithLogger(ctx, FromContext(ctx).With(attrs...))
+}
+
+// FromContext returns the Logger carried by ctx, falling back to the default Logger when ctx doesn't carry one
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerContextKey{}).(*slog.Logger); ok && logger != nil {
+		return logger
+	}
+	return slog.Default()
+}
+
+// Buffered returns a Logger which holds the lines logged to it until flush is called, at which point these are
+// written (in order) using the specified Logger. This allows the lines for a unit of work performed concurrently
+// with others to be output together, rather than interleaved.
+func Buffered(logger *slog.Logger) (buffered *slog.Logger, flush func(ctx context.Context)) {
+	buffer := &recordBuffer{}
+	buffered = slog.New(&bufferedHandler{
+		next:   logger.Handler(),
+		buffer: buffer,
+	})
+	flush = func(ctx context.Context) {
+		buffer.mu.Lock()
+		defer buffer.mu.Unlock()
+		for _, v := range buffer.records {
+			_ = v.handler.Handle(ctx, v.record)
+		}
+		buffer.records = nil
+	}
+	return buffered, flush
+}
+
+type bufferedRecord struct {
+	handler slog.Handler
+	record  slog.Record
+}
+
+type recordBuffer struct {
+	records []bufferedRecord
+	mu      sync.Mutex
+}
+
+type bufferedHandler struct {
+	next   slog.Handler
+	buffer *recordBuffer
+}
+
+func (h *bufferedHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *bufferedHandler) Handle(_ context.Context, record slog.Record) error {
+	h.buffer.mu.Lock()
+	defer h.buffer.mu.Unlock()
+	h.buffer.records = append(h.buffer.records, bufferedRecord{
+		handler: h.next,
+		record:  record.Clone(),
+	})
+	return nil
+}
+
+func (h *bufferedHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &bufferedHandler{
+		next:   h.next.WithAttrs(attrs),
+		buffer: h.buffer,
+	}
+}
+
+func (h *bufferedHandler) WithGroup(name string) slog.Handler {
+	return &bufferedHandler{
+		next:   h.next.WithGroup(name),
+		buffer: h.buffer,
+	}
+}
+
+func Cleaner(name string) slog.Attr {
+	return slog.String(KeyCleaner, name)
+}
+
+func Err(err error) slog.Attr {
+	return slog.Any(KeyError, err)
+}
+
+func ResourceGroup(name string) slog.Attr {
+	return slog.String(KeyResourceGroup, name)
+}
+
+func ResourceID(id string) slog.Attr {
+	return slog.String(KeyResourceID, id)
+}
+
+func SubscriptionID(id string) slog.Attr {
+	return slog.String(KeySubscriptionID, id)
+}
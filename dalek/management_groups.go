@@ -2,64 +2,181 @@ package dalek
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
-	"strings"
+	"net/http"
+	"time"
 
+	"github.com/hashicorp/go-azure-helpers/lang/pointer"
 	"github.com/hashicorp/go-azure-helpers/resourcemanager/commonids"
 	"github.com/hashicorp/go-azure-sdk/resource-manager/management/2023-04-01/managementgroups"
 	"github.com/hashicorp/go-azure-sdk/resource-manager/management/2023-04-01/managements"
 	"github.com/hashicorp/go-uuid"
+	"github.com/jackofallops/azurerm-dalek/dalek/checkpoint"
+	"github.com/jackofallops/azurerm-dalek/dalek/logging"
+	"github.com/jackofallops/azurerm-dalek/dalek/plan"
+	"github.com/jackofallops/azurerm-dalek/dalek/report"
+	"github.com/jackofallops/azurerm-dalek/dalek/retry"
+	"github.com/jackofallops/azurerm-dalek/dalek/safety"
 )
 
 func (d *Dalek) ManagementGroups(ctx context.Context) error {
+	// Management Groups aren't soft-deleted, so there's nothing to purge
+	if d.opts.PurgeOnly {
+		return nil
+	}
+	d = d.withClient(d.clients.ManagementGroups())
+
+	progress := checkpoint.FromContext(ctx)
+	if progress.IsComplete(d.client.TenantID, "Management Groups") {
+		logging.FromContext(ctx).Debug("Skipping the Management Groups since these were completed by a previous run")
+		return nil
+	}
+
 	if err := d.deleteManagementGroups(ctx); err != nil {
 		return fmt.Errorf("processing Management Groups: %+v", err)
 	}
+
+	if err := progress.Complete(d.client.TenantID, "Management Groups"); err != nil {
+		return fmt.Errorf("recording progress: %+v", err)
+	}
 	return nil
 }
 
 func (d *Dalek) deleteManagementGroups(ctx context.Context) error {
+	logger := logging.FromContext(ctx)
 	client := d.client.ResourceManager.ManagementClient
 	listClient := d.client.ResourceManager.ManagementGroupsListClient
 
 	groups, err := listClient.ManagementGroupsList(ctx, managements.DefaultManagementGroupsListOperationOptions())
 	if err != nil {
-		return fmt.Errorf("[ERROR] Error obtaining Management Groups List: %+v", err)
+		return fmt.Errorf("listing Management Groups: %+v", err)
 	}
 
 	if groups.Model == nil {
-		log.Printf("[DEBUG]   No Management Groups found")
+		logger.Debug("No Management Groups found")
 		return nil
 	}
+
+	// each Management Group is matched once, so that the safety limits are checked against exactly those which would
+	// be deleted - or when applying a plan, those which are planned
+	rep := report.FromContext(ctx)
+	candidates := make([]managementGroupCandidate, 0)
+	listed := make([]string, 0, len(*groups.Model))
 	for _, group := range *groups.Model {
 		if group.Name == nil || group.Id == nil {
 			continue
 		}
-		if props := group.Properties; props != nil && props.DisplayName != nil && d.opts.Prefix != "" {
-			if !strings.HasPrefix(strings.ToLower(*props.DisplayName), strings.ToLower(d.opts.Prefix)) {
-				continue
-			}
-		}
 
 		groupName := *group.Name
-		id := commonids.NewManagementGroupID(*group.Id)
+		id := commonids.NewManagementGroupID(groupName)
+		listed = append(listed, id.ID())
+		entry := report.Entry{
+			ID:    id.ID(),
+			Name:  groupName,
+			Kind:  report.KindManagementGroup,
+			Scope: d.client.TenantID,
+		}
+
+		// the prefix is compared with the display name, since the name of a Management Group created by the tests is a UUID
+		var displayName string
+		if props := group.Properties; props != nil {
+			displayName = pointer.From(props.DisplayName)
+		}
+		if matched, _ := d.matcher.MatchName(displayName); !matched {
+			entry.Action = report.ActionSkipped
+			entry.Rule = fmt.Sprintf("the display name doesn't start with the prefix %q", d.opts.Prefix)
+			rep.Record(entry)
+			continue
+		}
 
 		if _, err := uuid.ParseUUID(groupName); err != nil {
-			log.Printf("[DEBUG]   Skipping Management Group %q", groupName)
+			logger.Debug("Skipping Management Group since its name isn't a UUID", logging.ResourceID(id.ID()))
+			entry.Action = report.ActionSkipped
+			entry.Rule = "the name isn't a UUID"
+			rep.Record(entry)
 			continue
 		}
-		if !d.opts.ActuallyDelete {
-			log.Printf("[DEBUG] Would have deleted Management Group %q", id)
+		entry.Rule = "the name is a UUID"
+		if d.opts.Prefix != "" {
+			entry.Rule = fmt.Sprintf("the name is a UUID and the display name starts with the prefix %q", d.opts.Prefix)
+		}
+
+		planItem := plan.Item{
+			Kind:  entry.Kind,
+			ID:    entry.ID,
+			Name:  entry.Name,
+			Scope: entry.Scope,
+		}
+		if props := group.Properties; props != nil && props.DisplayName != nil {
+			planItem.Attributes = map[string]string{
+				"displayName": *props.DisplayName,
+			}
+		}
+		candidates = append(candidates, managementGroupCandidate{
+			id:       id,
+			entry:    entry,
+			planItem: planItem,
+		})
+	}
+
+	guard := safety.FromContext(ctx)
+	count := plan.FromContext(ctx).Count(report.KindManagementGroup, listed, len(candidates))
+	if err := guard.CheckManagementGroups(ctx, count, len(*groups.Model)); err != nil {
+		for _, candidate := range candidates {
+			candidate.entry.Action = report.ActionSkipped
+			candidate.entry.Rule = err.Error()
+			rep.Record(candidate.entry)
+		}
+		return err
+	}
+	if guard.CountOnly() {
+		return nil
+	}
+
+	errs := make([]error, 0)
+	for _, candidate := range candidates {
+		id, entry, planItem := candidate.id, candidate.entry, candidate.planItem
+		if err := plan.FromContext(ctx).Check(planItem); err != nil {
+			logger.Warn("Skipping Management Group", logging.ResourceID(id.ID()), logging.Err(err))
+			entry.Action = report.ActionSkipped
+			entry.Rule = err.Error()
+			rep.Record(entry)
+			continue
 		}
 
-		log.Printf("[DEBUG]   Deleting %s", id)
+		if !d.opts.ActuallyDelete {
+			logger.Info("Would have deleted Management Group", logging.ResourceID(id.ID()))
+			entry.Action = report.ActionWouldDelete
+			rep.Record(entry)
+			continue
+		}
 
-		if _, err := client.Delete(ctx, id, managementgroups.DefaultDeleteOperationOptions()); err != nil {
-			log.Printf("[DEBUG]   Error during deletion of %s: %s", id, err)
+		logger.Info("Deleting Management Group", logging.ResourceID(id.ID()))
+		startedAt := time.Now()
+		err := retry.Do(ctx, fmt.Sprintf("deleting %s", id), func(ctx context.Context) (*http.Response, error) {
+			resp, err := client.Delete(ctx, id, managementgroups.DefaultDeleteOperationOptions())
+			return resp.HttpResponse, err
+		})
+		entry.DurationSeconds = time.Since(startedAt).Seconds()
+		if err != nil {
+			logger.Error("Deleting Management Group", logging.ResourceID(id.ID()), logging.Err(err))
+			errs = append(errs, fmt.Errorf("deleting %s: %+v", id, err))
+			entry.Action = report.ActionFailed
+			entry.Error = err.Error()
+			rep.Record(entry)
 			continue
 		}
-		log.Printf("[DEBUG]   Deleted %s", id)
+		logger.Info("Deleted Management Group", logging.ResourceID(id.ID()))
+		entry.Action = report.ActionDeleted
+		rep.Record(entry)
 	}
-	return nil
+	return errors.Join(errs...)
+}
+
+// managementGroupCandidate is a Management Group which matched, and so would be deleted
+type managementGroupCandidate struct {
+	id       commonids.ManagementGroupId
+	entry    report.Entry
+	planItem plan.Item
 }
@@ -0,0 +1,226 @@
+package matcher
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackofallops/azurerm-dalek/dalek/filter"
+	"github.com/jackofallops/azurerm-dalek/dalek/options"
+)
+
+// ProtectionTag is the tag which prevents an object from ever being deleted
+const ProtectionTag = "DoNotDelete"
+
+// Object is an object within Resource Manager being considered for deletion
+type Object struct {
+	// Name is compared with the prefix - for the objects deleted by the Subscription Cleaners this is the name of
+	// the Resource Group containing them
+	Name string
+
+	Tags map[string]string
+
+	// CreatedTime is when the object was created, or nil when that's unknown or wasn't looked up
+	CreatedTime *time.Time
+
+	// Location and ProvisioningState are only used by the Resource Group filter
+	Location          string
+	ProvisioningState string
+
+	// Deleted is whether the object has already been soft-deleted and so is only being purged, in which case neither
+	// its expiry nor the minimum age apply since it can no longer be in use
+	Deleted bool
+}
+
+// Matcher decides which objects are deleted, so that the same rules are applied by every Cleaner, the Microsoft Graph
+// sweeps and when deleting Management Groups
+type Matcher struct {
+	prefix              string
+	minimumAge          time.Duration
+	expiresOnTag        string
+	ttlTag              string
+	resourceGroupFilter *filter.Filter
+
+	now func() time.Time
+}
+
+func New(opts options.Options) Matcher {
+	return Matcher{
+		prefix:              opts.Prefix,
+		minimumAge:          opts.MinimumAge,
+		expiresOnTag:        opts.ExpiresOnTag,
+		ttlTag:              opts.TTLTag,
+		resourceGroupFilter: opts.ResourceGroupFilter,
+		now:                 time.Now,
+	}
+}
+
+// Match returns whether the object should be deleted, along with a description of the rule which matched or excluded
+// it. The rules are applied in order:
+//
+//  1. an object with the `DoNotDelete` tag is never deleted
+//  2. an object with an expiry tag is kept until it's expired
+//  3. the name must start with the prefix (compared case-insensitively), when one is specified
+//  4. the object must be older than the minimum age, when one is specified - unless it's expired
+//
+// Only the first and third rules apply to objects which have already been soft-deleted.
+func (m Matcher) Match(input Object) (bool, string) {
+	matched, rule, _ := m.match(input, m.now(), false)
+	return matched, rule
+}
+
+// MatchResourceGroup returns whether the Resource Group should be deleted, which is when it matches both the rules
+// used by Match and the Resource Group filter (if any). Unlike other objects, a Resource Group which has expired is
+// deleted regardless of the prefix and the filter, since Resource Groups can declare their own lifetime.
+func (m Matcher) MatchResourceGroup(input Object) (bool, string) {
+	now := m.now()
+	matched, rule, expired := m.match(input, now, true)
+	if !matched || expired || m.resourceGroupFilter == nil {
+		return matched, rule
+	}
+
+	subject := filter.Subject{
+		Name:              input.Name,
+		Location:          input.Location,
+		ProvisioningState: input.ProvisioningState,
+		Tags:              input.Tags,
+		CreatedTime:       input.CreatedTime,
+	}
+	if !m.resourceGroupFilter.Matches(subject, now) {
+		return false, fmt.Sprintf("doesn't match the filter %s", m.resourceGroupFilter)
+	}
+	return true, fmt.Sprintf("%s and it matches the filter %s", rule, m.resourceGroupFilter)
+}
+
+// MatchName returns whether the name starts with the prefix (compared case-insensitively), along with a description
+// of the rule - this is used directly for objects without tags, such as those within Microsoft Graph
+func (m Matcher) MatchName(name string) (bool, string) {
+	if m.prefix == "" {
+		return true, "no prefix was specified"
+	}
+	if !strings.HasPrefix(strings.ToLower(name), strings.ToLower(m.prefix)) {
+		return false, fmt.Sprintf("the name doesn't start with the prefix %q", m.prefix)
+	}
+	return true, fmt.Sprintf("the name starts with the prefix %q", m.prefix)
+}
+
+// ProtectedBy returns the name of the tag which prevents the object from being deleted, if any - the name of the tag
+// is compared case-insensitively
+func ProtectedBy(tags map[string]string) (string, bool) {
+	for k := range tags {
+		if strings.EqualFold(k, ProtectionTag) {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// NeedsCreatedTime returns whether the time the object was created is required to match it, so that this is only
+// looked up when it's needed
+func (m Matcher) NeedsCreatedTime(input Object) bool {
+	if input.Deleted {
+		return false
+	}
+	if m.minimumAge > 0 {
+		return true
+	}
+	for k := range input.Tags {
+		if m.ttlTag != "" && strings.EqualFold(k, m.ttlTag) {
+			return true
+		}
+	}
+	return false
+}
+
+// NeedsResourceGroupCreatedTime is NeedsCreatedTime for Resource Groups, which can also be filtered by age
+func (m Matcher) NeedsResourceGroupCreatedTime(input Object) bool {
+	return m.NeedsCreatedTime(input) || (m.resourceGroupFilter != nil && m.resourceGroupFilter.UsesAge())
+}
+
+// match applies the rules used by Match, additionally returning whether the object matched because it's expired - in
+// which case no further rules should be applied. An expired object only ignores the prefix when expiryOverridesPrefix
+// is set.
+func (m Matcher) match(input Object, now time.Time, expiryOverridesPrefix bool) (bool, string, bool) {
+	if tag, ok := ProtectedBy(input.Tags); ok {
+		return false, fmt.Sprintf("the tag %q is present", tag), false
+	}
+
+	var expiresAt *time.Time
+	var tag string
+	if !input.Deleted {
+		var err error
+		if expiresAt, tag, err = m.expiry(input); err != nil {
+			return false, err.Error(), false
+		}
+	}
+	if expiresAt != nil {
+		if now.Before(*expiresAt) {
+			return false, fmt.Sprintf("the tag %q specifies that it expires at %s", tag, expiresAt.Format(time.RFC3339)), false
+		}
+		expiredRule := fmt.Sprintf("the tag %q specifies that it expired at %s", tag, expiresAt.Format(time.RFC3339))
+		if expiryOverridesPrefix {
+			return true, expiredRule, true
+		}
+		if matched, rule := m.MatchName(input.Name); !matched {
+			return false, rule, false
+		}
+		return true, expiredRule, true
+	}
+
+	matched, rule := m.MatchName(input.Name)
+	if !matched {
+		return false, rule, false
+	}
+
+	if m.minimumAge > 0 && !input.Deleted {
+		if input.CreatedTime == nil {
+			return false, fmt.Sprintf("the time it was created is unknown, so it may be younger than the minimum age of %s", m.minimumAge), false
+		}
+		if age := now.Sub(*input.CreatedTime); age < m.minimumAge {
+			return false, fmt.Sprintf("it was created %s ago, which is less than the minimum age of %s", age.Round(time.Second), m.minimumAge), false
+		}
+	}
+
+	return true, rule, false
+}
+
+// expiry returns when the object expires according to its tags, along with the name of the tag which specified that
+// - or nil when it doesn't declare its own lifetime. The expires on tag is either an RFC3339 timestamp or a date (in
+// which case the object expires at the end of that day, in UTC), whereas the TTL tag is a duration relative to when
+// the object was created. When both are specified the earliest expiry is used.
+func (m Matcher) expiry(input Object) (*time.Time, string, error) {
+	var expiresAt *time.Time
+	var expiryTag string
+
+	for k, v := range input.Tags {
+		switch {
+		case m.expiresOnTag != "" && strings.EqualFold(k, m.expiresOnTag):
+			value, err := time.Parse(time.RFC3339, strings.TrimSpace(v))
+			if err != nil {
+				date, dateErr := time.Parse(time.DateOnly, strings.TrimSpace(v))
+				if dateErr != nil {
+					return nil, k, fmt.Errorf("the tag %q has the value %q which is neither an RFC3339 timestamp nor a date", k, v)
+				}
+				value = date.AddDate(0, 0, 1)
+			}
+			if expiresAt == nil || value.Before(*expiresAt) {
+				expiresAt, expiryTag = &value, k
+			}
+
+		case m.ttlTag != "" && strings.EqualFold(k, m.ttlTag):
+			ttl, err := time.ParseDuration(strings.TrimSpace(v))
+			if err != nil {
+				return nil, k, fmt.Errorf("the tag %q has the value %q which isn't a duration", k, v)
+			}
+			if input.CreatedTime == nil {
+				return nil, k, fmt.Errorf("the tag %q specifies a time to live but the time it was created is unknown", k)
+			}
+			value := input.CreatedTime.Add(ttl)
+			if expiresAt == nil || value.Before(*expiresAt) {
+				expiresAt, expiryTag = &value, k
+			}
+		}
+	}
+
+	return expiresAt, expiryTag, nil
+}
@@ -0,0 +1,376 @@
+package matcher
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackofallops/azurerm-dalek/dalek/filter"
+	"github.com/jackofallops/azurerm-dalek/dalek/options"
+)
+
+var now = time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+
+func newMatcher(opts options.Options) Matcher {
+	m := New(opts)
+	m.now = func() time.Time {
+		return now
+	}
+	return m
+}
+
+func ago(d time.Duration) *time.Time {
+	v := now.Add(-d)
+	return &v
+}
+
+type matchTestCase struct {
+	name     string
+	opts     options.Options
+	input    Object
+	expected bool
+
+	// rule is a substring of the expected rule
+	rule string
+}
+
+func (v matchTestCase) test(t *testing.T) {
+	t.Helper()
+
+	matched, rule := newMatcher(v.opts).Match(v.input)
+	if matched != v.expected {
+		t.Fatalf("expected %t but got %t (%s)", v.expected, matched, rule)
+	}
+	if !strings.Contains(rule, v.rule) {
+		t.Fatalf("expected the rule to contain %q but got %q", v.rule, rule)
+	}
+}
+
+func TestMatchMinimumAge(t *testing.T) {
+	opts := options.Options{
+		Prefix:     "acctest",
+		MinimumAge: 24 * time.Hour,
+	}
+
+	testData := []matchTestCase{
+		{
+			name:     "old enough",
+			opts:     opts,
+			input:    Object{Name: "acctest-1", CreatedTime: ago(25 * time.Hour)},
+			expected: true,
+			rule:     `the name starts with the prefix "acctest"`,
+		},
+		{
+			name:     "exactly the minimum age",
+			opts:     opts,
+			input:    Object{Name: "acctest-1", CreatedTime: ago(24 * time.Hour)},
+			expected: true,
+		},
+		{
+			name:     "too young",
+			opts:     opts,
+			input:    Object{Name: "acctest-1", CreatedTime: ago(23 * time.Hour)},
+			expected: false,
+			rule:     "it was created 23h0m0s ago, which is less than the minimum age of 24h0m0s",
+		},
+		{
+			name:     "unknown created time",
+			opts:     opts,
+			input:    Object{Name: "acctest-1"},
+			expected: false,
+			rule:     "the time it was created is unknown",
+		},
+		{
+			name:     "the prefix is checked first",
+			opts:     opts,
+			input:    Object{Name: "other", CreatedTime: ago(23 * time.Hour)},
+			expected: false,
+			rule:     "the name doesn't start with the prefix",
+		},
+		{
+			name:     "deleted objects ignore the minimum age",
+			opts:     opts,
+			input:    Object{Name: "acctest-1", Deleted: true},
+			expected: true,
+		},
+		{
+			name:     "no minimum age",
+			opts:     options.Options{Prefix: "acctest"},
+			input:    Object{Name: "ACCTEST-1"},
+			expected: true,
+		},
+	}
+
+	for _, v := range testData {
+		t.Run(v.name, v.test)
+	}
+}
+
+func TestNeedsCreatedTime(t *testing.T) {
+	testData := []struct {
+		name     string
+		opts     options.Options
+		input    Object
+		expected bool
+	}{
+		{
+			name:     "no rules",
+			opts:     options.Options{Prefix: "acctest"},
+			input:    Object{Name: "acctest-1"},
+			expected: false,
+		},
+		{
+			name:     "minimum age",
+			opts:     options.Options{MinimumAge: time.Hour},
+			input:    Object{Name: "acctest-1"},
+			expected: true,
+		},
+		{
+			name:     "minimum age for a deleted object",
+			opts:     options.Options{MinimumAge: time.Hour},
+			input:    Object{Name: "acctest-1", Deleted: true},
+			expected: false,
+		},
+		{
+			name:     "TTL tag",
+			opts:     options.Options{TTLTag: "ttl"},
+			input:    Object{Tags: map[string]string{"TTL": "1h"}},
+			expected: true,
+		},
+		{
+			name:     "without the TTL tag",
+			opts:     options.Options{TTLTag: "ttl"},
+			input:    Object{Tags: map[string]string{"expiresOn": "2026-01-01"}},
+			expected: false,
+		},
+	}
+
+	for _, v := range testData {
+		t.Run(v.name, func(t *testing.T) {
+			if actual := newMatcher(v.opts).NeedsCreatedTime(v.input); actual != v.expected {
+				t.Fatalf("expected %t but got %t", v.expected, actual)
+			}
+		})
+	}
+}
+
+func TestMatchExpiry(t *testing.T) {
+	opts := options.Options{
+		Prefix:       "acctest",
+		MinimumAge:   24 * time.Hour,
+		ExpiresOnTag: "expiresOn",
+		TTLTag:       "ttl",
+	}
+
+	testData := []matchTestCase{
+		{
+			name:     "expired timestamp",
+			opts:     opts,
+			input:    Object{Name: "acctest-1", Tags: map[string]string{"expiresOn": "2026-01-02T11:00:00Z"}},
+			expected: true,
+			rule:     `the tag "expiresOn" specifies that it expired at 2026-01-02T11:00:00Z`,
+		},
+		{
+			name:     "timestamp not yet expired",
+			opts:     opts,
+			input:    Object{Name: "acctest-1", CreatedTime: ago(48 * time.Hour), Tags: map[string]string{"expiresOn": "2026-01-02T13:00:00+00:00"}},
+			expected: false,
+			rule:     `the tag "expiresOn" specifies that it expires at 2026-01-02T13:00:00Z`,
+		},
+		{
+			name:     "a date expires at the end of the day",
+			opts:     opts,
+			input:    Object{Name: "acctest-1", Tags: map[string]string{"expiresOn": "2026-01-02"}},
+			expected: false,
+			rule:     "expires at 2026-01-03T00:00:00Z",
+		},
+		{
+			name:     "expired date",
+			opts:     opts,
+			input:    Object{Name: "acctest-1", Tags: map[string]string{"expiresOn": "2026-01-01"}},
+			expected: true,
+		},
+		{
+			name:     "the tag name is case-insensitive",
+			opts:     opts,
+			input:    Object{Name: "acctest-1", Tags: map[string]string{"EXPIRESON": " 2026-01-01 "}},
+			expected: true,
+		},
+		{
+			name:     "invalid expiry",
+			opts:     opts,
+			input:    Object{Name: "acctest-1", CreatedTime: ago(48 * time.Hour), Tags: map[string]string{"expiresOn": "tomorrow"}},
+			expected: false,
+			rule:     "which is neither an RFC3339 timestamp nor a date",
+		},
+		{
+			name:     "expired objects ignore the minimum age",
+			opts:     opts,
+			input:    Object{Name: "acctest-1", CreatedTime: ago(time.Hour), Tags: map[string]string{"ttl": "30m"}},
+			expected: true,
+			rule:     `the tag "ttl" specifies that it expired`,
+		},
+		{
+			name:     "expired objects must still match the prefix",
+			opts:     opts,
+			input:    Object{Name: "other", CreatedTime: ago(48 * time.Hour), Tags: map[string]string{"expiresOn": "2026-01-01"}},
+			expected: false,
+			rule:     `the name doesn't start with the prefix "acctest"`,
+		},
+		{
+			name:     "TTL not yet expired",
+			opts:     opts,
+			input:    Object{Name: "acctest-1", CreatedTime: ago(time.Hour), Tags: map[string]string{"ttl": "2h"}},
+			expected: false,
+			rule:     "expires at 2026-01-02T13:00:00Z",
+		},
+		{
+			name:     "TTL without a created time",
+			opts:     opts,
+			input:    Object{Name: "acctest-1", Tags: map[string]string{"ttl": "2h"}},
+			expected: false,
+			rule:     "the time it was created is unknown",
+		},
+		{
+			name:     "invalid TTL",
+			opts:     opts,
+			input:    Object{Name: "acctest-1", CreatedTime: ago(48 * time.Hour), Tags: map[string]string{"ttl": "2 days"}},
+			expected: false,
+			rule:     "which isn't a duration",
+		},
+		{
+			name:     "the earliest expiry is used",
+			opts:     opts,
+			input:    Object{Name: "acctest-1", CreatedTime: ago(time.Hour), Tags: map[string]string{"ttl": "30m", "expiresOn": "2027-01-01"}},
+			expected: true,
+			rule:     `the tag "ttl"`,
+		},
+		{
+			name:     "tags aren't used when the tag names aren't specified",
+			opts:     options.Options{Prefix: "acctest"},
+			input:    Object{Name: "acctest-1", Tags: map[string]string{"expiresOn": "2027-01-01"}},
+			expected: true,
+			rule:     "the name starts with the prefix",
+		},
+		{
+			name:     "deleted objects ignore the expiry",
+			opts:     opts,
+			input:    Object{Name: "acctest-1", Deleted: true, Tags: map[string]string{"expiresOn": "2027-01-01"}},
+			expected: true,
+			rule:     "the name starts with the prefix",
+		},
+	}
+
+	for _, v := range testData {
+		t.Run(v.name, v.test)
+	}
+}
+
+func TestMatchProtectionTag(t *testing.T) {
+	opts := options.Options{
+		ExpiresOnTag: "expiresOn",
+		TTLTag:       "ttl",
+	}
+
+	testData := []matchTestCase{
+		{
+			name:     "protected",
+			opts:     opts,
+			input:    Object{Name: "acctest-1", Tags: map[string]string{"DoNotDelete": ""}},
+			expected: false,
+			rule:     `the tag "DoNotDelete" is present`,
+		},
+		{
+			name:     "the tag name is case-insensitive",
+			opts:     opts,
+			input:    Object{Name: "acctest-1", Tags: map[string]string{"donotdelete": "true"}},
+			expected: false,
+			rule:     `the tag "donotdelete" is present`,
+		},
+		{
+			name:     "protected objects are kept after they've expired",
+			opts:     opts,
+			input:    Object{Name: "acctest-1", Tags: map[string]string{"DoNotDelete": "", "expiresOn": "2026-01-01"}},
+			expected: false,
+			rule:     "DoNotDelete",
+		},
+		{
+			name:     "protected objects are kept when the expiry is invalid",
+			opts:     opts,
+			input:    Object{Name: "acctest-1", Tags: map[string]string{"DoNotDelete": "", "ttl": "invalid"}},
+			expected: false,
+			rule:     "DoNotDelete",
+		},
+		{
+			name:     "deleted objects are protected",
+			opts:     opts,
+			input:    Object{Name: "acctest-1", Deleted: true, Tags: map[string]string{"DoNotDelete": ""}},
+			expected: false,
+			rule:     "DoNotDelete",
+		},
+	}
+
+	for _, v := range testData {
+		t.Run(v.name, v.test)
+	}
+}
+
+func TestMatchResourceGroup(t *testing.T) {
+	opts := options.Options{
+		Prefix:       "acctest",
+		ExpiresOnTag: "expiresOn",
+		ResourceGroupFilter: &filter.Filter{
+			Locations: []string{"westeurope"},
+		},
+	}
+
+	testData := []matchTestCase{
+		{
+			name:     "matches the filter",
+			opts:     opts,
+			input:    Object{Name: "acctest-1", Location: "West Europe"},
+			expected: true,
+			rule:     `and it matches the filter location in ["westeurope"]`,
+		},
+		{
+			name:     "doesn't match the filter",
+			opts:     opts,
+			input:    Object{Name: "acctest-1", Location: "eastus"},
+			expected: false,
+			rule:     "doesn't match the filter",
+		},
+		{
+			name:     "expired Resource Groups ignore the prefix",
+			opts:     opts,
+			input:    Object{Name: "other", Location: "westeurope", Tags: map[string]string{"expiresOn": "2026-01-01"}},
+			expected: true,
+			rule:     "expired at",
+		},
+		{
+			name:     "expired Resource Groups ignore the filter",
+			opts:     opts,
+			input:    Object{Name: "acctest-1", Location: "eastus", Tags: map[string]string{"expiresOn": "2026-01-01"}},
+			expected: true,
+			rule:     "expired at",
+		},
+		{
+			name:     "protected Resource Groups ignore the filter",
+			opts:     opts,
+			input:    Object{Name: "acctest-1", Location: "westeurope", Tags: map[string]string{"DoNotDelete": ""}},
+			expected: false,
+			rule:     "DoNotDelete",
+		},
+	}
+
+	for _, v := range testData {
+		t.Run(v.name, func(t *testing.T) {
+			matched, rule := newMatcher(v.opts).MatchResourceGroup(v.input)
+			if matched != v.expected {
+				t.Fatalf("expected %t but got %t (%s)", v.expected, matched, rule)
+			}
+			if !strings.Contains(rule, v.rule) {
+				t.Fatalf("expected the rule to contain %q but got %q", v.rule, rule)
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackofallops/azurerm-dalek/dalek/report"
+)
+
+// Job is the name of the job the metrics are grouped under when pushed to a Pushgateway
+const Job = "azurerm_dalek"
+
+// resourceGroupCleanupBuckets are the upper bounds (in seconds) of the buckets for the Resource Group cleanup histogram
+var resourceGroupCleanupBuckets = []float64{1, 5, 10, 30, 60, 120, 300, 600, 1800, 3600}
+
+type cleanerRun struct {
+	cleaner string
+	result  string
+}
+
+// Metrics collects the measurements which can't be derived from the run Report - the outcome of each Cleaner and how
+// long each Resource Group took to clean up - so that these can be output alongside those which can.
+type Metrics struct {
+	cleanerRuns            map[cleanerRun]int
+	resourceGroupDurations []float64
+
+	mu sync.Mutex
+}
+
+func New() *Metrics {
+	return &Metrics{
+		cleanerRuns:            make(map[cleanerRun]int),
+		resourceGroupDurations: make([]float64, 0),
+	}
+}
+
+// ObserveCleaner records the outcome of running the named Cleaner - this is a no-op on nil Metrics so that callers
+// needn't check whether metrics are being collected
+func (m *Metrics) ObserveCleaner(name string, err error) {
+	if m == nil {
+		return
+	}
+
+	result := "succeeded"
+	if err != nil {
+		result = "failed"
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cleanerRuns[cleanerRun{cleaner: name, result: result}]++
+}
+
+// ObserveResourceGroupCleanup records how long it took to run the Cleaners against and trigger the deletion of a
+// Resource Group - this is a no-op on nil Metrics
+func (m *Metrics) ObserveResourceGroupCleanup(duration time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.resourceGroupDurations = append(m.resourceGroupDurations, duration.Seconds())
+}
+
+// Write outputs the metrics in the Prometheus text format, combining those collected with those derived from the
+// specified Report - which must have been finished. The object counts are only as complete as the Report, so every
+// Cleaner which deletes or purges objects records these there.
+func (m *Metrics) Write(w io.Writer, r *report.Report) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := &writer{w: w}
+
+	objects := make(map[[2]string]int)
+	matched := make(map[string]int)
+	for _, entry := range r.Entries {
+		objects[[2]string{string(entry.Kind), string(entry.Action)}]++
+		if entry.Action != report.ActionSkipped {
+			matched[string(entry.Kind)]++
+		}
+	}
+
+	out.header("dalek_objects_total", "counter", "The number of objects considered during the run, by kind and the action taken.")
+	for _, key := range sortedKeys(objects, func(a, b [2]string) int {
+		return strings.Compare(a[0]+"|"+a[1], b[0]+"|"+b[1])
+	}) {
+		out.sample("dalek_objects_total", labels("kind", key[0], "action", key[1]), float64(objects[key]))
+	}
+
+	out.header("dalek_objects_matched_total", "counter", "The number of objects which matched the filter (and so were, or would have been, deleted or purged), by kind.")
+	for _, kind := range sortedKeys(matched, strings.Compare) {
+		out.sample("dalek_objects_matched_total", labels("kind", kind), float64(matched[kind]))
+	}
+
+	out.header("dalek_cleaner_runs_total", "counter", "The number of times each Cleaner was run, by result.")
+	for _, key := range sortedKeys(m.cleanerRuns, func(a, b cleanerRun) int {
+		return strings.Compare(a.cleaner+"|"+a.result, b.cleaner+"|"+b.result)
+	}) {
+		out.sample("dalek_cleaner_runs_total", labels("cleaner", key.cleaner, "result", key.result), float64(m.cleanerRuns[key]))
+	}
+
+	out.header("dalek_resource_group_cleanup_duration_seconds", "histogram", "How long it took to run the Cleaners against and trigger the deletion of each Resource Group.")
+	sum := 0.0
+	for _, v := range m.resourceGroupDurations {
+		sum += v
+	}
+	for _, bucket := range resourceGroupCleanupBuckets {
+		count := 0
+		for _, v := range m.resourceGroupDurations {
+			if v <= bucket {
+				count++
+			}
+		}
+		out.sample("dalek_resource_group_cleanup_duration_seconds_bucket", labels("le", formatFloat(bucket)), float64(count))
+	}
+	out.sample("dalek_resource_group_cleanup_duration_seconds_bucket", labels("le", "+Inf"), float64(len(m.resourceGroupDurations)))
+	out.sample("dalek_resource_group_cleanup_duration_seconds_sum", "", sum)
+	out.sample("dalek_resource_group_cleanup_duration_seconds_count", "", float64(len(m.resourceGroupDurations)))
+
+	actuallyDelete := 0.0
+	if r.ActuallyDelete {
+		actuallyDelete = 1
+	}
+	out.header("dalek_run_actually_delete", "gauge", "Whether the run deleted objects (1) or was a dry-run (0).")
+	out.sample("dalek_run_actually_delete", "", actuallyDelete)
+
+	out.header("dalek_run_errors", "gauge", "The number of errors returned from the run.")
+	out.sample("dalek_run_errors", "", float64(len(r.Errors)))
+
+	out.header("dalek_run_start_timestamp_seconds", "gauge", "When the run started, as a Unix timestamp.")
+	out.sample("dalek_run_start_timestamp_seconds", "", float64(r.StartedAt.Unix()))
+
+	out.header("dalek_run_duration_seconds", "gauge", "How long the run took.")
+	out.sample("dalek_run_duration_seconds", "", r.FinishedAt.Sub(r.StartedAt).Seconds())
+
+	return out.err
+}
+
+// WriteFile writes the metrics to the specified path, for use with the textfile collector of the node exporter. A
+// temporary file is written first and then renamed, so that the collector never reads a half-written file.
+func (m *Metrics) WriteFile(path string, r *report.Report) error {
+	buffer := &bytes.Buffer{}
+	if err := m.Write(buffer, r); err != nil {
+		return fmt.Errorf("building the metrics: %+v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("creating a temporary file for the metrics: %+v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(buffer.Bytes()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing the metrics to %q: %+v", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing %q: %+v", tmp.Name(), err)
+	}
+	// CreateTemp uses 0600, whereas the collector may well be running as a different user
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("setting the permissions of %q: %+v", tmp.Name(), err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("writing the metrics to %q: %+v", path, err)
+	}
+
+	return nil
+}
+
+// Push sends the metrics to the Pushgateway at the specified URL, replacing any metrics previously pushed for the Job
+func (m *Metrics) Push(ctx context.Context, pushgatewayURL string, r *report.Report) error {
+	buffer := &bytes.Buffer{}
+	if err := m.Write(buffer, r); err != nil {
+		return fmt.Errorf("building the metrics: %+v", err)
+	}
+
+	endpoint := fmt.Sprintf("%s/metrics/job/%s", strings.TrimSuffix(pushgatewayURL, "/"), url.PathEscape(Job))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, buffer)
+	if err != nil {
+		return fmt.Errorf("building the request to push the metrics to %q: %+v", endpoint, err)
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("pushing the metrics to %q: %+v", endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("pushing the metrics to %q: unexpected status %d: %s", endpoint, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
+
+// writer outputs the Prometheus text format, retaining the first error encountered so that this can be checked once
+type writer struct {
+	w   io.Writer
+	err error
+}
+
+func (w *writer) header(name, metricType, help string) {
+	w.printf("# HELP %s %s\n", name, help)
+	w.printf("# TYPE %s %s\n", name, metricType)
+}
+
+func (w *writer) sample(name, labels string, value float64) {
+	w.printf("%s%s %s\n", name, labels, formatFloat(value))
+}
+
+func (w *writer) printf(format string, args ...any) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = fmt.Fprintf(w.w, format, args...)
+}
+
+// labels formats the specified name/value pairs as a Prometheus label set
+func labels(pairs ...string) string {
+	output := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		output = append(output, fmt.Sprintf(`%s="%s"`, pairs[i], labelValueReplacer.Replace(pairs[i+1])))
+	}
+	return fmt.Sprintf("{%s}", strings.Join(output, ","))
+}
+
+// labelValueReplacer escapes the characters which the Prometheus text format requires to be escaped in label values
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func sortedKeys[K comparable, V any](input map[K]V, cmp func(a, b K) int) []K {
+	output := make([]K, 0, len(input))
+	for k := range input {
+		output = append(output, k)
+	}
+	slices.SortFunc(output, cmp)
+	return output
+}
+
+type metricsContextKey struct{}
+
+// WithMetrics returns a copy of ctx which carries the specified Metrics
+func WithMetrics(ctx context.Context, metrics *Metrics) context.Context {
+	return context.WithValue(ctx, metricsContextKey{}, metrics)
+}
+
+// FromContext returns the Metrics carried by ctx, or nil when ctx doesn't carry any
+func FromContext(ctx context.Context) *Metrics {
+	metrics, _ := ctx.Value(metricsContextKey{}).(*Metrics)
+	return metrics
+}
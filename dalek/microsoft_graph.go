@@ -2,9 +2,11 @@ package dalek
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
-	"strings"
+	"log/slog"
+	"net/http"
+	"time"
 
 	"github.com/hashicorp/go-azure-helpers/lang/pointer"
 	"github.com/hashicorp/go-azure-sdk/microsoft-graph/applications/stable/application"
@@ -13,358 +15,439 @@ import (
 	"github.com/hashicorp/go-azure-sdk/microsoft-graph/groups/stable/group"
 	"github.com/hashicorp/go-azure-sdk/microsoft-graph/serviceprincipals/stable/serviceprincipal"
 	"github.com/hashicorp/go-azure-sdk/microsoft-graph/users/stable/user"
+	"github.com/hashicorp/go-azure-sdk/sdk/nullable"
 	"github.com/hashicorp/go-azure-sdk/sdk/odata"
+	"github.com/jackofallops/azurerm-dalek/dalek/checkpoint"
+	"github.com/jackofallops/azurerm-dalek/dalek/logging"
+	"github.com/jackofallops/azurerm-dalek/dalek/matcher"
+	"github.com/jackofallops/azurerm-dalek/dalek/plan"
+	"github.com/jackofallops/azurerm-dalek/dalek/report"
+	"github.com/jackofallops/azurerm-dalek/dalek/retry"
+	"github.com/jackofallops/azurerm-dalek/dalek/safety"
 )
 
-func (d *Dalek) MicrosoftGraph(ctx context.Context) error {
-	log.Printf("[DEBUG] Preparing to delete Service Principals")
-	if err := d.deleteMicrosoftGraphServicePrincipals(ctx); err != nil {
-		return fmt.Errorf("deleting Service Principals: %+v", err)
-	}
-
-	log.Printf("[DEBUG] Preparing to delete Applications")
-	if err := d.deleteMicrosoftGraphApplications(ctx); err != nil {
-		return fmt.Errorf("deleting Applications: %+v", err)
-	}
-
-	log.Printf("[DEBUG] Preparing to delete Groups")
-	if err := d.deleteMicrosoftGraphGroups(ctx); err != nil {
-		return fmt.Errorf("deleting Groups: %+v", err)
-	}
-
-	log.Printf("[DEBUG] Preparing to delete Users")
-	if err := d.deleteMicrosoftGraphUsers(ctx); err != nil {
-		return fmt.Errorf("deleting Users: %+v", err)
-	}
-
-	return nil
+// graphPurgeRetryPolicy also retries a 404 when purging, since a deleted item isn't always consistently
+// visible immediately after it's been deleted
+var graphPurgeRetryPolicy = retry.Policy{
+	MaxAttempts:   4,
+	InitialDelay:  5 * time.Second,
+	MaxDelay:      time.Minute,
+	RetryNotFound: true,
 }
 
-func (d *Dalek) deleteMicrosoftGraphApplications(ctx context.Context) error {
-	if len(d.opts.Prefix) == 0 {
-		return fmt.Errorf("[ERROR] Not proceeding to delete Microsoft Graph Applications for safety; prefix not specified")
-	}
+// microsoftGraphObject is an object within Microsoft Graph being considered for deletion (or purging)
+type microsoftGraphObject struct {
+	ID          string
+	DisplayName string
 
-	client := d.client.MicrosoftGraph.Applications
-	deletedItemClient := d.client.MicrosoftGraph.DeletedItems
-
-	listOptions := application.ListApplicationsOperationOptions{
-		Filter: pointer.To(fmt.Sprintf("startswith(displayName, '%s')", d.opts.Prefix)),
-	}
-	resp, err := client.ListApplications(ctx, listOptions)
-	if err != nil {
-		return fmt.Errorf("listing Microsoft Graph Applications with prefix %q: %+v", d.opts.Prefix, err)
-	}
-	if resp.Model == nil {
-		return nil
-	}
+	// CreatedTime is when the object was created, or nil when Microsoft Graph doesn't expose this for the kind of
+	// object (or it's already been deleted, in which case it's not needed)
+	CreatedTime *time.Time
 
-	for _, app := range *resp.Model {
-		if app.Id == nil {
-			continue
-		}
+	// LogAttrs are any additional attributes logged for the object, such as the App ID of an Application
+	LogAttrs []any
+}
 
-		id := *app.Id
-		appID := app.AppId.GetOrZero()
-		displayName := app.DisplayName.GetOrZero()
+// microsoftGraphSweep deletes (or purges) one kind of Microsoft Graph object
+type microsoftGraphSweep struct {
+	// name is the name of the phase, which is used to record progress
+	name string
 
-		if strings.TrimPrefix(displayName, d.opts.Prefix) != displayName {
-			if !d.opts.ActuallyDelete {
-				log.Printf("[DEBUG] Would have deleted Microsoft Graph Application %q (AppID: %s, ObjID: %s)", displayName, appID, id)
-				continue
-			}
+	// description describes the objects, e.g. "Microsoft Graph Applications"
+	description string
 
-			log.Printf("[DEBUG] Deleting Microsoft Graph Application %q (AppID: %s, ObjectId: %s)...", displayName, appID, id)
-			if _, err := client.DeleteApplication(ctx, stable.NewApplicationID(id), application.DefaultDeleteApplicationOperationOptions()); err != nil {
-				log.Printf("[DEBUG] Error during deletion of Microsoft Graph Application %q (AppID: %s, ObjID: %s): %s", displayName, appID, id, err)
-				continue
-			}
-			log.Printf("[DEBUG] Deleted Microsoft Graph Application %q (AppID: %s, ObjID: %s)", displayName, appID, id)
-		}
-	}
+	kind report.Kind
 
-	deletedListOptions := deleteditem.ListDeletedItemApplicationsOperationOptions{
-		Select: pointer.To([]string{"id", "displayName"}),
-	}
+	// purge is whether the objects listed have already been deleted, and so are purged rather than deleted
+	purge bool
 
-	deletedResp, err := deletedItemClient.ListDeletedItemApplicationsComplete(ctx, deletedListOptions)
-	if err != nil {
-		return fmt.Errorf("listing deleted applications: %+v", err)
-	}
+	list   func(ctx context.Context) ([]microsoftGraphObject, error)
+	delete func(ctx context.Context, id string) (*http.Response, error)
+}
 
-	for _, g := range deletedResp.Items {
-		if g.Id == nil {
+func (d *Dalek) MicrosoftGraph(ctx context.Context) error {
+	d = d.withClient(d.clients.MicrosoftGraph())
+	sweeps := []microsoftGraphSweep{
+		d.microsoftGraphServicePrincipals(),
+		d.deletedMicrosoftGraphServicePrincipals(),
+		d.microsoftGraphApplications(),
+		d.deletedMicrosoftGraphApplications(),
+		d.microsoftGraphGroups(),
+		d.deletedMicrosoftGraphGroups(),
+		d.microsoftGraphUsers(),
+		d.deletedMicrosoftGraphUsers(),
+	}
+
+	logger := logging.FromContext(ctx)
+	progress := checkpoint.FromContext(ctx)
+	errs := make([]error, 0)
+	for _, sweep := range sweeps {
+		if d.opts.PurgeOnly && !sweep.purge {
 			continue
 		}
-
-		id := *g.Id
-		displayName := g.DisplayName.GetOrZero()
-
-		// TODO: Arguably if an application has been deleted we can quite safely assume it can be purged, remove check?
-		if strings.TrimPrefix(displayName, d.opts.Prefix) == displayName {
+		if progress.IsComplete(d.client.TenantID, "Microsoft Graph", sweep.name) {
+			logger.Debug("Skipping Microsoft Graph phase since it was completed by a previous run", slog.String("phase", sweep.name))
 			continue
 		}
 
-		if !d.opts.ActuallyDelete {
-			log.Printf("[DEBUG] Would have purged Microsoft Graph Application %q (ObjID: %s)", displayName, id)
+		logger.Debug("Preparing to delete Microsoft Graph objects", slog.String("phase", sweep.name))
+		// a sweep is only recorded as complete when nothing failed, so that a resumed run retries any failures
+		if err := d.sweepMicrosoftGraph(ctx, sweep); err != nil {
+			errs = append(errs, fmt.Errorf("deleting %s: %+v", sweep.name, err))
 			continue
 		}
 
-		log.Printf("[DEBUG] Purging Microsoft Graph Application %q (ObjectId: %s)...", displayName, id)
-		if _, err := deletedItemClient.DeleteDeletedItem(ctx, stable.NewDirectoryDeletedItemID(id), deleteditem.DefaultDeleteDeletedItemOperationOptions()); err != nil {
-			log.Printf("[DEBUG] Error during purging of Microsoft Graph Application %q (ObjID: %s): %s", displayName, id, err)
-			continue
+		if err := progress.Complete(d.client.TenantID, "Microsoft Graph", sweep.name); err != nil {
+			errs = append(errs, fmt.Errorf("recording progress: %+v", err))
 		}
-		log.Printf("[DEBUG] Purged Microsoft Graph Application %q (ObjID: %s)", displayName, id)
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
 
-func (d *Dalek) deleteMicrosoftGraphGroups(ctx context.Context) error {
+// sweepMicrosoftGraph deletes (or purges) each of the objects listed by the sweep which match. Microsoft Graph objects
+// have no tags, so neither the `DoNotDelete` tag nor the expiry tags apply - but the prefix does, as does the minimum
+// age when the object isn't already deleted (meaning objects for which Microsoft Graph doesn't expose when these were
+// created are skipped when a minimum age is specified).
+func (d *Dalek) sweepMicrosoftGraph(ctx context.Context, sweep microsoftGraphSweep) error {
+	verb, wouldHave, performing, performed := "delete", "Would have deleted Microsoft Graph object", "Deleting Microsoft Graph object", "Deleted Microsoft Graph object"
+	wouldAction, performedAction, retryPolicy, retryDescription := report.ActionWouldDelete, report.ActionDeleted, retry.DefaultPolicy, "deleting %s"
+	if sweep.purge {
+		verb, wouldHave, performing, performed = "purge", "Would have purged deleted Microsoft Graph object", "Purging deleted Microsoft Graph object", "Purged deleted Microsoft Graph object"
+		wouldAction, performedAction, retryPolicy, retryDescription = report.ActionWouldPurge, report.ActionPurged, graphPurgeRetryPolicy, "purging deleted item %s"
+	}
 	if len(d.opts.Prefix) == 0 {
-		return fmt.Errorf("[ERROR] Not proceeding to delete Microsoft Graph Groups for safety; prefix not specified")
+		return fmt.Errorf("not proceeding to %s %s for safety; prefix not specified", verb, sweep.description)
 	}
 
-	client := d.client.MicrosoftGraph.Groups
-	deletedItemClient := d.client.MicrosoftGraph.DeletedItems
+	rep := report.FromContext(ctx)
+	gate := plan.FromContext(ctx)
+	logger := logging.FromContext(ctx)
 
-	listOptions := group.ListGroupsOperationOptions{
-		Filter: pointer.To(fmt.Sprintf("startswith(displayName, '%s')", d.opts.Prefix)),
-	}
-	resp, err := client.ListGroups(ctx, listOptions)
+	objects, err := sweep.list(ctx)
 	if err != nil {
-		return fmt.Errorf("[ERROR] Unable to list Microsoft Graph Groups with prefix: %q", d.opts.Prefix)
+		return fmt.Errorf("listing %s: %+v", sweep.description, err)
 	}
-	if resp.Model == nil {
-		return nil
-	}
-
-	for _, g := range *resp.Model {
-		if g.Id == nil {
-			continue
-		}
 
-		id := *g.Id
-		displayName := g.DisplayName.GetOrZero()
-
-		if strings.TrimPrefix(displayName, d.opts.Prefix) != displayName {
-			if !d.opts.ActuallyDelete {
-				log.Printf("[DEBUG] Would have deleted Microsoft Graph Group %q (ObjID: %s)", displayName, id)
-				continue
-			}
-
-			log.Printf("[DEBUG] Deleting Microsoft Graph Group %q (ObjectId: %s)...", displayName, id)
-			if _, err := client.DeleteGroup(ctx, stable.NewGroupID(id), group.DefaultDeleteGroupOperationOptions()); err != nil {
-				log.Printf("[DEBUG] Error during deletion of Microsoft Graph Group %q (ObjID: %s): %s", displayName, id, err)
-				continue
-			}
-			log.Printf("[DEBUG] Deleted Microsoft Graph Group %q (ObjID: %s)", displayName, id)
+	// the safety limits are checked before any of these are deleted - when applying a plan, against those planned
+	matched := 0
+	ids := make([]string, 0, len(objects))
+	for _, object := range objects {
+		ids = append(ids, object.ID)
+		if ok, _ := d.matcher.Match(microsoftGraphMatcherObject(object, sweep.purge)); ok {
+			matched++
 		}
 	}
-
-	deletedListOptions := deleteditem.ListDeletedItemGroupsOperationOptions{
-		Select: pointer.To([]string{"id", "displayName"}),
+	guard := safety.FromContext(ctx)
+	if err := guard.CheckGraphObjects(ctx, sweep.description, gate.Count(sweep.kind, ids, matched)); err != nil {
+		return err
 	}
-
-	deletedResp, err := deletedItemClient.ListDeletedItemGroupsComplete(ctx, deletedListOptions)
-	if err != nil {
-		return fmt.Errorf("listing deleted groups: %+v", err)
+	if guard.CountOnly() {
+		return nil
 	}
 
-	for _, g := range deletedResp.Items {
-		if g.Id == nil {
-			continue
+	errs := make([]error, 0)
+	for _, object := range objects {
+		attrs := append([]any{logging.ResourceID(object.ID), slog.String("kind", string(sweep.kind)), slog.String("display_name", object.DisplayName)}, object.LogAttrs...)
+		entry := report.Entry{
+			ID:    object.ID,
+			Name:  object.DisplayName,
+			Kind:  sweep.kind,
+			Scope: d.client.TenantID,
 		}
 
-		id := *g.Id
-		displayName := g.DisplayName.GetOrZero()
+		matched, rule := d.matcher.Match(microsoftGraphMatcherObject(object, sweep.purge))
+		entry.Rule = rule
+		if !matched {
+			entry.Action = report.ActionSkipped
+			rep.Record(entry)
+			continue
+		}
 
-		// TODO: Arguably if a group has been deleted we can quite safely assume it can be purged, remove check?
-		if strings.TrimPrefix(displayName, d.opts.Prefix) == displayName {
+		planItem := plan.Item{
+			Kind:  entry.Kind,
+			ID:    entry.ID,
+			Name:  entry.Name,
+			Scope: entry.Scope,
+		}
+		if err := gate.Check(planItem); err != nil {
+			logger.Warn("Skipping Microsoft Graph object", append(attrs, logging.Err(err))...)
+			entry.Action = report.ActionSkipped
+			entry.Rule = err.Error()
+			rep.Record(entry)
 			continue
 		}
 
 		if !d.opts.ActuallyDelete {
-			log.Printf("[DEBUG] Would have purged Microsoft Graph Group %q (ObjID: %s)", displayName, id)
+			logger.Info(wouldHave, attrs...)
+			entry.Action = wouldAction
+			rep.Record(entry)
 			continue
 		}
 
-		log.Printf("[DEBUG] Purging Microsoft Graph Group %q (ObjectId: %s)...", displayName, id)
-		if _, err := deletedItemClient.DeleteDeletedItem(ctx, stable.NewDirectoryDeletedItemID(id), deleteditem.DefaultDeleteDeletedItemOperationOptions()); err != nil {
-			log.Printf("[DEBUG] Error during purging of Microsoft Graph Group %q (ObjID: %s): %s", displayName, id, err)
+		logger.Info(performing, attrs...)
+		startedAt := time.Now()
+		err := retryPolicy.Do(ctx, fmt.Sprintf(retryDescription, object.ID), func(ctx context.Context) (*http.Response, error) {
+			return sweep.delete(ctx, object.ID)
+		})
+		entry.DurationSeconds = time.Since(startedAt).Seconds()
+		if err != nil {
+			logger.Error(performing, append(attrs, logging.Err(err))...)
+			errs = append(errs, fmt.Errorf("%s %q: %+v", sweep.kind, object.ID, err))
+			entry.Action = report.ActionFailed
+			entry.Error = err.Error()
+			rep.Record(entry)
 			continue
 		}
-		log.Printf("[DEBUG] Purged Microsoft Graph Group %q (ObjID: %s)", displayName, id)
+		logger.Info(performed, attrs...)
+		entry.Action = performedAction
+		rep.Record(entry)
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
 
-func (d *Dalek) deleteMicrosoftGraphServicePrincipals(ctx context.Context) error {
-	if len(d.opts.Prefix) == 0 {
-		return fmt.Errorf("[ERROR] Not proceeding to delete Microsoft Graph Service Principals for safety; prefix not specified")
+func microsoftGraphMatcherObject(object microsoftGraphObject, deleted bool) matcher.Object {
+	return matcher.Object{
+		Name:        object.DisplayName,
+		CreatedTime: object.CreatedTime,
+		Deleted:     deleted,
 	}
+}
 
-	client := d.client.MicrosoftGraph.ServicePrincipals
-	deletedItemClient := d.client.MicrosoftGraph.DeletedItems
-	//
-	listOptions := serviceprincipal.ListServicePrincipalsOperationOptions{
-		ConsistencyLevel: pointer.To(odata.ConsistencyLevelEventual),
-		Count:            pointer.To(true),
-		// skip `ManagedIdentity` types as these cannot be deleted using the API
-		Filter: pointer.To(fmt.Sprintf("startswith(displayName, '%s') and servicePrincipalType ne 'ManagedIdentity'", d.opts.Prefix)),
-	}
-	resp, err := client.ListServicePrincipals(ctx, listOptions)
+// microsoftGraphCreatedTime parses the `createdDateTime` of a Microsoft Graph object, returning nil when it's not
+// available
+func microsoftGraphCreatedTime(input nullable.Type[string]) *time.Time {
+	v, err := time.Parse(time.RFC3339, input.GetOrZero())
 	if err != nil {
-		return fmt.Errorf("listing Microsoft Graph Service Principals with prefix %q: %+v", d.opts.Prefix, err)
-	}
-	if resp.Model == nil {
 		return nil
 	}
+	return &v
+}
 
-	for _, servicePrincipal := range *resp.Model {
-		if servicePrincipal.Id == nil {
-			continue
-		}
-
-		id := *servicePrincipal.Id
-		displayName := servicePrincipal.DisplayName.GetOrZero()
-
-		if strings.TrimPrefix(displayName, d.opts.Prefix) != displayName {
-			if !d.opts.ActuallyDelete {
-				log.Printf("[DEBUG] Would have deleted Microsoft Graph Service Principal %q (ObjID: %s)", displayName, id)
-				continue
+func (d *Dalek) microsoftGraphApplications() microsoftGraphSweep {
+	client := d.client.MicrosoftGraph.Applications
+	return microsoftGraphSweep{
+		name:        "Applications",
+		description: "Microsoft Graph Applications",
+		kind:        report.KindApplication,
+		list: func(ctx context.Context) ([]microsoftGraphObject, error) {
+			resp, err := client.ListApplications(ctx, application.ListApplicationsOperationOptions{
+				Filter: pointer.To(fmt.Sprintf("startswith(displayName, '%s')", d.opts.Prefix)),
+			})
+			if err != nil {
+				return nil, err
 			}
-
-			log.Printf("[DEBUG] Deleting Microsoft Graph Service Principal %q (ObjectId: %s)...", displayName, id)
-			if _, err := client.DeleteServicePrincipal(ctx, stable.NewServicePrincipalID(id), serviceprincipal.DefaultDeleteServicePrincipalOperationOptions()); err != nil {
-				log.Printf("[DEBUG] Error during deletion of Microsoft Graph Service Principal %q (ObjID: %s): %s", displayName, id, err)
-				continue
+			output := make([]microsoftGraphObject, 0)
+			for _, v := range pointer.From(resp.Model) {
+				if v.Id == nil {
+					continue
+				}
+				output = append(output, microsoftGraphObject{
+					ID:          *v.Id,
+					DisplayName: v.DisplayName.GetOrZero(),
+					CreatedTime: microsoftGraphCreatedTime(v.CreatedDateTime),
+					LogAttrs:    []any{slog.String("app_id", v.AppId.GetOrZero())},
+				})
 			}
-			log.Printf("[DEBUG] Deleted Microsoft Graph Service Principal %q (ObjID: %s)", displayName, id)
-		}
-	}
-
-	deletedListOptions := deleteditem.ListDeletedItemServicePrincipalsOperationOptions{
-		Select: pointer.To([]string{"id", "displayName", "servicePrincipalType"}),
-	}
-
-	deletedResp, err := deletedItemClient.ListDeletedItemServicePrincipalsComplete(ctx, deletedListOptions)
-	if err != nil {
-		return fmt.Errorf("listing deleted service principals: %+v", err)
+			return output, nil
+		},
+		delete: func(ctx context.Context, id string) (*http.Response, error) {
+			resp, err := client.DeleteApplication(ctx, stable.NewApplicationID(id), application.DefaultDeleteApplicationOperationOptions())
+			return resp.HttpResponse, err
+		},
 	}
+}
 
-	for _, g := range deletedResp.Items {
-		if g.Id == nil {
-			continue
+func (d *Dalek) deletedMicrosoftGraphApplications() microsoftGraphSweep {
+	return d.deletedMicrosoftGraphObjects("Applications", report.KindApplication, func(ctx context.Context) ([]microsoftGraphObject, error) {
+		resp, err := d.client.MicrosoftGraph.DeletedItems.ListDeletedItemApplicationsComplete(ctx, deleteditem.ListDeletedItemApplicationsOperationOptions{
+			Select: pointer.To([]string{"id", "displayName"}),
+		})
+		if err != nil {
+			return nil, err
 		}
-
-		// filter this here rather than server side because there is currently no way to pass `ConsistencyLevel` to this List nmethod
-		if g.ServicePrincipalType.GetOrZero() == "ManagedIdentity" {
-			continue
+		output := make([]microsoftGraphObject, 0)
+		for _, v := range resp.Items {
+			if v.Id != nil {
+				output = append(output, microsoftGraphObject{ID: *v.Id, DisplayName: v.DisplayName.GetOrZero()})
+			}
 		}
+		return output, nil
+	})
+}
 
-		id := *g.Id
-		displayName := g.DisplayName.GetOrZero()
-
-		// TODO: Arguably if a service principal has been deleted we can quite safely assume it can be purged, remove check?
-		if strings.TrimPrefix(displayName, d.opts.Prefix) == displayName {
-			continue
-		}
+func (d *Dalek) microsoftGraphGroups() microsoftGraphSweep {
+	client := d.client.MicrosoftGraph.Groups
+	return microsoftGraphSweep{
+		name:        "Groups",
+		description: "Microsoft Graph Groups",
+		kind:        report.KindGroup,
+		list: func(ctx context.Context) ([]microsoftGraphObject, error) {
+			resp, err := client.ListGroups(ctx, group.ListGroupsOperationOptions{
+				Filter: pointer.To(fmt.Sprintf("startswith(displayName, '%s')", d.opts.Prefix)),
+			})
+			if err != nil {
+				return nil, err
+			}
+			output := make([]microsoftGraphObject, 0)
+			for _, v := range pointer.From(resp.Model) {
+				if v.Id == nil {
+					continue
+				}
+				output = append(output, microsoftGraphObject{
+					ID:          *v.Id,
+					DisplayName: v.DisplayName.GetOrZero(),
+					CreatedTime: microsoftGraphCreatedTime(v.CreatedDateTime),
+				})
+			}
+			return output, nil
+		},
+		delete: func(ctx context.Context, id string) (*http.Response, error) {
+			resp, err := client.DeleteGroup(ctx, stable.NewGroupID(id), group.DefaultDeleteGroupOperationOptions())
+			return resp.HttpResponse, err
+		},
+	}
+}
 
-		if !d.opts.ActuallyDelete {
-			log.Printf("[DEBUG] Would have purged Microsoft Graph Service Principal %q (ObjID: %s)", displayName, id)
-			continue
+func (d *Dalek) deletedMicrosoftGraphGroups() microsoftGraphSweep {
+	return d.deletedMicrosoftGraphObjects("Groups", report.KindGroup, func(ctx context.Context) ([]microsoftGraphObject, error) {
+		resp, err := d.client.MicrosoftGraph.DeletedItems.ListDeletedItemGroupsComplete(ctx, deleteditem.ListDeletedItemGroupsOperationOptions{
+			Select: pointer.To([]string{"id", "displayName"}),
+		})
+		if err != nil {
+			return nil, err
 		}
-
-		log.Printf("[DEBUG] Purging Microsoft Graph Service Principal %q (ObjectId: %s)...", displayName, id)
-		if _, err := deletedItemClient.DeleteDeletedItem(ctx, stable.NewDirectoryDeletedItemID(id), deleteditem.DefaultDeleteDeletedItemOperationOptions()); err != nil {
-			log.Printf("[DEBUG] Error during purging of Microsoft Graph Service Principal %q (ObjID: %s): %s", displayName, id, err)
-			return fmt.Errorf("deleting deleted items: %+v", err)
+		output := make([]microsoftGraphObject, 0)
+		for _, v := range resp.Items {
+			if v.Id != nil {
+				output = append(output, microsoftGraphObject{ID: *v.Id, DisplayName: v.DisplayName.GetOrZero()})
+			}
 		}
-		log.Printf("[DEBUG] Purged Microsoft Graph Service Principal %q (ObjID: %s)", displayName, id)
-	}
-
-	return nil
+		return output, nil
+	})
 }
 
-func (d *Dalek) deleteMicrosoftGraphUsers(ctx context.Context) error {
-	if len(d.opts.Prefix) == 0 {
-		return fmt.Errorf("[ERROR] Not proceeding to delete Microsoft Graph Users for safety; prefix not specified")
-	}
-
-	client := d.client.MicrosoftGraph.Users
-	deletedItemClient := d.client.MicrosoftGraph.DeletedItems
-
-	listOptions := user.ListUsersOperationOptions{
-		Filter: pointer.To(fmt.Sprintf("startswith(displayName, '%s')", d.opts.Prefix)),
-	}
-	resp, err := client.ListUsers(ctx, listOptions)
-	if err != nil {
-		return fmt.Errorf("[ERROR] Unable to list Microsoft Graph Users with prefix: %q", d.opts.Prefix)
-	}
-	if resp.Model == nil {
-		return nil
+// microsoftGraphServicePrincipals deletes the Service Principals - Microsoft Graph doesn't expose when these were
+// created, so these are skipped when a minimum age is specified (although deleting an Application also deletes its
+// Service Principal)
+func (d *Dalek) microsoftGraphServicePrincipals() microsoftGraphSweep {
+	client := d.client.MicrosoftGraph.ServicePrincipals
+	return microsoftGraphSweep{
+		name:        "Service Principals",
+		description: "Microsoft Graph Service Principals",
+		kind:        report.KindServicePrincipal,
+		list: func(ctx context.Context) ([]microsoftGraphObject, error) {
+			resp, err := client.ListServicePrincipals(ctx, serviceprincipal.ListServicePrincipalsOperationOptions{
+				ConsistencyLevel: pointer.To(odata.ConsistencyLevelEventual),
+				Count:            pointer.To(true),
+				// skip `ManagedIdentity` types as these cannot be deleted using the API
+				Filter: pointer.To(fmt.Sprintf("startswith(displayName, '%s') and servicePrincipalType ne 'ManagedIdentity'", d.opts.Prefix)),
+			})
+			if err != nil {
+				return nil, err
+			}
+			output := make([]microsoftGraphObject, 0)
+			for _, v := range pointer.From(resp.Model) {
+				if v.Id != nil {
+					output = append(output, microsoftGraphObject{ID: *v.Id, DisplayName: v.DisplayName.GetOrZero()})
+				}
+			}
+			return output, nil
+		},
+		delete: func(ctx context.Context, id string) (*http.Response, error) {
+			resp, err := client.DeleteServicePrincipal(ctx, stable.NewServicePrincipalID(id), serviceprincipal.DefaultDeleteServicePrincipalOperationOptions())
+			return resp.HttpResponse, err
+		},
 	}
+}
 
-	for _, u := range *resp.Model {
-		if u.Id == nil {
-			continue
+func (d *Dalek) deletedMicrosoftGraphServicePrincipals() microsoftGraphSweep {
+	return d.deletedMicrosoftGraphObjects("Service Principals", report.KindServicePrincipal, func(ctx context.Context) ([]microsoftGraphObject, error) {
+		resp, err := d.client.MicrosoftGraph.DeletedItems.ListDeletedItemServicePrincipalsComplete(ctx, deleteditem.ListDeletedItemServicePrincipalsOperationOptions{
+			Select: pointer.To([]string{"id", "displayName", "servicePrincipalType"}),
+		})
+		if err != nil {
+			return nil, err
 		}
-
-		id := *u.Id
-		displayName := u.DisplayName.GetOrZero()
-
-		if strings.TrimPrefix(displayName, d.opts.Prefix) != displayName {
-			if !d.opts.ActuallyDelete {
-				log.Printf("[DEBUG] Would have deleted Microsoft Graph User %q (ObjID: %s)", displayName, id)
+		output := make([]microsoftGraphObject, 0)
+		for _, v := range resp.Items {
+			// filter this here rather than server side because there is currently no way to pass `ConsistencyLevel` to this List nmethod
+			if v.Id == nil || v.ServicePrincipalType.GetOrZero() == "ManagedIdentity" {
 				continue
 			}
-
-			log.Printf("[DEBUG] Deleting Microsoft Graph User %q (ObjectId: %s)...", displayName, id)
-			if _, err := client.DeleteUser(ctx, stable.NewUserID(id), user.DefaultDeleteUserOperationOptions()); err != nil {
-				log.Printf("[DEBUG] Error during deletion of Microsoft Graph User %q (ObjID: %s): %s", displayName, id, err)
-				continue
-			}
-			log.Printf("[DEBUG] Deleted Microsoft Graph User %q (ObjID: %s)", displayName, id)
+			output = append(output, microsoftGraphObject{ID: *v.Id, DisplayName: v.DisplayName.GetOrZero()})
 		}
-	}
-
-	deletedListOptions := deleteditem.ListDeletedItemUsersOperationOptions{
-		Select: pointer.To([]string{"id", "displayName"}),
-	}
+		return output, nil
+	})
+}
 
-	deletedResp, err := deletedItemClient.ListDeletedItemUsersComplete(ctx, deletedListOptions)
-	if err != nil {
-		return fmt.Errorf("listing deleted users: %+v", err)
+func (d *Dalek) microsoftGraphUsers() microsoftGraphSweep {
+	client := d.client.MicrosoftGraph.Users
+	return microsoftGraphSweep{
+		name:        "Users",
+		description: "Microsoft Graph Users",
+		kind:        report.KindUser,
+		list: func(ctx context.Context) ([]microsoftGraphObject, error) {
+			resp, err := client.ListUsers(ctx, user.ListUsersOperationOptions{
+				Filter: pointer.To(fmt.Sprintf("startswith(displayName, '%s')", d.opts.Prefix)),
+				// `createdDateTime` isn't one of the properties returned for Users by default
+				Select: pointer.To([]string{"id", "displayName", "createdDateTime"}),
+			})
+			if err != nil {
+				return nil, err
+			}
+			output := make([]microsoftGraphObject, 0)
+			for _, v := range pointer.From(resp.Model) {
+				if v.Id == nil {
+					continue
+				}
+				output = append(output, microsoftGraphObject{
+					ID:          *v.Id,
+					DisplayName: v.DisplayName.GetOrZero(),
+					CreatedTime: microsoftGraphCreatedTime(v.CreatedDateTime),
+				})
+			}
+			return output, nil
+		},
+		delete: func(ctx context.Context, id string) (*http.Response, error) {
+			resp, err := client.DeleteUser(ctx, stable.NewUserID(id), user.DefaultDeleteUserOperationOptions())
+			return resp.HttpResponse, err
+		},
 	}
+}
 
-	for _, g := range deletedResp.Items {
-		if g.Id == nil {
-			continue
-		}
-
-		id := *g.Id
-		displayName := g.DisplayName.GetOrZero()
-
-		// TODO: Arguably if a user has been deleted we can quite safely assume it can be purged, remove check?
-		if strings.TrimPrefix(displayName, d.opts.Prefix) == displayName {
-			continue
+func (d *Dalek) deletedMicrosoftGraphUsers() microsoftGraphSweep {
+	return d.deletedMicrosoftGraphObjects("Users", report.KindUser, func(ctx context.Context) ([]microsoftGraphObject, error) {
+		resp, err := d.client.MicrosoftGraph.DeletedItems.ListDeletedItemUsersComplete(ctx, deleteditem.ListDeletedItemUsersOperationOptions{
+			Select: pointer.To([]string{"id", "displayName"}),
+		})
+		if err != nil {
+			return nil, err
 		}
-
-		if !d.opts.ActuallyDelete {
-			log.Printf("[DEBUG] Would have purged Microsoft Graph User %q (ObjID: %s)", displayName, id)
-			continue
+		output := make([]microsoftGraphObject, 0)
+		for _, v := range resp.Items {
+			if v.Id != nil {
+				output = append(output, microsoftGraphObject{ID: *v.Id, DisplayName: v.DisplayName.GetOrZero()})
+			}
 		}
+		return output, nil
+	})
+}
 
-		log.Printf("[DEBUG] Purging Microsoft Graph User %q (ObjectId: %s)...", displayName, id)
-		if _, err := deletedItemClient.DeleteDeletedItem(ctx, stable.NewDirectoryDeletedItemID(id), deleteditem.DefaultDeleteDeletedItemOperationOptions()); err != nil {
-			log.Printf("[DEBUG] Error during purging of Microsoft Graph User %q (ObjID: %s): %s", displayName, id, err)
-			continue
-		}
-		log.Printf("[DEBUG] Purged Microsoft Graph User %q (ObjID: %s)", displayName, id)
+// deletedMicrosoftGraphObjects returns the sweep which purges the deleted Microsoft Graph objects returned by list,
+// which are all purged in the same way
+func (d *Dalek) deletedMicrosoftGraphObjects(plural string, kind report.Kind, list func(ctx context.Context) ([]microsoftGraphObject, error)) microsoftGraphSweep {
+	client := d.client.MicrosoftGraph.DeletedItems
+	return microsoftGraphSweep{
+		name:        fmt.Sprintf("Deleted %s", plural),
+		description: fmt.Sprintf("deleted Microsoft Graph %s", plural),
+		kind:        kind,
+		purge:       true,
+		list:        list,
+		delete: func(ctx context.Context, id string) (*http.Response, error) {
+			resp, err := client.DeleteDeletedItem(ctx, stable.NewDirectoryDeletedItemID(id), deleteditem.DefaultDeleteDeletedItemOperationOptions())
+			return resp.HttpResponse, err
+		},
 	}
-
-	return nil
 }
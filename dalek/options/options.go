@@ -1,21 +1,164 @@
 package options
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
+
+	"github.com/jackofallops/azurerm-dalek/dalek/allowlist"
+	"github.com/jackofallops/azurerm-dalek/dalek/filter"
+	"github.com/jackofallops/azurerm-dalek/dalek/safety"
 )
 
+// ProtectedResources determines what happens to a Resource Group which matches, but contains resources with the
+// `DoNotDelete` tag
+type ProtectedResources string
+
+const (
+	// ProtectedResourcesSkip skips the Resource Group, leaving everything within it
+	ProtectedResourcesSkip ProtectedResources = "skip"
+
+	// ProtectedResourcesDeleteUnprotected deletes the other resources within the Resource Group, but not the
+	// Resource Group itself
+	ProtectedResourcesDeleteUnprotected ProtectedResources = "delete-unprotected"
+)
+
+// Validate returns an error when the value isn't one of the supported values
+func (p ProtectedResources) Validate() error {
+	switch p {
+	case ProtectedResourcesSkip, ProtectedResourcesDeleteUnprotected:
+		return nil
+	}
+	return fmt.Errorf("expected the protected resources behaviour to be %q or %q but got %q", ProtectedResourcesSkip, ProtectedResourcesDeleteUnprotected, p)
+}
+
 type Options struct {
 	Prefix                         string
+	SubscriptionIDs                []string
+	AllSubscriptions               bool
+	ManagementGroupID              string
 	NumberOfResourceGroupsToDelete int64
 	ActuallyDelete                 bool
+	Parallelism                    int
+	WaitForDeletion                bool
+	WaitForDeletionTimeout         time.Duration
+	ReportFile                     string
+	Resume                         bool
+	StateFile                      string
+	MetricsFile                    string
+	MetricsPushgatewayURL          string
+	Cleaners                       []string
+	SkipCleaners                   []string
+	Timeout                        time.Duration
+
+	// MinimumAge is how long ago Resource Groups (and the resources deleted by the Subscription Cleaners) must
+	// have been created to be deleted, so that those still in use by running tests are left alone
+	MinimumAge time.Duration
+
+	// ExpiresOnTag and TTLTag are the names of the tags which Resource Groups can use to declare their own lifetime,
+	// either of which can be empty to disable it
+	ExpiresOnTag string
+	TTLTag       string
+
+	// ResourceGroupFilter, when specified, must also match for a Resource Group to be deleted
+	ResourceGroupFilter *filter.Filter
+
+	// ProtectedResources is what happens to a Resource Group which contains resources with the `DoNotDelete` tag
+	ProtectedResources ProtectedResources
+
+	// Allowlist is the Tenants and Subscriptions which can be processed
+	Allowlist allowlist.Allowlist
+
+	// SafetyLimits bound how many objects can be deleted, unless OverrideSafetyLimits is set
+	SafetyLimits         safety.Limits
+	OverrideSafetyLimits bool
+
+	// PurgeOnly limits the run to purging objects which have already been soft-deleted
+	PurgeOnly bool
 }
 
 func (o Options) String() string {
+	resourceGroupFilter := "<none>"
+	if o.ResourceGroupFilter != nil {
+		resourceGroupFilter = o.ResourceGroupFilter.String()
+	}
 	components := []string{
 		fmt.Sprintf("Prefix %q", o.Prefix),
+		fmt.Sprintf("Subscription IDs %q", o.SubscriptionIDs),
+		fmt.Sprintf("All Subscriptions %t", o.AllSubscriptions),
+		fmt.Sprintf("Management Group ID %q", o.ManagementGroupID),
 		fmt.Sprintf("Number RGs to Delete %d", o.NumberOfResourceGroupsToDelete),
 		fmt.Sprintf("Actually Delete %t", o.ActuallyDelete),
+		fmt.Sprintf("Parallelism %d", o.Parallelism),
+		fmt.Sprintf("Wait For Deletion %t", o.WaitForDeletion),
+		fmt.Sprintf("Wait For Deletion Timeout %s", o.WaitForDeletionTimeout),
+		fmt.Sprintf("Report File %q", o.ReportFile),
+		fmt.Sprintf("Resume %t", o.Resume),
+		fmt.Sprintf("State File %q", o.StateFile),
+		fmt.Sprintf("Metrics File %q", o.MetricsFile),
+		fmt.Sprintf("Metrics Pushgateway URL %q", o.MetricsPushgatewayURL),
+		fmt.Sprintf("Cleaners %q", o.Cleaners),
+		fmt.Sprintf("Skip Cleaners %q", o.SkipCleaners),
+		fmt.Sprintf("Timeout %s", o.Timeout),
+		fmt.Sprintf("Minimum Age %s", o.MinimumAge),
+		fmt.Sprintf("Expires On Tag %q", o.ExpiresOnTag),
+		fmt.Sprintf("TTL Tag %q", o.TTLTag),
+		fmt.Sprintf("Resource Group Filter %s", resourceGroupFilter),
+		fmt.Sprintf("Protected Resources %q", o.ProtectedResources),
+		fmt.Sprintf("Allowlist %s", o.Allowlist),
+		fmt.Sprintf("Safety Limits %s", o.SafetyLimits),
+		fmt.Sprintf("Override Safety Limits %t", o.OverrideSafetyLimits),
+		fmt.Sprintf("Purge Only %t", o.PurgeOnly),
 	}
 	return strings.Join(components, "\n")
 }
+
+// Fingerprint returns a hash of the options which determine what a run processes - so that progress recorded by a run
+// is only resumed by a run using the same options. Those which only affect how a run behaves (such as the parallelism
+// or where the report is written) aren't included.
+func (o Options) Fingerprint() string {
+	subscriptionIds := make([]string, 0, len(o.SubscriptionIDs))
+	for _, v := range o.SubscriptionIDs {
+		subscriptionIds = append(subscriptionIds, strings.ToLower(v))
+	}
+	slices.Sort(subscriptionIds)
+
+	resourceGroupFilter := ""
+	if o.ResourceGroupFilter != nil {
+		resourceGroupFilter = o.ResourceGroupFilter.String()
+	}
+
+	components := []string{
+		fmt.Sprintf("prefix=%q", o.Prefix),
+		fmt.Sprintf("subscriptionIds=%q", subscriptionIds),
+		fmt.Sprintf("allSubscriptions=%t", o.AllSubscriptions),
+		fmt.Sprintf("managementGroupId=%q", o.ManagementGroupID),
+		fmt.Sprintf("numberOfResourceGroupsToDelete=%d", o.NumberOfResourceGroupsToDelete),
+		fmt.Sprintf("cleaners=%q", o.Cleaners),
+		fmt.Sprintf("skipCleaners=%q", o.SkipCleaners),
+		fmt.Sprintf("minimumAge=%s", o.MinimumAge),
+		fmt.Sprintf("expiresOnTag=%q", o.ExpiresOnTag),
+		fmt.Sprintf("ttlTag=%q", o.TTLTag),
+		fmt.Sprintf("resourceGroupFilter=%q", resourceGroupFilter),
+		fmt.Sprintf("protectedResources=%q", o.ProtectedResources),
+		fmt.Sprintf("purgeOnly=%t", o.PurgeOnly),
+		fmt.Sprintf("allowlist=%q", o.Allowlist.String()),
+	}
+	hash := sha256.Sum256([]byte(strings.Join(components, "\n")))
+	return hex.EncodeToString(hash[:])
+}
+
+// IsCleanerEnabled returns whether the named Cleaner should be run - that is when it's not skipped, and either no
+// Cleaners were specified or it's one of those which were
+func (o Options) IsCleanerEnabled(name string) bool {
+	matches := func(v string) bool {
+		return strings.EqualFold(v, name)
+	}
+	if slices.ContainsFunc(o.SkipCleaners, matches) {
+		return false
+	}
+	return len(o.Cleaners) == 0 || slices.ContainsFunc(o.Cleaners, matches)
+}
@@ -0,0 +1,261 @@
+package plan
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackofallops/azurerm-dalek/dalek/options"
+	"github.com/jackofallops/azurerm-dalek/dalek/report"
+)
+
+// SchemaVersion is incremented whenever the format of the Plan changes in an incompatible way
+const SchemaVersion = 1
+
+// Item is an object which the Plan intends to delete (or purge)
+type Item struct {
+	Kind report.Kind `json:"kind"`
+
+	// ID is the Resource ID (or for Microsoft Graph, the Object ID) of the object
+	ID string `json:"id"`
+
+	// Name is the name (or for Microsoft Graph, the display name) of the object
+	Name string `json:"name"`
+
+	// Scope is the Subscription ID or Tenant ID within which the object was found
+	Scope string `json:"scope"`
+
+	// Attributes are the properties of the object (such as its Tags) which must be unchanged for it to be deleted
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+func (i Item) key() string {
+	return fmt.Sprintf("%s|%s", i.Kind, strings.ToLower(i.ID))
+}
+
+// Plan is the reviewable list of objects which a subsequent apply will delete
+type Plan struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	Options       options.Options `json:"options"`
+	Items         []Item          `json:"items"`
+}
+
+// Load reads and validates the Plan at the specified path
+func Load(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading the plan from %q: %+v", path, err)
+	}
+
+	var p Plan
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("parsing the plan from %q: %+v", path, err)
+	}
+
+	if p.SchemaVersion != SchemaVersion {
+		return nil, fmt.Errorf("the plan %q uses schema version %d but only version %d is supported", path, p.SchemaVersion, SchemaVersion)
+	}
+
+	return &p, nil
+}
+
+// WriteFile writes the Plan as JSON to the specified path, with the items sorted so that the output is stable
+func (p *Plan) WriteFile(path string) error {
+	sort.SliceStable(p.Items, func(i, j int) bool {
+		a, b := p.Items[i], p.Items[j]
+		if a.Scope != b.Scope {
+			return a.Scope < b.Scope
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.ID < b.ID
+	})
+
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshalling the plan: %+v", err)
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing the plan to %q: %+v", path, err)
+	}
+
+	return nil
+}
+
+// SubscriptionIDs returns the IDs of the Subscriptions containing the planned Resource Manager objects
+func (p *Plan) SubscriptionIDs() []string {
+	ids := make([]string, 0)
+	for _, item := range p.Items {
+		if item.Kind.IsTenantScoped() || slices.Contains(ids, item.Scope) {
+			continue
+		}
+		ids = append(ids, item.Scope)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Gate decides whether each object which would be deleted can be - when creating a Plan every object is recorded into
+// the Plan, and when applying a Plan only those objects which are in it and are unchanged can be deleted.
+type Gate struct {
+	recording bool
+	plan      *Plan
+	planned   map[string]Item
+	visited   map[string]struct{}
+
+	mu sync.Mutex
+}
+
+// NewRecorder returns a Gate which records every object checked into a new Plan created with the specified Options
+func NewRecorder(opts options.Options) *Gate {
+	return &Gate{
+		recording: true,
+		plan: &Plan{
+			SchemaVersion: SchemaVersion,
+			CreatedAt:     time.Now().UTC(),
+			Options:       opts,
+			Items:         make([]Item, 0),
+		},
+	}
+}
+
+// NewApplier returns a Gate which only allows the objects within the specified Plan to be deleted
+func NewApplier(p *Plan) *Gate {
+	planned := make(map[string]Item, len(p.Items))
+	for _, item := range p.Items {
+		planned[item.key()] = item
+	}
+
+	return &Gate{
+		plan:    p,
+		planned: planned,
+		visited: make(map[string]struct{}),
+	}
+}
+
+// Check returns an error when the object can't be deleted because it's either not in the Plan being applied, or it
+// has changed since the Plan was created. When recording a Plan the object is added to it. This is a no-op on a nil
+// Gate, so that callers needn't check whether a Plan is being recorded or applied.
+func (g *Gate) Check(item Item) error {
+	if g == nil {
+		return nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.recording {
+		g.plan.Items = append(g.plan.Items, item)
+		return nil
+	}
+
+	planned, ok := g.planned[item.key()]
+	if !ok {
+		return fmt.Errorf("%s %q isn't in the plan", item.Kind, item.ID)
+	}
+	g.visited[item.key()] = struct{}{}
+
+	changes := make([]string, 0)
+	if planned.Name != item.Name {
+		changes = append(changes, fmt.Sprintf("the name changed from %q to %q", planned.Name, item.Name))
+	}
+	for _, key := range slices.Sorted(maps.Keys(planned.Attributes)) {
+		value, exists := item.Attributes[key]
+		if !exists {
+			changes = append(changes, fmt.Sprintf("%q was removed", key))
+			continue
+		}
+		if value != planned.Attributes[key] {
+			changes = append(changes, fmt.Sprintf("%q changed from %q to %q", key, planned.Attributes[key], value))
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(item.Attributes)) {
+		if _, exists := planned.Attributes[key]; !exists {
+			changes = append(changes, fmt.Sprintf("%q was added", key))
+		}
+	}
+	if len(changes) > 0 {
+		return fmt.Errorf("%s %q has changed since the plan was created: %s", item.Kind, item.ID, strings.Join(changes, ", "))
+	}
+
+	return nil
+}
+
+// Count returns the number of objects which count towards the safety limits, given the IDs of every object of the
+// specified kind which was listed and the number of these which matched. When applying a Plan this is the number of
+// those listed which are within the Plan - since these are what will be deleted - and otherwise the number which
+// matched. Unlike Check this doesn't mark the objects as visited.
+func (g *Gate) Count(kind report.Kind, ids []string, matched int) int {
+	if g == nil || g.recording {
+		return matched
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	count := 0
+	for _, id := range ids {
+		if _, ok := g.planned[Item{Kind: kind, ID: id}.key()]; ok {
+			count++
+		}
+	}
+	return count
+}
+
+// Plan returns the Plan being recorded or applied
+func (g *Gate) Plan() *Plan {
+	return g.plan
+}
+
+// Unvisited returns the planned items which were never checked whilst applying the Plan, for example because
+// these no longer exist
+func (g *Gate) Unvisited() []Item {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	output := make([]Item, 0)
+	if g.recording {
+		return output
+	}
+	for _, item := range g.plan.Items {
+		if _, ok := g.visited[item.key()]; !ok {
+			output = append(output, item)
+		}
+	}
+	return output
+}
+
+// TagAttributes returns the Attributes representing the specified Tags
+func TagAttributes(tags *map[string]string) map[string]string {
+	output := make(map[string]string)
+	if tags == nil {
+		return output
+	}
+	for k, v := range *tags {
+		output[fmt.Sprintf("tags.%s", k)] = v
+	}
+	return output
+}
+
+type gateContextKey struct{}
+
+// WithGate returns a copy of ctx which carries the specified Gate
+func WithGate(ctx context.Context, gate *Gate) context.Context {
+	return context.WithValue(ctx, gateContextKey{}, gate)
+}
+
+// FromContext returns the Gate carried by ctx, or nil when ctx doesn't carry one
+func FromContext(ctx context.Context) *Gate {
+	gate, _ := ctx.Value(gateContextKey{}).(*Gate)
+	return gate
+}
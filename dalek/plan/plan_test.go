@@ -0,0 +1,187 @@
+package plan
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jackofallops/azurerm-dalek/dalek/options"
+	"github.com/jackofallops/azurerm-dalek/dalek/report"
+)
+
+const resourceGroupId = "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/acctest-1"
+
+func resourceGroup(name string, attributes map[string]string) Item {
+	return Item{
+		Kind:       report.KindResourceGroup,
+		ID:         resourceGroupId,
+		Name:       name,
+		Scope:      "00000000-0000-0000-0000-000000000000",
+		Attributes: attributes,
+	}
+}
+
+func TestApplierCheck(t *testing.T) {
+	planned := resourceGroup("acctest-1", map[string]string{
+		"createdTime": "2026-01-01T00:00:00Z",
+		"tags.owner":  "alice",
+	})
+
+	testData := []struct {
+		name string
+		item Item
+
+		// expected is a substring of the expected error, or empty when no error is expected
+		expected string
+	}{
+		{
+			name: "unchanged",
+			item: planned,
+		},
+		{
+			name: "the ID is compared case-insensitively",
+			item: func() Item {
+				item := planned
+				item.ID = strings.ToUpper(item.ID)
+				return item
+			}(),
+		},
+		{
+			name:     "not in the plan",
+			item:     Item{Kind: report.KindResourceGroup, ID: resourceGroupId + "-other", Name: "acctest-1-other"},
+			expected: "isn't in the plan",
+		},
+		{
+			name: "a different kind isn't in the plan",
+			item: func() Item {
+				item := planned
+				item.Kind = report.KindNetAppAccount
+				return item
+			}(),
+			expected: "isn't in the plan",
+		},
+		{
+			name:     "renamed",
+			item:     resourceGroup("acctest-2", planned.Attributes),
+			expected: `the name changed from "acctest-1" to "acctest-2"`,
+		},
+		{
+			name: "recreated",
+			item: resourceGroup("acctest-1", map[string]string{
+				"createdTime": "2026-01-02T00:00:00Z",
+				"tags.owner":  "alice",
+			}),
+			expected: `"createdTime" changed from "2026-01-01T00:00:00Z" to "2026-01-02T00:00:00Z"`,
+		},
+		{
+			name: "the created time is no longer known",
+			item: resourceGroup("acctest-1", map[string]string{
+				"tags.owner": "alice",
+			}),
+			expected: `"createdTime" was removed`,
+		},
+		{
+			name: "tag added and changed",
+			item: resourceGroup("acctest-1", map[string]string{
+				"createdTime":      "2026-01-01T00:00:00Z",
+				"tags.owner":       "bob",
+				"tags.DoNotDelete": "",
+			}),
+			expected: `"tags.owner" changed from "alice" to "bob", "tags.DoNotDelete" was added`,
+		},
+	}
+
+	for _, v := range testData {
+		t.Run(v.name, func(t *testing.T) {
+			gate := NewApplier(&Plan{SchemaVersion: SchemaVersion, Items: []Item{planned}})
+			err := gate.Check(v.item)
+			if v.expected == "" {
+				if err != nil {
+					t.Fatalf("expected no error but got: %+v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected an error containing %q but got none", v.expected)
+			}
+			if !strings.Contains(err.Error(), v.expected) {
+				t.Fatalf("expected an error containing %q but got: %+v", v.expected, err)
+			}
+		})
+	}
+}
+
+func TestApplierUnvisited(t *testing.T) {
+	first := resourceGroup("acctest-1", nil)
+	second := first
+	second.ID, second.Name = resourceGroupId+"-2", "acctest-1-2"
+
+	gate := NewApplier(&Plan{SchemaVersion: SchemaVersion, Items: []Item{first, second}})
+	// a changed item has still been visited, since it exists
+	_ = gate.Check(resourceGroup("renamed", nil))
+
+	unvisited := gate.Unvisited()
+	if len(unvisited) != 1 || unvisited[0].ID != second.ID {
+		t.Fatalf("expected only %q to be unvisited but got %+v", second.ID, unvisited)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	gate := NewRecorder(options.Options{Prefix: "acctest"})
+	item := resourceGroup("acctest-1", map[string]string{"createdTime": ""})
+	if err := gate.Check(item); err != nil {
+		t.Fatalf("expected no error whilst recording but got: %+v", err)
+	}
+	if unvisited := gate.Unvisited(); len(unvisited) != 0 {
+		t.Fatalf("expected nothing to be unvisited whilst recording but got %+v", unvisited)
+	}
+
+	path := filepath.Join(t.TempDir(), "plan.json")
+	if err := gate.Plan().WriteFile(path); err != nil {
+		t.Fatalf("writing the plan: %+v", err)
+	}
+	p, err := Load(path)
+	if err != nil {
+		t.Fatalf("loading the plan: %+v", err)
+	}
+	if p.Options.Prefix != "acctest" {
+		t.Fatalf("expected the prefix %q but got %q", "acctest", p.Options.Prefix)
+	}
+	if len(p.Items) != 1 {
+		t.Fatalf("expected 1 item but got %d", len(p.Items))
+	}
+	if err := NewApplier(p).Check(item); err != nil {
+		t.Fatalf("expected the recorded item to be unchanged but got: %+v", err)
+	}
+}
+
+func TestNilGate(t *testing.T) {
+	var gate *Gate
+	if err := gate.Check(resourceGroup("acctest-1", nil)); err != nil {
+		t.Fatalf("expected no error but got: %+v", err)
+	}
+}
+
+func TestCount(t *testing.T) {
+	planned := resourceGroup("acctest-1", nil)
+	listed := []string{strings.ToUpper(resourceGroupId), resourceGroupId + "-2", resourceGroupId + "-3"}
+
+	var nilGate *Gate
+	if actual := nilGate.Count(report.KindResourceGroup, listed, 2); actual != 2 {
+		t.Fatalf("expected a nil Gate to count those which matched (2) but got %d", actual)
+	}
+	if actual := NewRecorder(options.Options{}).Count(report.KindResourceGroup, listed, 2); actual != 2 {
+		t.Fatalf("expected a recording Gate to count those which matched (2) but got %d", actual)
+	}
+
+	gate := NewApplier(&Plan{SchemaVersion: SchemaVersion, Items: []Item{planned}})
+	if actual := gate.Count(report.KindResourceGroup, listed, 3); actual != 1 {
+		t.Fatalf("expected an applying Gate to count those which are planned (1) but got %d", actual)
+	}
+	if actual := gate.Count(report.KindManagementGroup, listed, 3); actual != 0 {
+		t.Fatalf("expected an applying Gate to only count planned items of the same kind but got %d", actual)
+	}
+	if unvisited := gate.Unvisited(); len(unvisited) != 1 {
+		t.Fatalf("expected counting not to visit the planned items but got %+v", unvisited)
+	}
+}
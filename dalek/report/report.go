@@ -0,0 +1,224 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SchemaVersion is incremented whenever a field is removed or its meaning changes, new fields can be added without
+// changing this
+const SchemaVersion = 1
+
+type Kind string
+
+const (
+	KindApplication              Kind = "Application"
+	KindDeletedManagedHSM        Kind = "DeletedManagedHSM"
+	KindGroup                    Kind = "Group"
+	KindMachineLearningWorkspace Kind = "MachineLearningWorkspace"
+	KindManagementGroup          Kind = "ManagementGroup"
+	KindNetAppAccount            Kind = "NetAppAccount"
+	KindNewRelicMonitor          Kind = "NewRelicMonitor"
+	KindRecoveryServicesVault    Kind = "RecoveryServicesVault"
+	KindResourceGroup            Kind = "ResourceGroup"
+	KindServicePrincipal         Kind = "ServicePrincipal"
+	KindStorageSyncService       Kind = "StorageSyncService"
+	KindUser                     Kind = "User"
+)
+
+// IsTenantScoped returns whether objects of this Kind exist within a Tenant, rather than within a Subscription
+func (k Kind) IsTenantScoped() bool {
+	switch k {
+	case KindApplication, KindGroup, KindManagementGroup, KindServicePrincipal, KindUser:
+		return true
+	}
+	return false
+}
+
+type Action string
+
+const (
+	// ActionDeleted means the object was deleted
+	ActionDeleted Action = "Deleted"
+
+	// ActionDeletionTriggered means the deletion of the object was accepted but wasn't waited on
+	ActionDeletionTriggered Action = "DeletionTriggered"
+
+	// ActionFailed means the object should have been deleted or purged, but this failed
+	ActionFailed Action = "Failed"
+
+	// ActionPartiallyDeleted means the object contained protected resources, so only the other resources within it
+	// were deleted
+	ActionPartiallyDeleted Action = "PartiallyDeleted"
+
+	// ActionPurged means the (already soft-deleted) object was purged
+	ActionPurged Action = "Purged"
+
+	// ActionSkipped means that the object was excluded by a rule
+	ActionSkipped Action = "Skipped"
+
+	// ActionWouldDelete means the object matched and would have been deleted, but deletion isn't enabled
+	ActionWouldDelete Action = "WouldDelete"
+
+	// ActionWouldPartiallyDelete means the object contained protected resources, so only the other resources within it
+	// would have been deleted, but deletion isn't enabled
+	ActionWouldPartiallyDelete Action = "WouldPartiallyDelete"
+
+	// ActionWouldPurge means the object matched and would have been purged, but deletion isn't enabled
+	ActionWouldPurge Action = "WouldPurge"
+)
+
+type Entry struct {
+	// ID is the Resource ID (or for Microsoft Graph, the Object ID) of the object
+	ID string `json:"id"`
+
+	// Name is the name (or for Microsoft Graph, the display name) of the object
+	Name string `json:"name"`
+
+	Kind Kind `json:"kind"`
+
+	// Scope is the Subscription ID or Tenant ID within which the object was found
+	Scope string `json:"scope"`
+
+	// Rule describes the rule which matched (or excluded) the object
+	Rule string `json:"rule,omitempty"`
+
+	// Cleaners are the names of the Cleaners which were run against this object prior to deletion
+	Cleaners []string `json:"cleaners,omitempty"`
+
+	// CleanerActions are the changes which the Cleaners made (or would have made) to resources within this object
+	// prior to its deletion
+	CleanerActions []CleanerAction `json:"cleanerActions,omitempty"`
+
+	Action Action `json:"action"`
+
+	// DurationSeconds is how long the action took
+	DurationSeconds float64 `json:"durationSeconds"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// CleanerAction is a change which a Cleaner made (or would have made) to a resource within an object, such as removing
+// a Lock from a Resource Group
+type CleanerAction struct {
+	Cleaner     string `json:"cleaner"`
+	Description string `json:"description"`
+
+	// ID is the Resource ID of the resource which was changed
+	ID string `json:"id"`
+
+	// Performed is whether the change was made, rather than this being a dry-run
+	Performed bool `json:"performed"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// Report collects an Entry for each object considered during a run, so that these can be written out at the end
+type Report struct {
+	SchemaVersion  int       `json:"schemaVersion"`
+	StartedAt      time.Time `json:"startedAt"`
+	FinishedAt     time.Time `json:"finishedAt"`
+	ActuallyDelete bool      `json:"actuallyDelete"`
+	Entries        []Entry   `json:"entries"`
+	Errors         []string  `json:"errors"`
+
+	mu sync.Mutex
+}
+
+func New(actuallyDelete bool) *Report {
+	return &Report{
+		SchemaVersion:  SchemaVersion,
+		StartedAt:      time.Now().UTC(),
+		ActuallyDelete: actuallyDelete,
+		Entries:        make([]Entry, 0),
+		Errors:         make([]string, 0),
+	}
+}
+
+// Record adds the Entry to the Report - this is a no-op on a nil Report so that callers needn't check whether a Report
+// is being collected
+func (r *Report) Record(entry Entry) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Entries = append(r.Entries, entry)
+}
+
+// Finish marks the run as completed, recording the errors returned from the run
+func (r *Report) Finish(errs ...error) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.FinishedAt = time.Now().UTC()
+	for _, err := range errs {
+		if err != nil {
+			r.Errors = append(r.Errors, err.Error())
+		}
+	}
+}
+
+// SortedEntries returns the Entries sorted by their Scope, Kind and then ID
+func (r *Report) SortedEntries() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sortEntries()
+	return slices.Clone(r.Entries)
+}
+
+// WriteFile writes the Report as JSON to the specified path, with the entries sorted so that the output is stable
+func (r *Report) WriteFile(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sortEntries()
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshalling the report: %+v", err)
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing the report to %q: %+v", path, err)
+	}
+
+	return nil
+}
+
+func (r *Report) sortEntries() {
+	sort.SliceStable(r.Entries, func(i, j int) bool {
+		a, b := r.Entries[i], r.Entries[j]
+		if a.Scope != b.Scope {
+			return a.Scope < b.Scope
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.ID < b.ID
+	})
+}
+
+type reportContextKey struct{}
+
+// WithReport returns a copy of ctx which carries the specified Report
+func WithReport(ctx context.Context, report *Report) context.Context {
+	return context.WithValue(ctx, reportContextKey{}, report)
+}
+
+// FromContext returns the Report carried by ctx, or nil when ctx doesn't carry one
+func FromContext(ctx context.Context) *Report {
+	report, _ := ctx.Value(reportContextKey{}).(*Report)
+	return report
+}
@@ -3,20 +3,187 @@ package dalek
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/hashicorp/go-azure-helpers/resourcemanager/commonids"
+	"github.com/hashicorp/go-azure-sdk/resource-manager/management/2023-04-01/managementgroups"
+	"github.com/jackofallops/azurerm-dalek/dalek/checkpoint"
 	"github.com/jackofallops/azurerm-dalek/dalek/cleaners"
+	"github.com/jackofallops/azurerm-dalek/dalek/logging"
+	"github.com/jackofallops/azurerm-dalek/dalek/metrics"
+	"github.com/jackofallops/azurerm-dalek/dalek/safety"
 )
 
 func (d *Dalek) ResourceManager(ctx context.Context) (errors []error) {
-	subscriptionId := commonids.NewSubscriptionID(d.client.SubscriptionID)
-	for _, cleaner := range cleaners.SubscriptionCleaners {
-		log.Printf("[DEBUG] Running Subscription Cleaner %q in %q", cleaner.Name(), subscriptionId)
-		if err := cleaner.Cleanup(ctx, subscriptionId, d.client, d.opts); err != nil {
-			errors = append(errors, fmt.Errorf("running Subscription Cleaner %q in %q: %+v", cleaner.Name(), subscriptionId, err))
+	stages, err := cleaners.OrderedSubscriptionCleaners()
+	if err != nil {
+		return []error{fmt.Errorf("determining the order to run the Subscription Cleaners in: %+v", err)}
+	}
+
+	subscriptionIds, err := d.subscriptionIds(ctx)
+	if err != nil {
+		return []error{fmt.Errorf("determining the Subscriptions to process: %+v", err)}
+	}
+
+	logger := logging.FromContext(ctx)
+	progress := checkpoint.FromContext(ctx)
+	results := make(map[string][]error, len(subscriptionIds))
+	for i, subscriptionId := range subscriptionIds {
+		subscriptionCtx := logging.WithAttrs(ctx, logging.SubscriptionID(subscriptionId.SubscriptionId))
+		subscriptionLogger := logging.FromContext(subscriptionCtx)
+		if progress.IsComplete(subscriptionId.ID()) {
+			subscriptionLogger.Debug("Skipping Subscription since it was completed by a previous run")
+			continue
+		}
+
+		subscriptionLogger.Info("Processing Subscription", slog.Int("index", i+1), slog.Int("total", len(subscriptionIds)))
+		subscriptionErrors := d.cleanSubscription(subscriptionCtx, subscriptionId, stages)
+		if len(subscriptionErrors) == 0 {
+			if err := progress.Complete(subscriptionId.ID()); err != nil {
+				subscriptionErrors = append(subscriptionErrors, fmt.Errorf("recording progress: %+v", err))
+			}
+		}
+		results[subscriptionId.SubscriptionId] = subscriptionErrors
+	}
+
+	for _, subscriptionId := range subscriptionIds {
+		subscriptionErrors := results[subscriptionId.SubscriptionId]
+		if len(subscriptionErrors) == 0 {
+			logger.Info("Processed Subscription", logging.SubscriptionID(subscriptionId.SubscriptionId))
+			continue
+		}
+
+		logger.Error("Processed Subscription with errors", logging.SubscriptionID(subscriptionId.SubscriptionId), slog.Int("errors", len(subscriptionErrors)))
+		for _, err := range subscriptionErrors {
+			errors = append(errors, fmt.Errorf("processing Subscription %q: %+v", subscriptionId.SubscriptionId, err))
 		}
 	}
 
 	return
 }
+
+func (d *Dalek) cleanSubscription(ctx context.Context, subscriptionId commonids.SubscriptionId, stages [][]cleaners.SubscriptionCleaner) (errors []error) {
+	progress := checkpoint.FromContext(ctx)
+	for _, stage := range stages {
+		// the Cleaners within a stage don't depend on one another, so can be run in parallel
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		for _, cleaner := range stage {
+			cleanerCtx := logging.WithAttrs(ctx, logging.Cleaner(cleaner.Name()))
+			if !d.opts.IsCleanerEnabled(cleaner.Name()) || (d.opts.PurgeOnly && !cleaners.PurgesSoftDeletedItems(cleaner)) {
+				logging.FromContext(cleanerCtx).Debug("Skipping Subscription Cleaner since it isn't enabled")
+				continue
+			}
+			if safety.FromContext(ctx).CountOnly() && !cleaners.ChecksSafetyLimits(cleaner) {
+				logging.FromContext(cleanerCtx).Debug("Skipping Subscription Cleaner since only the safety limits are being checked")
+				continue
+			}
+			if progress.IsComplete(subscriptionId.ID(), cleaner.Name()) {
+				logging.FromContext(cleanerCtx).Debug("Skipping Subscription Cleaner since it was completed by a previous run")
+				continue
+			}
+
+			wg.Go(func() {
+				logging.FromContext(cleanerCtx).Debug("Running Subscription Cleaner")
+				err := cleaner.Cleanup(cleanerCtx, subscriptionId, d.clients.ForSubscription(subscriptionId.SubscriptionId), d.opts)
+				metrics.FromContext(ctx).ObserveCleaner(cleaner.Name(), err)
+				if err == nil {
+					err = progress.Complete(subscriptionId.ID(), cleaner.Name())
+				}
+				if err != nil {
+					mu.Lock()
+					errors = append(errors, fmt.Errorf("running Subscription Cleaner %q in %q: %+v", cleaner.Name(), subscriptionId, err))
+					mu.Unlock()
+				}
+			})
+		}
+		wg.Wait()
+	}
+
+	return
+}
+
+// subscriptionIds returns the Subscriptions which should be processed - either every enabled Subscription visible to
+// the principal, the Subscriptions specified in the Options and/or those within the specified Management Group, or
+// otherwise the Subscription the client was built for.
+func (d *Dalek) subscriptionIds(ctx context.Context) ([]commonids.SubscriptionId, error) {
+	ids := make([]string, 0)
+	switch {
+	case d.opts.AllSubscriptions:
+		subscriptions, err := d.client.ResourceManager.SubscriptionsClient.ListComplete(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing Subscriptions: %+v", err)
+		}
+		for _, subscription := range subscriptions {
+			if subscription.SubscriptionId == nil {
+				continue
+			}
+			if subscription.State != nil && !strings.EqualFold(*subscription.State, "Enabled") {
+				logging.FromContext(ctx).Debug("Skipping Subscription since it isn't enabled", logging.SubscriptionID(*subscription.SubscriptionId), slog.String("state", *subscription.State))
+				continue
+			}
+			ids = append(ids, *subscription.SubscriptionId)
+		}
+		sort.Strings(ids)
+
+	case len(d.opts.SubscriptionIDs) > 0 || d.opts.ManagementGroupID != "":
+		ids = append(ids, d.opts.SubscriptionIDs...)
+		if d.opts.ManagementGroupID != "" {
+			descendants, err := d.subscriptionIdsWithinManagementGroup(ctx, d.opts.ManagementGroupID)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, descendants...)
+		}
+
+	default:
+		ids = append(ids, d.client.SubscriptionID)
+	}
+
+	output := make([]commonids.SubscriptionId, 0, len(ids))
+	seen := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		key := strings.ToLower(id)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		output = append(output, commonids.NewSubscriptionID(id))
+	}
+
+	if len(output) == 0 {
+		return nil, fmt.Errorf("no Subscriptions were found to process")
+	}
+
+	return output, nil
+}
+
+// subscriptionIdsWithinManagementGroup returns the IDs of every Subscription beneath the specified Management Group,
+// including those within any nested Management Groups.
+func (d *Dalek) subscriptionIdsWithinManagementGroup(ctx context.Context, managementGroupName string) ([]string, error) {
+	id := commonids.NewManagementGroupID(managementGroupName)
+	logging.FromContext(ctx).Debug("Finding the Subscriptions within the Management Group", logging.ResourceID(id.ID()))
+
+	// the Descendants API returns every Management Group and Subscription within the hierarchy, not only the direct children
+	descendants, err := d.clients.ManagementGroups().ResourceManager.ManagementClient.GetDescendantsComplete(ctx, id, managementgroups.DefaultGetDescendantsOperationOptions())
+	if err != nil {
+		return nil, fmt.Errorf("listing the descendants of %s: %+v", id, err)
+	}
+
+	ids := make([]string, 0)
+	for _, descendant := range descendants.Items {
+		if descendant.Name == nil || descendant.Type == nil {
+			continue
+		}
+		if !strings.EqualFold(*descendant.Type, string(managementgroups.ManagementGroupChildTypeSubscriptions)) {
+			continue
+		}
+		ids = append(ids, *descendant.Name)
+	}
+	logging.FromContext(ctx).Info("Found the Subscriptions within the Management Group", logging.ResourceID(id.ID()), slog.Int("count", len(ids)))
+
+	return ids, nil
+}
@@ -0,0 +1,195 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackofallops/azurerm-dalek/dalek/logging"
+)
+
+// Operation performs a single attempt at a request, returning the HTTP Response (where one was received) so that
+// the error can be classified and any `Retry-After` header honoured.
+type Operation func(ctx context.Context) (*http.Response, error)
+
+type Policy struct {
+	// MaxAttempts is the maximum number of times the Operation is attempted
+	MaxAttempts int
+
+	// InitialDelay is the delay before the first retry, which is doubled for each subsequent retry
+	InitialDelay time.Duration
+
+	// MaxDelay is the upper bound on the delay between attempts
+	MaxDelay time.Duration
+
+	// RetryNotFound specifies that a 404 should be treated as transient, which is the case when the
+	// resource was only just created or listed and isn't yet consistently visible
+	RetryNotFound bool
+
+	// TransientErrorCodes are additional error codes which should be treated as transient for this operation,
+	// for example where a resource can't be deleted until an asynchronous operation on its children completes
+	TransientErrorCodes []string
+}
+
+// DefaultPolicy is the Policy used by Do
+var DefaultPolicy = Policy{
+	MaxAttempts:  6,
+	InitialDelay: 10 * time.Second,
+	MaxDelay:     5 * time.Minute,
+}
+
+// Do performs the Operation using the DefaultPolicy
+func Do(ctx context.Context, description string, operation Operation) error {
+	return DefaultPolicy.Do(ctx, description, operation)
+}
+
+// Do performs the Operation, retrying with a jittered exponential backoff whilst it fails with a transient error
+// until it either succeeds, fails with a permanent error, or the maximum number of attempts is reached.
+func (p Policy) Do(ctx context.Context, description string, operation Operation) error {
+	maxAttempts := max(p.MaxAttempts, 1)
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		var resp *http.Response
+		resp, err = operation(ctx)
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= maxAttempts || !p.isTransient(resp, err) {
+			break
+		}
+
+		delay := p.delay(attempt, resp)
+		logging.FromContext(ctx).Warn("Retrying after a transient error", slog.String("operation", description), slog.Duration("delay", delay.Round(time.Second)), slog.Int("attempt", attempt+1), slog.Int("max_attempts", maxAttempts), logging.Err(err))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%s: %w (%v)", description, ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+
+	return err
+}
+
+func (p Policy) isTransient(resp *http.Response, err error) bool {
+	for _, code := range p.TransientErrorCodes {
+		if strings.Contains(err.Error(), code) {
+			return true
+		}
+	}
+
+	return IsTransient(resp, err, p.RetryNotFound)
+}
+
+// delay returns how long to wait before the next attempt - preferring the `Retry-After` header if one was returned
+func (p Policy) delay(attempt int, resp *http.Response) time.Duration {
+	if retryAfter, ok := retryAfter(resp); ok {
+		return min(retryAfter, p.MaxDelay)
+	}
+
+	backoff := p.InitialDelay << (attempt - 1)
+	if backoff <= 0 || backoff > p.MaxDelay {
+		backoff = p.MaxDelay
+	}
+
+	// apply "equal jitter" so that the operations retried at the same time don't all retry in lockstep
+	half := backoff / 2
+	return half + rand.N(half+1)
+}
+
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+
+	value := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+
+	return 0, false
+}
+
+var statusCodeRegex = regexp.MustCompile(`unexpected status (\d{3})`)
+
+// connectionErrors are fragments of the errors returned when no response was received, since the SDK
+// formats (rather than wraps) the underlying errors these can only be detected from the error message
+var connectionErrors = []string{
+	"connection refused",
+	"connection reset",
+	"EOF",
+	"i/o timeout",
+	"TLS handshake timeout",
+}
+
+// IsTransient determines whether the error returned from an ARM or Microsoft Graph operation is transient
+// (and thus worth retrying) or permanent.
+func IsTransient(resp *http.Response, err error, retryNotFound bool) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	message := err.Error()
+
+	// this can be returned either as a 409 or as the error from a long-running operation
+	if strings.Contains(message, "AnotherOperationInProgress") {
+		return true
+	}
+
+	statusCode := 0
+	if resp != nil {
+		statusCode = resp.StatusCode
+	} else if matches := statusCodeRegex.FindStringSubmatch(message); len(matches) == 2 {
+		// the `...ThenPoll` methods don't expose the response, but the SDK includes the status code in the error
+		statusCode, _ = strconv.Atoi(matches[1])
+	}
+
+	switch {
+	case statusCode == 0:
+		var netErr net.Error
+		if errors.As(err, &netErr) {
+			return true
+		}
+		for _, v := range connectionErrors {
+			if strings.Contains(message, v) {
+				return true
+			}
+		}
+		return false
+
+	case statusCode == http.StatusTooManyRequests:
+		return true
+
+	case statusCode == http.StatusNotFound:
+		return retryNotFound
+
+	case statusCode >= 500 && statusCode != http.StatusNotImplemented && statusCode != http.StatusHTTPVersionNotSupported:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,195 @@
+package safety
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/jackofallops/azurerm-dalek/dalek/logging"
+)
+
+// ErrLimitExceeded is returned when deleting the matched objects would exceed one of the Limits
+var ErrLimitExceeded = errors.New("the safety limits were exceeded")
+
+// Limits bound how many objects a single run can delete, so that a mistake such as an empty prefix can't delete
+// everything. A zero value disables that limit.
+type Limits struct {
+	// MaxResourceGroups and MaxResourceGroupsPercent limit the number of Resource Groups deleted in each Subscription
+	MaxResourceGroups        int64
+	MaxResourceGroupsPercent float64
+
+	// MaxGraphObjects limits the number of Microsoft Graph objects of each kind which are deleted or purged. There's
+	// no percentage since these are listed using the prefix, so the total number of objects isn't known.
+	MaxGraphObjects int64
+
+	MaxManagementGroups        int64
+	MaxManagementGroupsPercent float64
+}
+
+// Enabled returns whether any of the Limits are specified
+func (l Limits) Enabled() bool {
+	return l.MaxResourceGroups > 0 || l.MaxResourceGroupsPercent > 0 || l.MaxGraphObjects > 0 || l.MaxManagementGroups > 0 || l.MaxManagementGroupsPercent > 0
+}
+
+// Validate returns an error when any of the Limits are out of range
+func (l Limits) Validate() error {
+	if l.MaxResourceGroups < 0 || l.MaxGraphObjects < 0 || l.MaxManagementGroups < 0 {
+		return fmt.Errorf("the maximum number of objects to delete can't be negative")
+	}
+	for _, v := range []float64{l.MaxResourceGroupsPercent, l.MaxManagementGroupsPercent} {
+		if v < 0 || v > 100 {
+			return fmt.Errorf("the maximum percentage of objects to delete must be between 0 and 100 but got %g", v)
+		}
+	}
+	return nil
+}
+
+func (l Limits) String() string {
+	return fmt.Sprintf("Resource Groups %d / %g%%, Graph Objects %d, Management Groups %d / %g%%", l.MaxResourceGroups, l.MaxResourceGroupsPercent, l.MaxGraphObjects, l.MaxManagementGroups, l.MaxManagementGroupsPercent)
+}
+
+// Violation describes a set of objects which exceeded one of the Limits
+type Violation struct {
+	// Description describes the objects, e.g. "Resource Groups within Subscription 00000000-..."
+	Description string
+	Matched     int
+	Total       int
+	Limit       string
+}
+
+func (v Violation) String() string {
+	if v.Total > 0 {
+		return fmt.Sprintf("%d of the %d %s (%.1f%%) matched, which exceeds the limit of %s", v.Matched, v.Total, v.Description, percentage(v.Matched, v.Total), v.Limit)
+	}
+	return fmt.Sprintf("%d %s matched, which exceeds the limit of %s", v.Matched, v.Description, v.Limit)
+}
+
+// Guard checks the number of objects which are about to be deleted against the Limits, recording any Violations
+type Guard struct {
+	limits     Limits
+	override   bool
+	countOnly  bool
+	violations []Violation
+
+	mu sync.Mutex
+}
+
+// NewGuard returns a Guard for the specified Limits - when override is set Violations are logged but don't prevent
+// the objects from being deleted
+func NewGuard(limits Limits, override bool) *Guard {
+	return &Guard{
+		limits:     limits,
+		override:   override,
+		violations: make([]Violation, 0),
+	}
+}
+
+// NewPreflightGuard returns a Guard for the specified Limits which is only used to count the objects which would be
+// deleted - once each set of objects has been checked nothing more needs to be done with them, see CountOnly
+func NewPreflightGuard(limits Limits) *Guard {
+	guard := NewGuard(limits, false)
+	guard.countOnly = true
+	return guard
+}
+
+// CountOnly returns whether the Guard is only counting the objects which would be deleted, in which case callers
+// should stop once the Limits have been checked - rather than running the Cleaners, checking the plan or deleting
+// anything. This is false for a nil Guard.
+func (g *Guard) CountOnly() bool {
+	return g != nil && g.countOnly
+}
+
+// CheckResourceGroups returns an error wrapping ErrLimitExceeded when deleting the matched Resource Groups within the
+// Subscription would exceed the Limits. This is a no-op on a nil Guard, as are the other checks.
+func (g *Guard) CheckResourceGroups(ctx context.Context, subscriptionId string, matched, total int) error {
+	if g == nil {
+		return nil
+	}
+	return g.check(ctx, fmt.Sprintf("Resource Groups within Subscription %q", subscriptionId), matched, total, g.limits.MaxResourceGroups, g.limits.MaxResourceGroupsPercent)
+}
+
+// CheckGraphObjects returns an error wrapping ErrLimitExceeded when deleting (or purging) the matched Microsoft Graph
+// objects of the specified kind would exceed the Limits
+func (g *Guard) CheckGraphObjects(ctx context.Context, kind string, matched int) error {
+	if g == nil {
+		return nil
+	}
+	return g.check(ctx, kind, matched, 0, g.limits.MaxGraphObjects, 0)
+}
+
+// CheckManagementGroups returns an error wrapping ErrLimitExceeded when deleting the matched Management Groups would
+// exceed the Limits
+func (g *Guard) CheckManagementGroups(ctx context.Context, matched, total int) error {
+	if g == nil {
+		return nil
+	}
+	return g.check(ctx, "Management Groups", matched, total, g.limits.MaxManagementGroups, g.limits.MaxManagementGroupsPercent)
+}
+
+func (g *Guard) check(ctx context.Context, description string, matched, total int, maxCount int64, maxPercent float64) error {
+	limits := make([]string, 0)
+	if maxCount > 0 && int64(matched) > maxCount {
+		limits = append(limits, fmt.Sprintf("%d", maxCount))
+	}
+	if maxPercent > 0 && total > 0 && percentage(matched, total) > maxPercent {
+		limits = append(limits, fmt.Sprintf("%g%%", maxPercent))
+	}
+	if len(limits) == 0 {
+		return nil
+	}
+
+	violation := Violation{
+		Description: description,
+		Matched:     matched,
+		Total:       total,
+		Limit:       strings.Join(limits, " and "),
+	}
+	if g.override {
+		logging.FromContext(ctx).Warn("Deleting regardless of exceeding the safety limits since these were overridden", slog.String("violation", violation.String()))
+		return nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.violations = append(g.violations, violation)
+	return fmt.Errorf("%w: %s", ErrLimitExceeded, violation)
+}
+
+// Err returns an error summarising every Violation recorded, or nil when there weren't any
+func (g *Guard) Err() error {
+	if g == nil {
+		return nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.violations) == 0 {
+		return nil
+	}
+
+	lines := make([]string, 0, len(g.violations))
+	for _, v := range g.violations {
+		lines = append(lines, fmt.Sprintf("  - %s", v))
+	}
+	return fmt.Errorf("%w, so these objects weren't deleted (specify `-override-safety-limits` to delete these regardless):\n%s", ErrLimitExceeded, strings.Join(lines, "\n"))
+}
+
+func percentage(matched, total int) float64 {
+	return float64(matched) / float64(total) * 100
+}
+
+type guardContextKey struct{}
+
+// WithGuard returns a copy of ctx which carries the specified Guard
+func WithGuard(ctx context.Context, guard *Guard) context.Context {
+	return context.WithValue(ctx, guardContextKey{}, guard)
+}
+
+// FromContext returns the Guard carried by ctx, or nil when ctx doesn't carry one
+func FromContext(ctx context.Context) *Guard {
+	guard, _ := ctx.Value(guardContextKey{}).(*Guard)
+	return guard
+}
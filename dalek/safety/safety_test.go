@@ -0,0 +1,224 @@
+package safety
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckResourceGroups(t *testing.T) {
+	testData := []struct {
+		name    string
+		limits  Limits
+		matched int
+		total   int
+
+		// expected is a substring of the expected error, or empty when no error is expected
+		expected string
+	}{
+		{
+			name:    "no limits",
+			limits:  Limits{},
+			matched: 1000,
+			total:   1000,
+		},
+		{
+			name:    "at the count",
+			limits:  Limits{MaxResourceGroups: 10},
+			matched: 10,
+			total:   100,
+		},
+		{
+			name:     "over the count",
+			limits:   Limits{MaxResourceGroups: 10},
+			matched:  11,
+			total:    100,
+			expected: "11 of the 100 Resource Groups within Subscription \"sub\" (11.0%) matched, which exceeds the limit of 10",
+		},
+		{
+			name:    "at the percentage",
+			limits:  Limits{MaxResourceGroupsPercent: 50},
+			matched: 5,
+			total:   10,
+		},
+		{
+			name:     "over the percentage",
+			limits:   Limits{MaxResourceGroupsPercent: 50},
+			matched:  2,
+			total:    3,
+			expected: "(66.7%) matched, which exceeds the limit of 50%",
+		},
+		{
+			name:     "over both",
+			limits:   Limits{MaxResourceGroups: 1, MaxResourceGroupsPercent: 50},
+			matched:  3,
+			total:    3,
+			expected: "which exceeds the limit of 1 and 50%",
+		},
+		{
+			name:    "the percentage is ignored when there's nothing to delete",
+			limits:  Limits{MaxResourceGroupsPercent: 50},
+			matched: 0,
+			total:   0,
+		},
+		{
+			name:    "other limits don't apply",
+			limits:  Limits{MaxGraphObjects: 1, MaxManagementGroups: 1, MaxManagementGroupsPercent: 1},
+			matched: 10,
+			total:   10,
+		},
+	}
+
+	for _, v := range testData {
+		t.Run(v.name, func(t *testing.T) {
+			err := NewGuard(v.limits, false).CheckResourceGroups(context.Background(), "sub", v.matched, v.total)
+			if v.expected == "" {
+				if err != nil {
+					t.Fatalf("expected no error but got: %+v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrLimitExceeded) {
+				t.Fatalf("expected an error wrapping ErrLimitExceeded but got: %+v", err)
+			}
+			if !strings.Contains(err.Error(), v.expected) {
+				t.Fatalf("expected an error containing %q but got: %+v", v.expected, err)
+			}
+		})
+	}
+}
+
+func TestCheckGraphObjects(t *testing.T) {
+	guard := NewGuard(Limits{MaxGraphObjects: 2, MaxResourceGroupsPercent: 1}, false)
+
+	if err := guard.CheckGraphObjects(context.Background(), "Applications", 2); err != nil {
+		t.Fatalf("expected no error at the limit but got: %+v", err)
+	}
+	err := guard.CheckGraphObjects(context.Background(), "Applications", 3)
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("expected an error wrapping ErrLimitExceeded but got: %+v", err)
+	}
+	if expected := "3 Applications matched, which exceeds the limit of 2"; !strings.Contains(err.Error(), expected) {
+		t.Fatalf("expected an error containing %q but got: %+v", expected, err)
+	}
+}
+
+func TestOverride(t *testing.T) {
+	guard := NewGuard(Limits{MaxResourceGroups: 1, MaxManagementGroupsPercent: 10}, true)
+
+	if err := guard.CheckResourceGroups(context.Background(), "sub", 5, 5); err != nil {
+		t.Fatalf("expected no error when overridden but got: %+v", err)
+	}
+	if err := guard.CheckManagementGroups(context.Background(), 5, 5); err != nil {
+		t.Fatalf("expected no error when overridden but got: %+v", err)
+	}
+	if err := guard.Err(); err != nil {
+		t.Fatalf("expected no violations to be recorded when overridden but got: %+v", err)
+	}
+}
+
+func TestErr(t *testing.T) {
+	guard := NewGuard(Limits{MaxResourceGroups: 1, MaxGraphObjects: 1}, false)
+	if err := guard.Err(); err != nil {
+		t.Fatalf("expected no error before anything's checked but got: %+v", err)
+	}
+
+	_ = guard.CheckResourceGroups(context.Background(), "first", 2, 2)
+	_ = guard.CheckResourceGroups(context.Background(), "second", 1, 2)
+	_ = guard.CheckGraphObjects(context.Background(), "Groups", 3)
+
+	err := guard.Err()
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("expected an error wrapping ErrLimitExceeded but got: %+v", err)
+	}
+	lines := strings.Split(err.Error(), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected a summary and 2 violations but got %d lines: %q", len(lines), lines)
+	}
+	if !strings.Contains(lines[0], "-override-safety-limits") {
+		t.Fatalf("expected the summary to mention `-override-safety-limits` but got %q", lines[0])
+	}
+	if !strings.Contains(lines[1], `Subscription "first"`) {
+		t.Fatalf("expected the first violation to be for the Subscription \"first\" but got %q", lines[1])
+	}
+	if !strings.Contains(lines[2], "3 Groups matched") {
+		t.Fatalf("expected the second violation to be for the Groups but got %q", lines[2])
+	}
+}
+
+func TestNilGuard(t *testing.T) {
+	guard := FromContext(context.Background())
+	if guard != nil {
+		t.Fatalf("expected no Guard but got %+v", guard)
+	}
+
+	if err := guard.CheckResourceGroups(context.Background(), "sub", 10, 10); err != nil {
+		t.Fatalf("expected no error but got: %+v", err)
+	}
+	if err := guard.CheckGraphObjects(context.Background(), "Users", 10); err != nil {
+		t.Fatalf("expected no error but got: %+v", err)
+	}
+	if err := guard.CheckManagementGroups(context.Background(), 10, 10); err != nil {
+		t.Fatalf("expected no error but got: %+v", err)
+	}
+	if err := guard.Err(); err != nil {
+		t.Fatalf("expected no error but got: %+v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	testData := []struct {
+		name   string
+		limits Limits
+		valid  bool
+	}{
+		{
+			name:  "zero",
+			valid: true,
+		},
+		{
+			name:   "percentages at the bounds",
+			limits: Limits{MaxResourceGroupsPercent: 100, MaxManagementGroupsPercent: 0},
+			valid:  true,
+		},
+		{
+			name:   "negative count",
+			limits: Limits{MaxGraphObjects: -1},
+		},
+		{
+			name:   "percentage over 100",
+			limits: Limits{MaxManagementGroupsPercent: 100.5},
+		},
+		{
+			name:   "negative percentage",
+			limits: Limits{MaxResourceGroupsPercent: -1},
+		},
+	}
+
+	for _, v := range testData {
+		t.Run(v.name, func(t *testing.T) {
+			if err := v.limits.Validate(); (err == nil) != v.valid {
+				t.Fatalf("expected valid to be %t but got: %+v", v.valid, err)
+			}
+		})
+	}
+}
+
+func TestCountOnly(t *testing.T) {
+	var guard *Guard
+	if guard.CountOnly() {
+		t.Fatalf("expected a nil Guard not to be count-only")
+	}
+	if NewGuard(Limits{}, false).CountOnly() {
+		t.Fatalf("expected a Guard not to be count-only")
+	}
+
+	guard = NewPreflightGuard(Limits{MaxResourceGroups: 1})
+	if !guard.CountOnly() {
+		t.Fatalf("expected a preflight Guard to be count-only")
+	}
+	if err := guard.CheckResourceGroups(context.Background(), "sub", 2, 2); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("expected a preflight Guard to enforce the limits but got: %+v", err)
+	}
+}
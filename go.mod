@@ -8,6 +8,8 @@ require (
 	github.com/hashicorp/go-azure-sdk/resource-manager v0.20260619.1225202
 	github.com/hashicorp/go-azure-sdk/sdk v0.20260619.1225202
 	github.com/hashicorp/go-uuid v1.0.3
+	golang.org/x/oauth2 v0.30.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -46,7 +48,6 @@ require (
 	github.com/zclconf/go-cty v1.17.0 // indirect
 	golang.org/x/crypto v0.47.0 // indirect
 	golang.org/x/mod v0.31.0 // indirect
-	golang.org/x/oauth2 v0.30.0 // indirect
 	golang.org/x/sync v0.19.0 // indirect
 	golang.org/x/sys v0.40.0 // indirect
 	golang.org/x/text v0.33.0 // indirect
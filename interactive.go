@@ -0,0 +1,412 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jackofallops/azurerm-dalek/clients"
+	"github.com/jackofallops/azurerm-dalek/dalek/logging"
+	"github.com/jackofallops/azurerm-dalek/dalek/options"
+	"github.com/jackofallops/azurerm-dalek/dalek/plan"
+	"github.com/jackofallops/azurerm-dalek/dalek/report"
+)
+
+// interactiveGroups is the order the kinds of object are confirmed in, along with how each group is described. The
+// soft-deleted objects of every kind are grouped together at the end, since purging these can't be undone.
+var interactiveGroups = []interactiveKind{
+	{kind: report.KindResourceGroup, label: "Resource Groups"},
+	{kind: report.KindNetAppAccount, label: "NetApp Accounts"},
+	{kind: report.KindNewRelicMonitor, label: "New Relic Monitors"},
+	{kind: report.KindRecoveryServicesVault, label: "Recovery Services Vaults"},
+	{kind: report.KindStorageSyncService, label: "Storage Sync Services"},
+	{kind: report.KindApplication, label: "Microsoft Graph Applications"},
+	{kind: report.KindServicePrincipal, label: "Microsoft Graph Service Principals"},
+	{kind: report.KindGroup, label: "Microsoft Graph Groups"},
+	{kind: report.KindUser, label: "Microsoft Graph Users"},
+	{kind: report.KindManagementGroup, label: "Management Groups"},
+}
+
+type interactiveKind struct {
+	kind  report.Kind
+	label string
+}
+
+const softDeletedGroupLabel = "Soft-Deleted Objects"
+
+type interactiveEntry struct {
+	number   int
+	item     plan.Item
+	selected bool
+}
+
+type interactiveGroup struct {
+	label   string
+	entries []*interactiveEntry
+}
+
+// requireTerminal returns an error when stdin isn't a terminal, so that interactive mode can't be confirmed by
+// whatever happens to be piped in
+func requireTerminal(f *os.File) error {
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("determining whether stdin is a terminal: %+v", err)
+	}
+	if info.Mode()&os.ModeCharDevice == 0 {
+		return fmt.Errorf("refusing to run interactively since stdin isn't a terminal")
+	}
+	return nil
+}
+
+// runInteractive performs a dry-run to find the objects which match the filter, then deletes only those which are
+// confirmed at the prompt. Each phase has its own timeout, since there's no telling how long the review will take.
+func runInteractive(credentials clients.Credentials, opts options.Options) error {
+	dryRunOpts := opts
+	dryRunOpts.ActuallyDelete = false
+	dryRunOpts.ReportFile = ""
+	dryRunOpts.MetricsFile = ""
+	dryRunOpts.MetricsPushgatewayURL = ""
+
+	listCtx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+
+	slog.Info("Finding the objects which match the filter")
+	recorder := plan.NewRecorder(dryRunOpts)
+	rep, listErr := run(plan.WithGate(listCtx, recorder), credentials, dryRunOpts)
+	if listErr != nil {
+		slog.Warn("Not every object could be listed, so only those which were are shown", logging.Err(listErr))
+	}
+
+	groups := groupForReview(recorder.Plan().Items, rep)
+	if len(groups) == 0 {
+		slog.Info("Nothing matches the filter, so there's nothing to delete")
+		return listErr
+	}
+
+	p := &prompter{
+		in:  bufio.NewReader(os.Stdin),
+		out: os.Stdout,
+	}
+	confirmed, err := p.review(groups)
+	if err != nil {
+		return errors.Join(listErr, err)
+	}
+	if !confirmed {
+		slog.Info("Nothing was confirmed, so nothing has been deleted")
+		return listErr
+	}
+
+	selected := &plan.Plan{
+		SchemaVersion: plan.SchemaVersion,
+		CreatedAt:     time.Now().UTC(),
+		Options:       opts,
+		Items:         make([]plan.Item, 0),
+	}
+	for _, group := range groups {
+		for _, entry := range group.entries {
+			if entry.selected {
+				selected.Items = append(selected.Items, entry.item)
+			}
+		}
+	}
+
+	// the confirmed objects are deleted in the same way as applying a plan - so any which have changed since these
+	// were listed are skipped
+	applyOpts := opts
+	applyOpts.ActuallyDelete = true
+	applyOpts.SubscriptionIDs = selected.SubscriptionIDs()
+	applyOpts.AllSubscriptions = false
+	applyOpts.ManagementGroupID = ""
+	applyOpts.NumberOfResourceGroupsToDelete = 0
+
+	applyCtx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+
+	slog.Info("Deleting the confirmed objects", slog.Int("items", len(selected.Items)))
+	applier := plan.NewApplier(selected)
+	_, err = run(plan.WithGate(applyCtx, applier), credentials, applyOpts)
+
+	for _, item := range applier.Unvisited() {
+		slog.Warn("A confirmed object was not found", slog.String("kind", string(item.Kind)), logging.ResourceID(item.ID))
+	}
+
+	return errors.Join(listErr, err)
+}
+
+// groupForReview groups the planned items by kind, numbering each so that these can be deselected - the Report is
+// used to determine which of the items have already been soft-deleted
+func groupForReview(items []plan.Item, rep *report.Report) []interactiveGroup {
+	purging := make(map[string]struct{})
+	for _, entry := range rep.SortedEntries() {
+		if entry.Action == report.ActionWouldPurge {
+			purging[fmt.Sprintf("%s|%s", entry.Kind, strings.ToLower(entry.ID))] = struct{}{}
+		}
+	}
+	isSoftDeleted := func(item plan.Item) bool {
+		// these kinds are only ever listed once soft-deleted
+		if item.Kind == report.KindDeletedManagedHSM || item.Kind == report.KindMachineLearningWorkspace {
+			return true
+		}
+		_, ok := purging[fmt.Sprintf("%s|%s", item.Kind, strings.ToLower(item.ID))]
+		return ok
+	}
+
+	sorted := slices.Clone(items)
+	slices.SortStableFunc(sorted, func(a, b plan.Item) int {
+		if c := strings.Compare(a.Scope, b.Scope); c != 0 {
+			return c
+		}
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+
+	groups := make([]interactiveGroup, 0)
+	number := 0
+	add := func(label string, include func(plan.Item) bool) {
+		group := interactiveGroup{
+			label:   label,
+			entries: make([]*interactiveEntry, 0),
+		}
+		for _, item := range sorted {
+			if !include(item) {
+				continue
+			}
+			number++
+			group.entries = append(group.entries, &interactiveEntry{
+				number:   number,
+				item:     item,
+				selected: true,
+			})
+		}
+		if len(group.entries) > 0 {
+			groups = append(groups, group)
+		}
+	}
+
+	for _, g := range interactiveGroups {
+		add(g.label, func(item plan.Item) bool {
+			return item.Kind == g.kind && !isSoftDeleted(item)
+		})
+	}
+	add(softDeletedGroupLabel, isSoftDeleted)
+
+	return groups
+}
+
+// prompter asks for confirmation of the objects to delete
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// review lists every group and then asks whether to delete everything, or to review each group in turn. It returns
+// whether anything remains selected and was confirmed for deletion.
+func (p *prompter) review(groups []interactiveGroup) (bool, error) {
+	all := make([]*interactiveEntry, 0)
+	for _, group := range groups {
+		p.printGroup(group)
+		all = append(all, group.entries...)
+	}
+
+	for {
+		answer, err := p.ask(fmt.Sprintf("Delete the %d selected objects listed above? [y]es, [n]o, [d]eselect entries, [r]eview each group", countSelected(all)), "y", "n", "d", "r")
+		if err != nil {
+			return false, err
+		}
+		switch answer {
+		case "y":
+			return countSelected(all) > 0, nil
+		case "n":
+			return false, nil
+		case "d":
+			if err := p.deselect(all); err != nil {
+				return false, err
+			}
+			for _, group := range groups {
+				p.printGroup(group)
+			}
+		case "r":
+			return p.reviewEachGroup(groups)
+		}
+	}
+}
+
+func (p *prompter) reviewEachGroup(groups []interactiveGroup) (bool, error) {
+	for _, group := range groups {
+		p.printGroup(group)
+
+	prompt:
+		for {
+			verb := "Delete"
+			if group.label == softDeletedGroupLabel {
+				verb = "Purge"
+			}
+			answer, err := p.ask(fmt.Sprintf("%s the %d selected %s? [y]es, [n]o, [d]eselect entries", verb, countSelected(group.entries), group.label), "y", "n", "d")
+			if err != nil {
+				return false, err
+			}
+			switch answer {
+			case "y":
+				break prompt
+			case "n":
+				for _, entry := range group.entries {
+					entry.selected = false
+				}
+				break prompt
+			case "d":
+				if err := p.deselect(group.entries); err != nil {
+					return false, err
+				}
+				p.printGroup(group)
+			}
+		}
+	}
+
+	total := 0
+	for _, group := range groups {
+		total += countSelected(group.entries)
+	}
+	if total == 0 {
+		return false, nil
+	}
+
+	answer, err := p.ask(fmt.Sprintf("Delete the %d objects confirmed above? [y]es, [n]o", total), "y", "n")
+	if err != nil {
+		return false, err
+	}
+	return answer == "y", nil
+}
+
+// deselect asks for the numbers of the entries which shouldn't be deleted, asking again until the input is valid
+func (p *prompter) deselect(entries []*interactiveEntry) error {
+	numbers := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		numbers = append(numbers, entry.number)
+	}
+
+	for {
+		fmt.Fprint(p.out, "Entries to deselect (e.g. 1,3-5): ")
+		line, err := p.readLine()
+		if err != nil {
+			return err
+		}
+		deselected, err := parseSelection(line, numbers)
+		if err != nil {
+			fmt.Fprintf(p.out, "%+v\n", err)
+			continue
+		}
+		for _, entry := range entries {
+			if slices.Contains(deselected, entry.number) {
+				entry.selected = false
+			}
+		}
+		return nil
+	}
+}
+
+// ask asks the question until one of the choices is answered, returning that choice
+func (p *prompter) ask(question string, choices ...string) (string, error) {
+	for {
+		fmt.Fprintf(p.out, "%s: ", question)
+		line, err := p.readLine()
+		if err != nil {
+			return "", err
+		}
+		answer := strings.ToLower(line)
+		for _, choice := range choices {
+			if answer == choice || answer == choiceWords[choice] {
+				return choice, nil
+			}
+		}
+		fmt.Fprintf(p.out, "Please answer one of %s\n", strings.Join(choices, ", "))
+	}
+}
+
+// choiceWords are the words which can be answered in place of each choice
+var choiceWords = map[string]string{
+	"y": "yes",
+	"n": "no",
+	"d": "deselect",
+	"r": "review",
+}
+
+// readLine reads the next line of input - the end of the input is an error, rather than being taken as an answer
+func (p *prompter) readLine() (string, error) {
+	line, err := p.in.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		if errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("no answer was given, so nothing has been deleted")
+		}
+		return "", fmt.Errorf("reading the answer: %+v", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+func (p *prompter) printGroup(group interactiveGroup) {
+	fmt.Fprintf(p.out, "\n%s (%d of %d selected):\n", group.label, countSelected(group.entries), len(group.entries))
+	if group.label == softDeletedGroupLabel {
+		fmt.Fprintln(p.out, "  These will be purged, which can't be undone.")
+	}
+
+	w := tabwriter.NewWriter(p.out, 0, 4, 2, ' ', 0)
+	for _, entry := range group.entries {
+		marker := "x"
+		if !entry.selected {
+			marker = " "
+		}
+		kind := ""
+		if group.label == softDeletedGroupLabel {
+			kind = string(entry.item.Kind)
+		}
+		fmt.Fprintf(w, "  [%s] %d\t%s\t%s\t%s\t%s\n", marker, entry.number, kind, entry.item.Scope, entry.item.Name, entry.item.ID)
+	}
+	_ = w.Flush()
+}
+
+func countSelected(entries []*interactiveEntry) int {
+	count := 0
+	for _, entry := range entries {
+		if entry.selected {
+			count++
+		}
+	}
+	return count
+}
+
+// parseSelection parses a list of numbers and ranges such as "1,3-5", each of which must be one of the allowed numbers
+func parseSelection(input string, allowed []int) ([]int, error) {
+	output := make([]int, 0)
+	for _, v := range strings.FieldsFunc(input, func(r rune) bool {
+		return r == ',' || r == ' '
+	}) {
+		from, to, isRange := strings.Cut(v, "-")
+		start, err := strconv.Atoi(from)
+		if err != nil {
+			return nil, fmt.Errorf("%q isn't a number or a range", v)
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(to); err != nil || end < start {
+				return nil, fmt.Errorf("%q isn't a number or a range", v)
+			}
+		}
+		for i := start; i <= end; i++ {
+			if !slices.Contains(allowed, i) {
+				return nil, fmt.Errorf("%d isn't one of the entries listed", i)
+			}
+			output = append(output, i)
+		}
+	}
+	if len(output) == 0 {
+		return nil, fmt.Errorf("no entries were specified")
+	}
+	return output, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSelection(t *testing.T) {
+	allowed := []int{1, 2, 3, 4, 5, 7}
+
+	testData := []struct {
+		input    string
+		expected []int
+
+		// err is a substring of the expected error, or empty when no error is expected
+		err string
+	}{
+		{
+			input:    "1",
+			expected: []int{1},
+		},
+		{
+			input:    "1,3",
+			expected: []int{1, 3},
+		},
+		{
+			input:    " 5 1, 2 ",
+			expected: []int{5, 1, 2},
+		},
+		{
+			input:    "2-4",
+			expected: []int{2, 3, 4},
+		},
+		{
+			input:    "1,3-5,7",
+			expected: []int{1, 3, 4, 5, 7},
+		},
+		{
+			input:    "3-3",
+			expected: []int{3},
+		},
+		{
+			input: "5-3",
+			err:   `"5-3" isn't a number or a range`,
+		},
+		{
+			input: "1-",
+			err:   `"1-" isn't a number or a range`,
+		},
+		{
+			input: "-1",
+			err:   `"-1" isn't a number or a range`,
+		},
+		{
+			input: "all",
+			err:   `"all" isn't a number or a range`,
+		},
+		{
+			input: "6",
+			err:   "6 isn't one of the entries listed",
+		},
+		{
+			input: "5-7",
+			err:   "6 isn't one of the entries listed",
+		},
+		{
+			input: "0",
+			err:   "0 isn't one of the entries listed",
+		},
+		{
+			input: "",
+			err:   "no entries were specified",
+		},
+		{
+			input: " , ",
+			err:   "no entries were specified",
+		},
+	}
+
+	for _, v := range testData {
+		t.Run(v.input, func(t *testing.T) {
+			actual, err := parseSelection(v.input, allowed)
+			if v.err != "" {
+				if err == nil {
+					t.Fatalf("expected an error containing %q but got %v", v.err, actual)
+				}
+				if !strings.Contains(err.Error(), v.err) {
+					t.Fatalf("expected an error containing %q but got: %+v", v.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error but got: %+v", err)
+			}
+			if !reflect.DeepEqual(actual, v.expected) {
+				t.Fatalf("expected %v but got %v", v.expected, actual)
+			}
+		})
+	}
+}
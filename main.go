@@ -1,66 +1,441 @@
 package main
 
 import (
-	"context"
 	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/hashicorp/go-azure-helpers/lang/pointer"
+	"github.com/hashicorp/go-uuid"
 	"github.com/jackofallops/azurerm-dalek/clients"
-	"github.com/jackofallops/azurerm-dalek/dalek"
+	"github.com/jackofallops/azurerm-dalek/dalek/cleaners"
+	"github.com/jackofallops/azurerm-dalek/dalek/config"
+	"github.com/jackofallops/azurerm-dalek/dalek/logging"
 	"github.com/jackofallops/azurerm-dalek/dalek/options"
+	"github.com/jackofallops/azurerm-dalek/dalek/safety"
 )
 
+// defaultCommand is run when no command is specified, for compatibility with the flags-only CLI
+const defaultCommand = "delete"
+
+type command struct {
+	name        string
+	description string
+	run         func(fs *flag.FlagSet, args []string) error
+}
+
+var commands = []command{
+	{name: "list", description: "List the objects which match the filter, without deleting anything.", run: runList},
+	{name: "plan", description: "Write the objects which match the filter to a plan, which can be reviewed and then applied.", run: runPlan},
+	{name: "apply", description: "Delete only the objects within a plan, providing these haven't changed since it was created.", run: runApply},
+	{name: "delete", description: "Delete the objects which match the filter, running the Cleaners first (the default).", run: runDelete},
+	{name: "purge", description: "Only purge the objects which have already been soft-deleted, such as deleted Microsoft Graph objects.", run: runPurge},
+	{name: "cleaners", description: "List the registered Cleaners and the Resource Types each handles.", run: runCleaners},
+}
+
 func main() {
-	log.Print("Starting Azure Dalek..")
+	name, args := defaultCommand, os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
 
-	prefix := flag.String("prefix", "acctest", "-prefix=acctest")
-	flag.Parse()
+	if name == "help" {
+		usage(os.Stdout)
+		return
+	}
 
-	credentials := clients.Credentials{
-		ClientID:        os.Getenv("ARM_CLIENT_ID"),
-		ClientSecret:    os.Getenv("ARM_CLIENT_SECRET"),
-		SubscriptionID:  os.Getenv("ARM_SUBSCRIPTION_ID"),
-		TenantID:        os.Getenv("ARM_TENANT_ID"),
-		EnvironmentName: os.Getenv("ARM_ENVIRONMENT"),
-		Endpoint:        os.Getenv("ARM_ENDPOINT"),
+	index := slices.IndexFunc(commands, func(c command) bool {
+		return c.name == name
+	})
+	if index == -1 {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		usage(os.Stderr)
+		os.Exit(2)
 	}
-	opts := options.Options{
-		ActuallyDelete:                 strings.EqualFold(os.Getenv("YES_I_REALLY_WANT_TO_DELETE_THINGS"), "true"),
-		NumberOfResourceGroupsToDelete: int64(1000),
-		Prefix:                         *prefix,
-	}
-	ctx, cancel := context.WithTimeout(context.Background(), 6*time.Hour)
-	defer cancel()
-	if err := run(ctx, credentials, opts); err != nil {
-		log.Print(err.Error())
+
+	cmd := commands[index]
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s [flags]\n\n%s\n\nFlags:\n", binaryName(), cmd.name, cmd.description)
+		fs.PrintDefaults()
+	}
+
+	if err := cmd.run(fs, args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		slog.Error("Azure Dalek failed", logging.Err(err))
 		os.Exit(1) // nolint gocritic
 	}
 }
 
-func run(ctx context.Context, credentials clients.Credentials, opts options.Options) error {
-	sdkClient, err := clients.BuildAzureClient(ctx, credentials)
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s <command> [flags]\n\nCommands:\n", binaryName())
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", c.name, c.description)
+	}
+	fmt.Fprintf(w, "\nRun `%s <command> -help` for the flags each command supports.\n", binaryName())
+}
+
+func binaryName() string {
+	return filepath.Base(os.Args[0])
+}
+
+// runFlags holds the flags used by the commands which process Subscriptions and Microsoft Graph. Each command only
+// registers the groups of flags which are relevant to it, the rest retain their defaults.
+type runFlags struct {
+	configFile string
+	logLevel   string
+	logFormat  string
+	timeout    time.Duration
+
+	prefix             string
+	subscriptions      string
+	allSubscriptions   bool
+	managementGroup    string
+	maxResourceGroups  int64
+	cleaners           string
+	skipCleaners       string
+	minAge             time.Duration
+	expiresOnTag       string
+	ttlTag             string
+	protectedResources string
+
+	safetyLimits         safety.Limits
+	overrideSafetyLimits bool
+
+	parallelism            int
+	waitForDeletion        bool
+	waitForDeletionTimeout time.Duration
+	resume                 bool
+	stateFile              string
+
+	reportFile            string
+	metricsFile           string
+	metricsPushgatewayURL string
+}
+
+func newRunFlags() *runFlags {
+	return &runFlags{
+		logLevel:               "info",
+		logFormat:              "text",
+		timeout:                6 * time.Hour,
+		prefix:                 "acctest",
+		expiresOnTag:           "expiresOn",
+		ttlTag:                 "ttl",
+		protectedResources:     string(options.ProtectedResourcesSkip),
+		maxResourceGroups:      1000,
+		parallelism:            10,
+		waitForDeletionTimeout: 1 * time.Hour,
+		stateFile:              "dalek-state.json",
+		safetyLimits: safety.Limits{
+			MaxResourceGroups:          500,
+			MaxResourceGroupsPercent:   90,
+			MaxGraphObjects:            500,
+			MaxManagementGroups:        50,
+			MaxManagementGroupsPercent: 90,
+		},
+	}
+}
+
+// registerCommon registers the flags used by every command which processes Subscriptions and Microsoft Graph
+func (f *runFlags) registerCommon(fs *flag.FlagSet) {
+	fs.StringVar(&f.configFile, "config", f.configFile, "-config=dalek.yaml")
+	fs.StringVar(&f.logLevel, "log-level", f.logLevel, "-log-level=debug|info|warn|error")
+	fs.StringVar(&f.logFormat, "log-format", f.logFormat, "-log-format=text|json")
+	fs.DurationVar(&f.timeout, "timeout", f.timeout, "-timeout=6h")
+	fs.IntVar(&f.parallelism, "parallelism", f.parallelism, "-parallelism=10")
+}
+
+// registerFilter registers the flags which determine which objects are processed
+func (f *runFlags) registerFilter(fs *flag.FlagSet) {
+	fs.StringVar(&f.prefix, "prefix", f.prefix, "-prefix=acctest")
+	fs.StringVar(&f.subscriptions, "subscriptions", f.subscriptions, "-subscriptions=00000000-0000-0000-0000-000000000000,11111111-1111-1111-1111-111111111111")
+	fs.BoolVar(&f.allSubscriptions, "all-subscriptions", f.allSubscriptions, "-all-subscriptions")
+	fs.StringVar(&f.managementGroup, "management-group", f.managementGroup, "-management-group=sandbox")
+	fs.Int64Var(&f.maxResourceGroups, "max-resource-groups", f.maxResourceGroups, "-max-resource-groups=1000")
+	fs.StringVar(&f.cleaners, "cleaners", f.cleaners, "-cleaners=\"Removing Locks..,Delete Resource Groups in Subscription\"")
+	fs.StringVar(&f.skipCleaners, "skip-cleaners", f.skipCleaners, "-skip-cleaners=\"Removing Net App\"")
+	fs.DurationVar(&f.minAge, "min-age", f.minAge, "-min-age=6h")
+	fs.StringVar(&f.expiresOnTag, "expires-on-tag", f.expiresOnTag, "-expires-on-tag=expiresOn")
+	fs.StringVar(&f.ttlTag, "ttl-tag", f.ttlTag, "-ttl-tag=ttl")
+	fs.StringVar(&f.protectedResources, "protected-resources", f.protectedResources, "-protected-resources=skip|delete-unprotected (for Resource Groups containing resources tagged DoNotDelete)")
+}
+
+// registerSafety registers the flags which limit how many objects can be deleted
+func (f *runFlags) registerSafety(fs *flag.FlagSet) {
+	fs.Int64Var(&f.safetyLimits.MaxResourceGroups, "safety-max-resource-groups", f.safetyLimits.MaxResourceGroups, "-safety-max-resource-groups=500 (refuse to delete anything when more Resource Groups than this match in a Subscription, 0 disables this)")
+	fs.Float64Var(&f.safetyLimits.MaxResourceGroupsPercent, "safety-max-resource-groups-percent", f.safetyLimits.MaxResourceGroupsPercent, "-safety-max-resource-groups-percent=90")
+	fs.Int64Var(&f.safetyLimits.MaxGraphObjects, "safety-max-graph-objects", f.safetyLimits.MaxGraphObjects, "-safety-max-graph-objects=500")
+	fs.Int64Var(&f.safetyLimits.MaxManagementGroups, "safety-max-management-groups", f.safetyLimits.MaxManagementGroups, "-safety-max-management-groups=50")
+	fs.Float64Var(&f.safetyLimits.MaxManagementGroupsPercent, "safety-max-management-groups-percent", f.safetyLimits.MaxManagementGroupsPercent, "-safety-max-management-groups-percent=90")
+	fs.BoolVar(&f.overrideSafetyLimits, "override-safety-limits", f.overrideSafetyLimits, "-override-safety-limits (delete the matching objects even when these exceed the safety limits)")
+}
+
+// registerExecution registers the flags which determine how the objects are deleted
+func (f *runFlags) registerExecution(fs *flag.FlagSet) {
+	fs.BoolVar(&f.waitForDeletion, "wait-for-deletion", f.waitForDeletion, "-wait-for-deletion")
+	fs.DurationVar(&f.waitForDeletionTimeout, "wait-for-deletion-timeout", f.waitForDeletionTimeout, "-wait-for-deletion-timeout=1h")
+	fs.BoolVar(&f.resume, "resume", f.resume, "-resume")
+	fs.StringVar(&f.stateFile, "state-file", f.stateFile, "-state-file=dalek-state.json")
+}
+
+// registerOutput registers the flags which determine what's output at the end of the run
+func (f *runFlags) registerOutput(fs *flag.FlagSet) {
+	fs.StringVar(&f.reportFile, "report-file", f.reportFile, "-report-file=report.json")
+	fs.StringVar(&f.metricsFile, "metrics-file", f.metricsFile, "-metrics-file=/var/lib/node_exporter/textfile_collector/dalek.prom")
+	fs.StringVar(&f.metricsPushgatewayURL, "metrics-pushgateway-url", f.metricsPushgatewayURL, "-metrics-pushgateway-url=http://pushgateway:9091")
+}
+
+// parse parses the arguments (applying the configuration file, if any) and configures logging, returning the
+// Credentials and Options to run with
+func (f *runFlags) parse(fs *flag.FlagSet, args []string) (clients.Credentials, options.Options, error) {
+	if err := fs.Parse(args); err != nil {
+		return clients.Credentials{}, options.Options{}, err
+	}
+
+	// the configuration file is the lowest precedence, so its values are only used for flags which weren't specified
+	cfg := &config.Config{}
+	if f.configFile != "" {
+		var err error
+		if cfg, err = loadConfig(fs, f.configFile); err != nil {
+			return clients.Credentials{}, options.Options{}, err
+		}
+	}
+
+	if err := logging.Configure(os.Stderr, f.logLevel, f.logFormat); err != nil {
+		return clients.Credentials{}, options.Options{}, err
+	}
+	slog.Info("Starting Azure Dalek", slog.String("command", fs.Name()))
+
+	credentials, err := credentialsFromConfig(cfg.Credentials)
+	if err != nil {
+		return clients.Credentials{}, options.Options{}, err
+	}
+	if credentials.Profiles, credentials.Assignments, err = profilesFromConfig(cfg); err != nil {
+		return clients.Credentials{}, options.Options{}, fmt.Errorf("validating the configuration file %q: %+v", f.configFile, err)
+	}
+	actuallyDelete := cfg.ActuallyDelete != nil && *cfg.ActuallyDelete
+	if v, ok := os.LookupEnv("YES_I_REALLY_WANT_TO_DELETE_THINGS"); ok {
+		actuallyDelete = strings.EqualFold(v, "true")
+	}
+	opts := options.Options{
+		ActuallyDelete:                 actuallyDelete,
+		NumberOfResourceGroupsToDelete: f.maxResourceGroups,
+		Prefix:                         f.prefix,
+		SubscriptionIDs:                splitList(f.subscriptions),
+		AllSubscriptions:               f.allSubscriptions,
+		ManagementGroupID:              f.managementGroup,
+		Parallelism:                    f.parallelism,
+		WaitForDeletion:                f.waitForDeletion,
+		WaitForDeletionTimeout:         f.waitForDeletionTimeout,
+		ReportFile:                     f.reportFile,
+		Resume:                         f.resume,
+		StateFile:                      f.stateFile,
+		MetricsFile:                    f.metricsFile,
+		MetricsPushgatewayURL:          f.metricsPushgatewayURL,
+		Cleaners:                       splitList(f.cleaners),
+		SkipCleaners:                   splitList(f.skipCleaners),
+		Timeout:                        f.timeout,
+		MinimumAge:                     f.minAge,
+		ExpiresOnTag:                   f.expiresOnTag,
+		TTLTag:                         f.ttlTag,
+		ProtectedResources:             options.ProtectedResources(f.protectedResources),
+		ResourceGroupFilter:            cfg.ResourceGroupFilter,
+		Allowlist:                      cfg.Allowlist,
+		SafetyLimits:                   f.safetyLimits,
+		OverrideSafetyLimits:           f.overrideSafetyLimits,
+	}
+	if opts.ResourceGroupFilter != nil {
+		if err := opts.ResourceGroupFilter.Validate(); err != nil {
+			return clients.Credentials{}, options.Options{}, fmt.Errorf("validating the configuration file %q: %+v", f.configFile, err)
+		}
+	}
+	if err := opts.ProtectedResources.Validate(); err != nil {
+		return clients.Credentials{}, options.Options{}, err
+	}
+	if err := opts.Allowlist.Validate(); err != nil {
+		return clients.Credentials{}, options.Options{}, fmt.Errorf("validating the configuration file %q: %+v", f.configFile, err)
+	}
+	if err := opts.SafetyLimits.Validate(); err != nil {
+		return clients.Credentials{}, options.Options{}, fmt.Errorf("validating the safety limits: %+v", err)
+	}
+	if err := cleaners.ValidateNames(append(slices.Clone(opts.Cleaners), opts.SkipCleaners...)); err != nil {
+		return clients.Credentials{}, options.Options{}, fmt.Errorf("validating the Cleaners to run: %+v", err)
+	}
+
+	return credentials, opts, nil
+}
+
+// loadConfig loads the configuration file at path, using its values for any flags within fs which weren't specified.
+// Values for flags which the command doesn't support are ignored, so that one file can be used for every command.
+func loadConfig(fs *flag.FlagSet, path string) (*config.Config, error) {
+	cfg, err := config.Load(path)
 	if err != nil {
-		return fmt.Errorf("building Azure Clients: %+v", err)
+		return nil, err
 	}
 
-	errs := make([]error, 0) // nolint prealloc
+	specified := make(map[string]struct{})
+	fs.Visit(func(f *flag.Flag) {
+		specified[f.Name] = struct{}{}
+	})
+	for name, value := range cfg.FlagValues() {
+		if _, ok := specified[name]; ok || fs.Lookup(name) == nil {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return nil, fmt.Errorf("setting %q from the configuration file %q: %+v", name, path, err)
+		}
+	}
 
-	log.Printf("[DEBUG] Options: %s", opts)
+	return cfg, nil
+}
 
-	client := dalek.NewDalek(sdkClient, opts)
-	log.Printf("[DEBUG] Processing Resource Manager..")
-	errs = append(errs, client.ResourceManager(ctx)...)
+// credentialsFromConfig returns the Credentials from the `ARM_*` environment variables, falling back to those within the
+// configuration file. The Azure CLI is used (when nothing else is configured) unless it's disabled.
+func credentialsFromConfig(cfg config.Credentials) (clients.Credentials, error) {
+	credentials := clients.Credentials{
+		ClientID:                  envOrDefault("ARM_CLIENT_ID", cfg.ClientID),
+		ClientSecret:              envOrDefault("ARM_CLIENT_SECRET", clientSecretFromConfig(cfg)),
+		SubscriptionID:            envOrDefault("ARM_SUBSCRIPTION_ID", cfg.SubscriptionID),
+		TenantID:                  envOrDefault("ARM_TENANT_ID", cfg.TenantID),
+		EnvironmentName:           envOrDefault("ARM_ENVIRONMENT", cfg.Environment),
+		Endpoint:                  envOrDefault("ARM_ENDPOINT", cfg.Endpoint),
+		ClientCertificatePath:     envOrDefault("ARM_CLIENT_CERTIFICATE_PATH", cfg.ClientCertificatePath),
+		ClientCertificatePassword: envOrDefault("ARM_CLIENT_CERTIFICATE_PASSWORD", nil),
+		MSIEndpoint:               envOrDefault("ARM_MSI_ENDPOINT", cfg.MSIEndpoint),
+		OIDCToken:                 envOrDefault("ARM_OIDC_TOKEN", nil),
+		OIDCTokenFilePath:         envOrDefault("ARM_OIDC_TOKEN_FILE_PATH", cfg.OIDCTokenFilePath),
+		// these are set by GitHub Actions when the job has the `id-token: write` permission
+		OIDCRequestURL:   envOrDefault("ARM_OIDC_REQUEST_URL", pointer.To(os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL"))),
+		OIDCRequestToken: envOrDefault("ARM_OIDC_REQUEST_TOKEN", pointer.To(os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN"))),
+	}
 
-	log.Printf("[DEBUG] Processing Microsoft Graph..")
-	errs = append(errs, client.MicrosoftGraph(ctx))
+	var err error
+	if credentials.UseCLI, err = envBoolOrDefault("ARM_USE_CLI", cfg.UseCLI, true); err != nil {
+		return clients.Credentials{}, err
+	}
+	if credentials.UseMSI, err = envBoolOrDefault("ARM_USE_MSI", cfg.UseMSI, false); err != nil {
+		return clients.Credentials{}, err
+	}
+	if credentials.UseOIDC, err = envBoolOrDefault("ARM_USE_OIDC", cfg.UseOIDC, false); err != nil {
+		return clients.Credentials{}, err
+	}
 
-	log.Printf("[DEBUG] Processing Management Groups..")
-	errs = append(errs, client.ManagementGroups(ctx))
+	return credentials, nil
+}
 
-	return errors.Join(errs...)
+// profilesFromConfig returns the credential profiles within the configuration file, and what each is assigned to.
+// Unlike the default Credentials these aren't overridden by the `ARM_*` environment variables, and the Azure CLI is
+// only used when it's enabled explicitly - other than requesting an OIDC token from GitHub Actions, which is shared.
+func profilesFromConfig(cfg *config.Config) (map[string]clients.Credentials, clients.ProfileAssignments, error) {
+	profiles := make(map[string]clients.Credentials, len(cfg.CredentialProfiles))
+	for name, v := range cfg.CredentialProfiles {
+		profiles[name] = clients.Credentials{
+			ClientID:              pointer.From(v.ClientID),
+			ClientSecret:          pointer.From(clientSecretFromConfig(v)),
+			SubscriptionID:        pointer.From(v.SubscriptionID),
+			TenantID:              pointer.From(v.TenantID),
+			EnvironmentName:       pointer.From(v.Environment),
+			Endpoint:              pointer.From(v.Endpoint),
+			ClientCertificatePath: pointer.From(v.ClientCertificatePath),
+			UseCLI:                pointer.From(v.UseCLI),
+			UseMSI:                pointer.From(v.UseMSI),
+			MSIEndpoint:           pointer.From(v.MSIEndpoint),
+			UseOIDC:               pointer.From(v.UseOIDC),
+			OIDCTokenFilePath:     pointer.From(v.OIDCTokenFilePath),
+			OIDCRequestURL:        envOrDefault("ARM_OIDC_REQUEST_URL", pointer.To(os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL"))),
+			OIDCRequestToken:      envOrDefault("ARM_OIDC_REQUEST_TOKEN", pointer.To(os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN"))),
+		}
+	}
+
+	assignments := clients.ProfileAssignments{
+		Subscriptions:    make(map[string]string, len(cfg.ProfileAssignments.Subscriptions)),
+		MicrosoftGraph:   pointer.From(cfg.ProfileAssignments.MicrosoftGraph),
+		ManagementGroups: pointer.From(cfg.ProfileAssignments.ManagementGroups),
+	}
+	checkProfile := func(key, name string) error {
+		if _, ok := profiles[name]; !ok {
+			return fmt.Errorf("credential-profile-assignments.%s: the credential profile %q isn't defined", key, name)
+		}
+		return nil
+	}
+	for subscriptionId, name := range cfg.ProfileAssignments.Subscriptions {
+		if _, err := uuid.ParseUUID(subscriptionId); err != nil {
+			return nil, clients.ProfileAssignments{}, fmt.Errorf("credential-profile-assignments.subscriptions: %q isn't a Subscription ID", subscriptionId)
+		}
+		if err := checkProfile(fmt.Sprintf("subscriptions.%s", subscriptionId), name); err != nil {
+			return nil, clients.ProfileAssignments{}, err
+		}
+		assignments.Subscriptions[subscriptionId] = name
+	}
+	if assignments.MicrosoftGraph != "" {
+		if err := checkProfile("microsoft-graph", assignments.MicrosoftGraph); err != nil {
+			return nil, clients.ProfileAssignments{}, err
+		}
+	}
+	if assignments.ManagementGroups != "" {
+		if err := checkProfile("management-groups", assignments.ManagementGroups); err != nil {
+			return nil, clients.ProfileAssignments{}, err
+		}
+	}
+
+	return profiles, assignments, nil
+}
+
+// clientSecretFromConfig returns the client secret from the configuration file, either specified directly or read
+// from the environment variable named by `client-secret-env`
+func clientSecretFromConfig(cfg config.Credentials) *string {
+	if cfg.ClientSecretEnv != nil {
+		if v, ok := os.LookupEnv(*cfg.ClientSecretEnv); ok {
+			return &v
+		}
+	}
+	return cfg.ClientSecret
+}
+
+// envBoolOrDefault is envOrDefault for boolean values, returning defaultValue when neither is set
+func envBoolOrDefault(name string, fallback *bool, defaultValue bool) (bool, error) {
+	if v, ok := os.LookupEnv(name); ok && v != "" {
+		value, err := strconv.ParseBool(v)
+		if err != nil {
+			return false, fmt.Errorf("parsing the environment variable %q: expected a boolean but got %q", name, v)
+		}
+		return value, nil
+	}
+	if fallback != nil {
+		return *fallback, nil
+	}
+	return defaultValue, nil
+}
+
+// envOrDefault returns the value of the environment variable when it's set, otherwise the value from the configuration
+// file (if any)
+func envOrDefault(name string, fallback *string) string {
+	if v, ok := os.LookupEnv(name); ok && v != "" {
+		return v
+	}
+	if fallback != nil {
+		return *fallback
+	}
+	return ""
+}
+
+// splitList splits a comma-separated list, ignoring any empty entries
+func splitList(input string) []string {
+	output := make([]string, 0)
+	for _, v := range strings.Split(input, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			output = append(output, v)
+		}
+	}
+	return output
 }